@@ -0,0 +1,321 @@
+// Package auth decides which callers may use which RPCs. A caller is identified
+// by the common name of its verified TLS client certificate, or by a bearer token.
+// Peers may replicate between KV servers and join the view, admins may call
+// operator RPCs, and clients may read and write the key prefixes granted to them.
+// Anyone may check health, so load balancers need no identity. Denials and admin
+// actions are written to an audit log.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"goDistributedSystemDemo/logging"
+	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/tlsconfig"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// Policy says what each principal may do
+type Policy struct {
+	Peers   []string           `json:"peers"`   // may replicate, ping and leave the view
+	Admins  []string           `json:"admins"`  // may call operator RPCs such as ForcePrimary
+	Clients map[string][]Grant `json:"clients"` // key prefixes each client may use
+	Tokens  map[string]string  `json:"tokens"`  // principal -> hex SHA-256 of its bearer token
+}
+
+// Grant gives a client access to the keys starting with Prefix; "" is every key
+type Grant struct {
+	Prefix string `json:"prefix"`
+	Read   bool   `json:"read"`  // Get, and Scan of prefixes within Prefix
+	Write  bool   `json:"write"` // Put and Delete
+}
+
+// LoadPolicy reads a policy from a JSON file
+func LoadPolicy(name string) (*Policy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid auth policy %s: %w", name, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth policy %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// Validate checks the policy for entries that cannot work
+func (p *Policy) Validate() error {
+	for principal, hash := range p.Tokens {
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("token of %q is not a hex SHA-256 hash", principal)
+		}
+	}
+	return nil
+}
+
+// HashToken returns the form a token is stored in a Policy
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authorizer enforces a Policy on the RPCs a server handles
+type Authorizer struct {
+	policy *Policy
+	tokens map[string]string // hash -> principal
+	audit  *slog.Logger
+}
+
+// New returns an Authorizer enforcing policy and logging to audit
+func New(policy *Policy, audit *slog.Logger) *Authorizer {
+	a := &Authorizer{policy: policy, tokens: make(map[string]string), audit: audit}
+	for principal, hash := range policy.Tokens {
+		a.tokens[strings.ToLower(hash)] = principal
+	}
+	return a
+}
+
+// publicMethods may be called by anyone, with or without an identity
+var publicMethods = []string{
+	healthgrpc.Health_Check_FullMethodName,
+	healthgrpc.Health_List_FullMethodName,
+	healthgrpc.Health_Watch_FullMethodName,
+}
+
+// ServerOptions install the Authorizer on a gRPC server
+func (a *Authorizer) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(a.StreamServerInterceptor()),
+	}
+}
+
+// UnaryServerInterceptor refuses calls the policy does not allow
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(publicMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		principal, err := a.principal(ctx)
+		if err == nil {
+			err = a.authorize(principal, req)
+		}
+		if err != nil {
+			a.log(ctx, slog.LevelWarn, "Permission denied", principal, info.FullMethod, req, slog.String("reason", status.Convert(err).Message()))
+			return nil, err
+		}
+		if a.adminOnly(req) {
+			a.log(ctx, slog.LevelInfo, "Admin action", principal, info.FullMethod, req)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor refuses streams the policy does not allow. Only health
+// watches and server reflection are streams; reflection is open to any known
+// principal.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(publicMethods, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx := ss.Context()
+		principal, err := a.principal(ctx)
+		if err == nil {
+			switch info.FullMethod {
+			case reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName,
+				reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName:
+				if !a.known(principal) {
+					err = status.Errorf(codes.PermissionDenied, "%s is not in the policy", principal)
+				}
+			default:
+				err = status.Errorf(codes.PermissionDenied, "no rule allows stream %s", info.FullMethod)
+			}
+		}
+		if err != nil {
+			a.log(ctx, slog.LevelWarn, "Permission denied", principal, info.FullMethod, nil, slog.String("reason", status.Convert(err).Message()))
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// principal identifies the caller by its bearer token if it sent one, else by its
+// verified certificate
+func (a *Authorizer) principal(ctx context.Context) (string, error) {
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return "", status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+		}
+		principal, ok := a.tokens[HashToken(token)]
+		if !ok {
+			return "", status.Error(codes.Unauthenticated, "unknown bearer token")
+		}
+		return principal, nil
+	}
+	if cert := tlsconfig.PeerCert(ctx); cert != nil && cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, nil
+	}
+	return "", status.Error(codes.Unauthenticated, "no client certificate or bearer token")
+}
+
+// authorize reports whether principal may make the request
+func (a *Authorizer) authorize(principal string, req any) error {
+	switch r := req.(type) {
+	case *pb.GetRequest:
+		return a.keyAccess(principal, r.Key, false)
+	case *pb.ScanRequest:
+		return a.keyAccess(principal, r.Prefix, false)
+	case *pb.PutRequest:
+		return a.keyAccess(principal, r.Key, true)
+	case *pb.DeleteRequest:
+		return a.keyAccess(principal, r.Key, true)
+	case *pb.ForwardUpdateRequest, *pb.SyncStateRequest, *pb.ConfirmViewRequest, *pb.PingRequest, *pb.LeaveRequest:
+		if !slices.Contains(a.policy.Peers, principal) {
+			return status.Errorf(codes.PermissionDenied, "%s is not a peer server", principal)
+		}
+		return nil
+	case *pb.ForcePrimaryRequest:
+		if !slices.Contains(a.policy.Admins, principal) {
+			return status.Errorf(codes.PermissionDenied, "%s is not an admin", principal)
+		}
+		return nil
+	case *pb.GetViewRequest, *pb.ListServersRequest, *pb.ListViewsRequest:
+		// Any known principal may find the primary and inspect the cluster
+		if !a.known(principal) {
+			return status.Errorf(codes.PermissionDenied, "%s is not in the policy", principal)
+		}
+		return nil
+	default:
+		return status.Errorf(codes.PermissionDenied, "no rule allows %T", req)
+	}
+}
+
+// keyAccess checks a client's grants for key. A Scan passes its prefix as key, so
+// it is allowed only when every key it can return lies within one grant.
+func (a *Authorizer) keyAccess(principal string, key string, write bool) error {
+	for _, g := range a.policy.Clients[principal] {
+		if (write && !g.Write) || (!write && !g.Read) {
+			continue
+		}
+		if strings.HasPrefix(key, g.Prefix) {
+			return nil
+		}
+	}
+	access := "read"
+	if write {
+		access = "write"
+	}
+	return status.Errorf(codes.PermissionDenied, "%s may not %s %q", principal, access, key)
+}
+
+// known reports whether the policy mentions principal at all
+func (a *Authorizer) known(principal string) bool {
+	_, client := a.policy.Clients[principal]
+	return client || slices.Contains(a.policy.Peers, principal) || slices.Contains(a.policy.Admins, principal)
+}
+
+// adminOnly reports whether req is an operator RPC
+func (a *Authorizer) adminOnly(req any) bool {
+	_, ok := req.(*pb.ForcePrimaryRequest)
+	return ok
+}
+
+// log writes an audit record of a call
+func (a *Authorizer) log(ctx context.Context, level slog.Level, msg string, principal string, method string, req any, attrs ...slog.Attr) {
+	attrs = append(attrs,
+		slog.String("principal", principal),
+		slog.String("method", path.Base(method)),
+		slog.String("request_id", logging.RequestID(ctx)))
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("remote", p.Addr.String()))
+	}
+	if r, ok := req.(interface{ GetKey() string }); ok {
+		attrs = append(attrs, slog.String("key", r.GetKey()))
+	}
+	if r, ok := req.(*pb.ScanRequest); ok {
+		attrs = append(attrs, slog.String("prefix", r.Prefix))
+	}
+	if r, ok := req.(*pb.ForcePrimaryRequest); ok {
+		attrs = append(attrs, slog.String("server", r.ServerName))
+	}
+	a.audit.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// Token returns per-RPC credentials sending token as a bearer token. They are only
+// sent over TLS.
+func Token(token string) credentials.PerRPCCredentials {
+	return bearer(token)
+}
+
+type bearer string
+
+func (b bearer) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+func (b bearer) RequireTransportSecurity() bool {
+	return true
+}
+
+// ReadToken reads a bearer token from a file, ignoring surrounding whitespace
+func ReadToken(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", name)
+	}
+	return token, nil
+}
+
+// TokenDialOption reads a bearer token from the file name and returns the dial
+// option sending it with every RPC. It fails unless tlsEnabled, since the token
+// would otherwise be sent in the clear.
+func TokenDialOption(name string, tlsEnabled bool) (grpc.DialOption, error) {
+	if !tlsEnabled {
+		return nil, fmt.Errorf("token file %s needs TLS, the token would be sent in the clear", name)
+	}
+	token, err := ReadToken(name)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithPerRPCCredentials(Token(token)), nil
+}
+
+// AuditLogger returns a logger appending audit records as JSON lines to the file
+// name, or adding them to the default log, marked audit=true, if name is empty
+func AuditLogger(name string) (*slog.Logger, error) {
+	if name == "" {
+		return slog.Default().With("audit", true), nil
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit log: %w", err)
+	}
+	return slog.New(slog.NewJSONHandler(f, nil)), nil
+}
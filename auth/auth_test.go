@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testPolicy has a peer, an admin, a reader of a/, a writer of logs/ without read
+// access and a client granted every key, each with a token named after it
+func testPolicy() *Policy {
+	p := &Policy{
+		Peers:  []string{"kv1"},
+		Admins: []string{"ops"},
+		Clients: map[string][]Grant{
+			"reader": {{Prefix: "a/", Read: true}},
+			"logger": {{Prefix: "logs/", Write: true}},
+			"app":    {{Prefix: "", Read: true, Write: true}},
+		},
+		Tokens: make(map[string]string),
+	}
+	for _, principal := range []string{"kv1", "ops", "reader", "logger", "app"} {
+		p.Tokens[principal] = HashToken(principal + "-token")
+	}
+	return p
+}
+
+// withToken returns a context carrying authorization as incoming metadata
+func withToken(authorization string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal string
+		req       any
+		allowed   bool
+	}{
+		{"read within grant", "reader", &pb.GetRequest{Key: "a/x"}, true},
+		{"read outside grant", "reader", &pb.GetRequest{Key: "b/x"}, false},
+		{"read of the grant's prefix itself", "reader", &pb.GetRequest{Key: "a/"}, true},
+		{"read of a key sharing the prefix's start", "reader", &pb.GetRequest{Key: "a"}, false},
+		{"write with read-only grant", "reader", &pb.PutRequest{Key: "a/x"}, false},
+		{"delete with read-only grant", "reader", &pb.DeleteRequest{Key: "a/x"}, false},
+		{"write with write-only grant", "logger", &pb.PutRequest{Key: "logs/1"}, true},
+		{"delete with write-only grant", "logger", &pb.DeleteRequest{Key: "logs/1"}, true},
+		{"read with write-only grant", "logger", &pb.GetRequest{Key: "logs/1"}, false},
+		{"scan within grant", "reader", &pb.ScanRequest{Prefix: "a/b"}, true},
+		{"scan of the grant's prefix", "reader", &pb.ScanRequest{Prefix: "a/"}, true},
+		{"scan wider than grant", "reader", &pb.ScanRequest{Prefix: "a"}, false},
+		{"scan of every key", "reader", &pb.ScanRequest{Prefix: ""}, false},
+		{"scan of every key with grant of every key", "app", &pb.ScanRequest{Prefix: ""}, true},
+		{"client without grants", "stranger", &pb.GetRequest{Key: "a/x"}, false},
+		{"peer replicates", "kv1", &pb.ForwardUpdateRequest{}, true},
+		{"peer syncs state", "kv1", &pb.SyncStateRequest{}, true},
+		{"peer confirms view", "kv1", &pb.ConfirmViewRequest{}, true},
+		{"peer pings", "kv1", &pb.PingRequest{}, true},
+		{"peer leaves", "kv1", &pb.LeaveRequest{}, true},
+		{"client replicates", "app", &pb.ForwardUpdateRequest{}, false},
+		{"client pings", "app", &pb.PingRequest{}, false},
+		{"peer reads keys", "kv1", &pb.GetRequest{Key: "a/x"}, false},
+		{"admin forces primary", "ops", &pb.ForcePrimaryRequest{}, true},
+		{"client forces primary", "app", &pb.ForcePrimaryRequest{}, false},
+		{"peer forces primary", "kv1", &pb.ForcePrimaryRequest{}, false},
+		{"client gets view", "reader", &pb.GetViewRequest{}, true},
+		{"admin lists servers", "ops", &pb.ListServersRequest{}, true},
+		{"peer lists views", "kv1", &pb.ListViewsRequest{}, true},
+		{"unknown principal gets view", "stranger", &pb.GetViewRequest{}, false},
+		{"request without a rule", "ops", &pb.View{}, false},
+	}
+	a := New(testPolicy(), slog.New(slog.DiscardHandler))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.authorize(tt.principal, tt.req)
+			if tt.allowed && err != nil {
+				t.Fatalf("denied: %v", err)
+			}
+			if !tt.allowed && status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("got %v, want PermissionDenied", err)
+			}
+		})
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string // "" if the caller is not authenticated
+	}{
+		{"known token", withToken("Bearer reader-token"), "reader"},
+		{"unknown token", withToken("Bearer guess"), ""},
+		{"empty token", withToken("Bearer "), ""},
+		{"not a bearer token", withToken("Basic cmVhZGVyOnB3"), ""},
+		{"lower-case scheme", withToken("bearer reader-token"), ""},
+		{"no credentials", context.Background(), ""},
+	}
+	a := New(testPolicy(), slog.New(slog.DiscardHandler))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := a.principal(tt.ctx)
+			if tt.want == "" {
+				if status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("got %q, %v; want Unauthenticated", principal, err)
+				}
+				return
+			}
+			if err != nil || principal != tt.want {
+				t.Fatalf("got %q, %v; want %q", principal, err, tt.want)
+			}
+		})
+	}
+}
+
+// auditRecord is the part of an audit log line the tests check
+type auditRecord struct {
+	Msg       string `json:"msg"`
+	Principal string `json:"principal"`
+	Method    string `json:"method"`
+	Key       string `json:"key"`
+	Server    string `json:"server"`
+	Reason    string `json:"reason"`
+}
+
+// records parses the JSON lines in buf
+func records(t *testing.T, buf *bytes.Buffer) []auditRecord {
+	t.Helper()
+	var out []auditRecord
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r auditRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		method    string
+		req       any
+		wantCode  codes.Code
+		wantAudit *auditRecord // nil if nothing is audited
+	}{
+		{
+			name: "health without credentials", ctx: context.Background(),
+			method: healthgrpc.Health_Check_FullMethodName, req: &healthgrpc.HealthCheckRequest{},
+			wantCode: codes.OK,
+		},
+		{
+			name: "health with an unknown token", ctx: withToken("Bearer guess"),
+			method: healthgrpc.Health_Check_FullMethodName, req: &healthgrpc.HealthCheckRequest{},
+			wantCode: codes.OK,
+		},
+		{
+			name: "allowed read", ctx: withToken("Bearer reader-token"),
+			method: "/proto.KVServer/Get", req: &pb.GetRequest{Key: "a/x"},
+			wantCode: codes.OK,
+		},
+		{
+			name: "denied write", ctx: withToken("Bearer reader-token"),
+			method: "/proto.KVServer/Put", req: &pb.PutRequest{Key: "a/x"},
+			wantCode:  codes.PermissionDenied,
+			wantAudit: &auditRecord{Msg: "Permission denied", Principal: "reader", Method: "Put", Key: "a/x", Reason: `reader may not write "a/x"`},
+		},
+		{
+			name: "unknown token", ctx: withToken("Bearer guess"),
+			method: "/proto.KVServer/Get", req: &pb.GetRequest{Key: "a/x"},
+			wantCode:  codes.Unauthenticated,
+			wantAudit: &auditRecord{Msg: "Permission denied", Method: "Get", Key: "a/x", Reason: "unknown bearer token"},
+		},
+		{
+			name: "no credentials", ctx: context.Background(),
+			method: "/proto.ViewService/GetView", req: &pb.GetViewRequest{},
+			wantCode:  codes.Unauthenticated,
+			wantAudit: &auditRecord{Msg: "Permission denied", Method: "GetView", Reason: "no client certificate or bearer token"},
+		},
+		{
+			name: "forced primary", ctx: withToken("Bearer ops-token"),
+			method: "/proto.ViewService/ForcePrimary", req: &pb.ForcePrimaryRequest{ServerName: "kv2"},
+			wantCode:  codes.OK,
+			wantAudit: &auditRecord{Msg: "Admin action", Principal: "ops", Method: "ForcePrimary", Server: "kv2"},
+		},
+		{
+			name: "denied forced primary", ctx: withToken("Bearer app-token"),
+			method: "/proto.ViewService/ForcePrimary", req: &pb.ForcePrimaryRequest{ServerName: "kv2"},
+			wantCode:  codes.PermissionDenied,
+			wantAudit: &auditRecord{Msg: "Permission denied", Principal: "app", Method: "ForcePrimary", Server: "kv2", Reason: "app is not an admin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			a := New(testPolicy(), slog.New(slog.NewJSONHandler(&buf, nil)))
+			called := false
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			}
+			_, err := a.UnaryServerInterceptor()(tt.ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("got %v, want %v", err, tt.wantCode)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %v", called)
+			}
+
+			audit := records(t, &buf)
+			if tt.wantAudit == nil {
+				if len(audit) != 0 {
+					t.Fatalf("audited %+v, want nothing", audit)
+				}
+				return
+			}
+			if len(audit) != 1 || audit[0] != *tt.wantAudit {
+				t.Fatalf("audited %+v, want %+v", audit, *tt.wantAudit)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"peers": ["kv1"], "tokens": {"kv1": "` + HashToken("x") + `"}}`, false},
+		{"empty", `{}`, false},
+		{"token not hashed", `{"tokens": {"kv1": "secret"}}`, true},
+		{"hash too short", `{"tokens": {"kv1": "abcd"}}`, true},
+		{"not JSON", `peers: kv1`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(name, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPolicy(name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
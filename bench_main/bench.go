@@ -0,0 +1,73 @@
+// Package main implements a load generator for the KV service.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"goDistributedSystemDemo/bench_main/bench"
+	"goDistributedSystemDemo/client_main/client"
+)
+
+func main() {
+	vsAddr := flag.String("vs", "localhost:8000", "View service address (host:port)")
+	timeout := flag.Duration("timeout", client.DefaultOpTimeout, "Deadline for each operation")
+	verbose := flag.Bool("v", false, "Show client retry logs")
+
+	cfg := bench.Config{}
+	flag.IntVar(&cfg.Concurrency, "c", 16, "Number of concurrent workers")
+	flag.DurationVar(&cfg.Duration, "d", 30*time.Second, "Duration of the run")
+	flag.DurationVar(&cfg.Interval, "interval", time.Second, "Reporting interval")
+	flag.IntVar(&cfg.Keys, "keys", 10000, "Size of the key space")
+	flag.StringVar(&cfg.Distribution, "dist", bench.Uniform, "Key distribution: uniform or zipfian")
+	flag.Float64Var(&cfg.ZipfS, "zipf-s", 1.1, "Zipf exponent (> 1), used with -dist=zipfian")
+	flag.Float64Var(&cfg.ReadRatio, "reads", 0.5, "Fraction of operations that are gets (0-1)")
+	flag.IntVar(&cfg.ValueSize, "value-size", 100, "Size of written values in bytes")
+	flag.Uint64Var(&cfg.Seed, "seed", uint64(time.Now().UnixNano()), "Seed for key and operation choice")
+	flag.Parse()
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *verbose {
+		// Retries are logged at debug level
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	fmt.Printf("Starting benchmark\n")
+	fmt.Printf("View Service at %s\n", *vsAddr)
+	fmt.Printf("Concurrency=%d Duration=%v Keys=%d Dist=%s Reads=%.2f ValueSize=%d Seed=%d\n",
+		cfg.Concurrency, cfg.Duration, cfg.Keys, cfg.Distribution, cfg.ReadRatio, cfg.ValueSize, cfg.Seed)
+
+	ck, err := client.MakeClient(*vsAddr, client.WithOpTimeout(*timeout))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	defer ck.Close()
+
+	// Stop early on interrupt and still print the summary
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := bench.NewReport(os.Stdout)
+	report.Header()
+	total, err := bench.Run(ctx, ck, cfg, report.Sample)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	report.Summary(total)
+}
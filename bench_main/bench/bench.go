@@ -0,0 +1,196 @@
+// Package bench drives a configurable read/write load through client.Client and
+// reports throughput and latency percentiles over time.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+)
+
+// Key distributions
+const (
+	Uniform = "uniform"
+	Zipfian = "zipfian"
+)
+
+// Config describes one benchmark run
+type Config struct {
+	Concurrency  int           // number of concurrent workers
+	Duration     time.Duration // how long to generate load
+	Interval     time.Duration // how often to report a sample
+	Keys         int           // size of the key space
+	Distribution string        // Uniform or Zipfian
+	ZipfS        float64       // Zipf exponent, must be > 1
+	ReadRatio    float64       // fraction of operations that are gets, 0 to 1
+	ValueSize    int           // bytes per written value
+	Seed         uint64        // seed for key and operation choice
+}
+
+// Validate checks that the configuration can be run
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.Concurrency < 1:
+		return fmt.Errorf("bench: concurrency must be at least 1")
+	case cfg.Duration <= 0:
+		return fmt.Errorf("bench: duration must be positive")
+	case cfg.Interval <= 0:
+		return fmt.Errorf("bench: interval must be positive")
+	case cfg.Keys < 1:
+		return fmt.Errorf("bench: key space must hold at least 1 key")
+	case cfg.Distribution != Uniform && cfg.Distribution != Zipfian:
+		return fmt.Errorf("bench: unknown distribution %q", cfg.Distribution)
+	case cfg.Distribution == Zipfian && cfg.ZipfS <= 1:
+		return fmt.Errorf("bench: zipf exponent must be greater than 1")
+	case cfg.ReadRatio < 0 || cfg.ReadRatio > 1:
+		return fmt.Errorf("bench: read ratio must be between 0 and 1")
+	case cfg.ValueSize < 0:
+		return fmt.Errorf("bench: value size must not be negative")
+	}
+	return nil
+}
+
+// Sample summarises the operations completed during one interval, or the whole run
+type Sample struct {
+	Elapsed    time.Duration // time since the run started, at the end of the sample
+	Ops        int           // successful operations
+	Errors     int           // failed operations
+	Throughput float64       // successful operations per second
+	P50        time.Duration // latency percentiles of the successful operations
+	P99        time.Duration
+	P999       time.Duration
+	Max        time.Duration
+	ErrorP50   time.Duration // latency percentiles of the failed operations
+	ErrorP99   time.Duration
+	ErrorMax   time.Duration
+	View       string // primary/backup at the end of the interval, if known
+}
+
+// Run generates load until cfg.Duration passes or ctx is cancelled. report is
+// called once per interval; the summary of the whole run is returned.
+func Run(ctx context.Context, ck *client.Client, cfg Config, report func(Sample)) (Sample, error) {
+	if err := cfg.Validate(); err != nil {
+		return Sample{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	rec := newRecorder()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			newWorker(cfg, uint64(w)).run(ctx, ck, rec)
+		}(w)
+	}
+
+	// Report one sample per interval until the load stops
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	last := start
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			s := rec.interval().sample(now.Sub(last))
+			s.Elapsed = now.Sub(start)
+			s.View = currentView(ck)
+			if report != nil {
+				report(s)
+			}
+			last = now
+		case <-done:
+			now := time.Now()
+			if tail := rec.interval(); tail.ok.n+tail.failed.n > 0 && report != nil {
+				s := tail.sample(now.Sub(last))
+				s.Elapsed = now.Sub(start)
+				s.View = currentView(ck)
+				report(s)
+			}
+			total := rec.total().sample(now.Sub(start))
+			total.Elapsed = now.Sub(start)
+			return total, nil
+		}
+	}
+}
+
+// worker issues operations in a closed loop
+type worker struct {
+	cfg   Config
+	rng   *rand.Rand
+	zipf  *rand.Zipf
+	value string
+}
+
+// newWorker creates a worker with its own random source, so workers need no locking
+func newWorker(cfg Config, id uint64) *worker {
+	w := &worker{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, id)),
+	}
+	if cfg.Distribution == Zipfian {
+		w.zipf = rand.NewZipf(w.rng, cfg.ZipfS, 1, uint64(cfg.Keys-1))
+	}
+	buf := make([]byte, cfg.ValueSize)
+	for i := range buf {
+		buf[i] = 'a' + byte(w.rng.IntN(26))
+	}
+	w.value = string(buf)
+	return w
+}
+
+// nextKey picks a key according to the configured distribution
+func (w *worker) nextKey() string {
+	if w.zipf != nil {
+		return fmt.Sprintf("key%d", w.zipf.Uint64())
+	}
+	return fmt.Sprintf("key%d", w.rng.IntN(w.cfg.Keys))
+}
+
+// run issues operations until ctx ends, recording the latency of each
+func (w *worker) run(ctx context.Context, ck *client.Client, rec *recorder) {
+	for ctx.Err() == nil {
+		key := w.nextKey()
+		read := w.rng.Float64() < w.cfg.ReadRatio
+
+		opStart := time.Now()
+		var err error
+		if read {
+			_, _, err = ck.Get(ctx, key)
+		} else {
+			err = ck.Put(ctx, key, w.value)
+		}
+		latency := time.Since(opStart)
+
+		// Operations cut short by the end of the run are not counted
+		if ctx.Err() != nil {
+			return
+		}
+		rec.record(latency, err)
+	}
+}
+
+// currentView describes the view at the time of a sample
+func currentView(ck *client.Client) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	view, err := ck.View(ctx)
+	if err != nil {
+		return "?"
+	}
+	return fmt.Sprintf("v%d %s/%s", view.ViewNumber, view.Primary, view.Backup)
+}
@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Latencies are counted in log-linear buckets: every power of two is split into
+// subBuckets equal parts, so a percentile is off by at most 1/subBuckets of its
+// value however long the run, and a histogram takes the same memory for any
+// number of operations.
+const (
+	subBucketBits = 6
+	subBuckets    = 1 << subBucketBits
+	numBuckets    = (64 - subBucketBits) * subBuckets
+)
+
+// histogram counts latencies in fixed buckets
+type histogram struct {
+	counts [numBuckets]uint64
+	n      int
+	max    time.Duration
+}
+
+// add counts one latency
+func (h *histogram) add(d time.Duration) {
+	d = max(d, 0)
+	h.counts[bucket(d)]++
+	h.n++
+	h.max = max(h.max, d)
+}
+
+// percentile returns the q-th quantile using the nearest-rank method, as the
+// highest latency its bucket holds but no more than the largest seen
+func (h *histogram) percentile(q float64) time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.n)))
+	rank = min(max(rank, 1), uint64(h.n))
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(bucketHigh(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// bucket returns the index of the bucket holding d
+func bucket(d time.Duration) int {
+	v := uint64(d)
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits - 1
+	return (shift+1)*subBuckets + int(v>>shift) - subBuckets
+}
+
+// bucketHigh returns the highest latency bucket i holds
+func bucketHigh(i int) time.Duration {
+	if i < subBuckets {
+		return time.Duration(i)
+	}
+	shift := i/subBuckets - 1
+	low := uint64(subBuckets+i%subBuckets) << shift
+	return time.Duration(low + 1<<shift - 1)
+}
@@ -0,0 +1,142 @@
+package bench
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// TestBuckets checks that every latency falls in a bucket whose highest value is
+// at least the latency and within 1/subBuckets of it, and that buckets are
+// contiguous
+func TestBuckets(t *testing.T) {
+	latencies := []time.Duration{0, 1, subBuckets - 1, subBuckets, subBuckets + 1, 127, 128, 129,
+		time.Microsecond, time.Millisecond, time.Second, time.Hour, math.MaxInt64}
+	for shift := range 63 {
+		latencies = append(latencies, 1<<shift-1, 1<<shift, 1<<shift+1)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 10000 {
+		latencies = append(latencies, time.Duration(rng.Int64N(math.MaxInt64)>>rng.IntN(63)))
+	}
+
+	for _, d := range latencies {
+		i := bucket(d)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("bucket(%d) = %d, outside [0, %d)", d, i, numBuckets)
+		}
+		high := bucketHigh(i)
+		if high < d {
+			t.Fatalf("bucket(%d) = %d holds up to %d only", d, i, high)
+		}
+		if d < subBuckets && high != d {
+			t.Fatalf("bucket(%d) holds up to %d, want exact", d, high)
+		}
+		if err := float64(high-d) / float64(d); d > 0 && err > 1.0/subBuckets {
+			t.Fatalf("bucket(%d) holds up to %d, error %.4f above %.4f", d, high, err, 1.0/subBuckets)
+		}
+	}
+
+	for i := range bucket(math.MaxInt64) + 1 {
+		if got := bucket(bucketHigh(i)); got != i {
+			t.Fatalf("the highest latency of bucket %d falls in bucket %d", i, got)
+		}
+		if i > 0 && bucket(bucketHigh(i-1)+1) != i {
+			t.Fatalf("bucket %d does not start right after bucket %d", i, i-1)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// 1ms to 100ms, one operation each
+	ramp := make([]time.Duration, 100)
+	for i := range ramp {
+		ramp[i] = time.Duration(i+1) * time.Millisecond
+	}
+	// 99 fast operations and one slow one
+	tail := make([]time.Duration, 100)
+	for i := range tail {
+		tail[i] = time.Millisecond
+	}
+	tail[42] = time.Second
+
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		q         float64
+		want      time.Duration
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single", []time.Duration{1234567}, 0.5, 1234567},
+		{"single at the lowest quantile", []time.Duration{1234567}, 0, 1234567},
+		{"negative counts as zero", []time.Duration{-time.Second}, 0.99, 0},
+		{"median of a ramp", ramp, 0.50, 50 * time.Millisecond},
+		{"p99 of a ramp", ramp, 0.99, 99 * time.Millisecond},
+		{"p999 of a ramp is the largest", ramp, 0.999, 100 * time.Millisecond},
+		{"lowest quantile of a ramp", ramp, 0, time.Millisecond},
+		{"p99 misses one slow operation in 100", tail, 0.99, time.Millisecond},
+		{"p999 finds one slow operation in 100", tail, 0.999, time.Second},
+		{"quantile above 1 is the largest", ramp, 2, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h histogram
+			for _, d := range tt.latencies {
+				h.add(d)
+			}
+			got := h.percentile(tt.q)
+			// A percentile is reported as the highest latency of its bucket
+			if got < tt.want || float64(got-tt.want) > float64(tt.want)/subBuckets {
+				t.Fatalf("percentile(%v) = %v, want %v within 1/%d", tt.q, got, tt.want, subBuckets)
+			}
+		})
+	}
+}
+
+// TestPercentileCappedAtMax never reports more than the largest latency seen,
+// though its bucket holds higher ones
+func TestPercentileCappedAtMax(t *testing.T) {
+	var h histogram
+	d := time.Duration(1<<20 + 1)
+	h.add(d)
+	if high := bucketHigh(bucket(d)); high == d {
+		t.Fatalf("test latency %d is the highest of its bucket", d)
+	}
+	if got := h.percentile(1); got != d {
+		t.Fatalf("percentile(1) = %d, want the largest latency %d", got, d)
+	}
+}
+
+// TestWindowSample keeps failed operations out of the latency of the successful
+// ones, and starts a fresh window for every interval
+func TestWindowSample(t *testing.T) {
+	rec := newRecorder()
+	for range 90 {
+		rec.record(time.Millisecond, nil)
+	}
+	for range 10 {
+		rec.record(time.Second, errors.New("timeout"))
+	}
+
+	s := rec.interval().sample(2 * time.Second)
+	if s.Ops != 90 || s.Errors != 10 || s.Throughput != 45 {
+		t.Errorf("got %d ops, %d errors, %.1f ops/s; want 90, 10, 45", s.Ops, s.Errors, s.Throughput)
+	}
+	if s.P99 > 2*time.Millisecond || s.Max != time.Millisecond {
+		t.Errorf("successful p99 %v, max %v; want about 1ms", s.P99, s.Max)
+	}
+	if s.ErrorP50 < time.Second || s.ErrorMax != time.Second {
+		t.Errorf("failed p50 %v, max %v; want about 1s", s.ErrorP50, s.ErrorMax)
+	}
+
+	rec.record(3*time.Millisecond, nil)
+	if s := rec.interval().sample(0); s.Ops != 1 || s.Errors != 0 || s.Throughput != 0 {
+		t.Errorf("second interval has %d ops, %d errors, %.1f ops/s; want 1, 0 and no throughput without a duration",
+			s.Ops, s.Errors, s.Throughput)
+	}
+	if s := rec.total().sample(time.Second); s.Ops != 91 || s.Errors != 10 || s.Max != 3*time.Millisecond {
+		t.Errorf("total has %d ops, %d errors, max %v; want 91, 10, 3ms", s.Ops, s.Errors, s.Max)
+	}
+}
@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"sync"
+	"time"
+)
+
+// recorder collects operation latencies for the current interval and the whole run
+type recorder struct {
+	mu      sync.Mutex
+	current window
+	all     window
+}
+
+// window holds the outcomes of the operations in one reporting period. Failed
+// operations are kept apart, so a failover's timeouts neither vanish from the
+// results nor hide the latency of the operations that succeeded.
+type window struct {
+	ok     histogram
+	failed histogram
+}
+
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+// record adds the outcome of one operation
+func (r *recorder) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.current.failed.add(latency)
+		r.all.failed.add(latency)
+		return
+	}
+	r.current.ok.add(latency)
+	r.all.ok.add(latency)
+}
+
+// interval returns the operations since the previous call and starts a new interval
+func (r *recorder) interval() window {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := r.current
+	r.current = window{}
+	return w
+}
+
+// total returns every operation of the run so far
+func (r *recorder) total() window {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.all
+}
+
+// sample computes throughput and percentiles for a window that lasted d
+func (w window) sample(d time.Duration) Sample {
+	s := Sample{
+		Ops:      w.ok.n,
+		Errors:   w.failed.n,
+		P50:      w.ok.percentile(0.50),
+		P99:      w.ok.percentile(0.99),
+		P999:     w.ok.percentile(0.999),
+		Max:      w.ok.max,
+		ErrorP50: w.failed.percentile(0.50),
+		ErrorP99: w.failed.percentile(0.99),
+		ErrorMax: w.failed.max,
+	}
+	if d > 0 {
+		s.Throughput = float64(w.ok.n) / d.Seconds()
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report prints samples as a table, one row per interval, and the summary of a run
+type Report struct {
+	w        io.Writer
+	lastView string
+}
+
+// NewReport creates a report written to w
+func NewReport(w io.Writer) *Report {
+	return &Report{w: w}
+}
+
+// Header prints the column titles of the table
+func (r *Report) Header() {
+	fmt.Fprintf(r.w, "%8s %10s %7s %10s %10s %10s %10s %10s  %s\n",
+		"elapsed", "ops/s", "errors", "p50", "p99", "p999", "max", "err p99", "view")
+}
+
+// Sample prints the row of one interval, marking a view different from the
+// previous row's. It has the signature of Run's report callback.
+func (r *Report) Sample(s Sample) {
+	marker := ""
+	if r.lastView != "" && s.View != r.lastView {
+		marker = "  <- view change"
+	}
+	r.lastView = s.View
+	fmt.Fprintf(r.w, "%8s %10.1f %7d %10v %10v %10v %10v %10v  %s%s\n",
+		s.Elapsed.Round(time.Second), s.Throughput, s.Errors, round(s.P50), round(s.P99), round(s.P999),
+		round(s.Max), round(s.ErrorP99), s.View, marker)
+}
+
+// Summary prints the totals of a run, with the latency of failed operations if any
+func (r *Report) Summary(total Sample) {
+	fmt.Fprintf(r.w, "\nSummary: %d ops, %d errors in %v\n", total.Ops, total.Errors, total.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(r.w, "Throughput: %.1f ops/s\n", total.Throughput)
+	fmt.Fprintf(r.w, "Latency: p50=%v p99=%v p999=%v max=%v\n", round(total.P50), round(total.P99), round(total.P999), round(total.Max))
+	if total.Errors > 0 {
+		fmt.Fprintf(r.w, "Failed latency: p50=%v p99=%v max=%v\n", round(total.ErrorP50), round(total.ErrorP99), round(total.ErrorMax))
+	}
+}
+
+// round shortens latencies for display
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	var out strings.Builder
+	r := NewReport(&out)
+	r.Header()
+	r.Sample(Sample{Elapsed: 1001 * time.Millisecond, Ops: 1500, Throughput: 1499.5,
+		P50: 1234567 * time.Nanosecond, P99: 5 * time.Millisecond, P999: 9 * time.Millisecond, Max: 12 * time.Millisecond,
+		View: "v3 kv1/kv2"})
+	r.Sample(Sample{Elapsed: 2 * time.Second, Throughput: 0, Errors: 4, ErrorP99: 2 * time.Second, View: "v4 kv2/"})
+	r.Sample(Sample{Elapsed: 3 * time.Second, Throughput: 800, View: "v4 kv2/"})
+
+	want := ` elapsed      ops/s  errors        p50        p99       p999        max    err p99  view
+      1s     1499.5       0    1.235ms        5ms        9ms       12ms         0s  v3 kv1/kv2
+      2s        0.0       4         0s         0s         0s         0s         2s  v4 kv2/  <- view change
+      3s      800.0       0         0s         0s         0s         0s         0s  v4 kv2/
+`
+	if got := out.String(); got != want {
+		t.Fatalf("report:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	total := Sample{Elapsed: 30*time.Second + 1234567*time.Nanosecond, Ops: 1000, Throughput: 33.3333,
+		P50: time.Millisecond, P99: 2 * time.Millisecond, P999: 3 * time.Millisecond, Max: 4 * time.Millisecond}
+	tests := []struct {
+		name   string
+		errors int
+		want   string
+	}{
+		{"without errors", 0, `
+Summary: 1000 ops, 0 errors in 30.001s
+Throughput: 33.3 ops/s
+Latency: p50=1ms p99=2ms p999=3ms max=4ms
+`},
+		{"with errors", 2, `
+Summary: 1000 ops, 2 errors in 30.001s
+Throughput: 33.3 ops/s
+Latency: p50=1ms p99=2ms p999=3ms max=4ms
+Failed latency: p50=1s p99=2s max=2.5s
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := total
+			s.Errors = tt.errors
+			if tt.errors > 0 {
+				s.ErrorP50, s.ErrorP99, s.ErrorMax = time.Second, 2*time.Second, 2500*time.Millisecond
+			}
+			var out strings.Builder
+			NewReport(&out).Summary(s)
+			if got := out.String(); got != tt.want {
+				t.Fatalf("summary:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,3 +1,7 @@
 go build -o ./bin/viewServer ./view/view_server.go
 go build -o ./bin/kvServer ./kv_server_main/kv_server_main.go
-go build -o ./bin/client ./client_main/client.go
\ No newline at end of file
+go build -o ./bin/client ./client_main/client.go
+go build -o ./bin/bench ./bench_main/bench.go
+go build -o ./bin/sim ./sim_main/sim.go
+go build -o ./bin/chaos ./chaos_main/chaos.go
+go build -o ./bin/gateway ./gateway_main/gateway.go
\ No newline at end of file
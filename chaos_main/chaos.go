@@ -0,0 +1,81 @@
+// Package main soaks a multi-process cluster with random server kills and pauses.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"goDistributedSystemDemo/chaos_main/chaos"
+	"goDistributedSystemDemo/client_main/client"
+)
+
+func main() {
+	reportFile := flag.String("report", "chaos_report.txt", "File to write the summary and timeline to")
+	verbose := flag.Bool("v", false, "Show client retry logs")
+
+	cfg := chaos.Config{}
+	flag.StringVar(&cfg.ViewServerBin, "vs-bin", "./bin/viewServer", "View service binary")
+	flag.StringVar(&cfg.KVServerBin, "kv-bin", "./bin/kvServer", "KV server binary")
+	flag.StringVar(&cfg.Host, "host", "localhost", "Host the servers listen on")
+	flag.IntVar(&cfg.BasePort, "port", 9300, "View service port; KV servers use the following ports")
+	flag.IntVar(&cfg.Servers, "servers", 3, "Number of KV servers")
+	flag.DurationVar(&cfg.Duration, "d", time.Hour, "How long to inject faults")
+	flag.DurationVar(&cfg.FaultEvery, "fault-every", 10*time.Second, "Mean time between faults")
+	flag.DurationVar(&cfg.MinOutage, "min-outage", time.Second, "Shortest time a server stays killed or paused")
+	flag.DurationVar(&cfg.MaxOutage, "max-outage", 10*time.Second, "Longest time a server stays killed or paused")
+	flag.IntVar(&cfg.MaxDown, "max-down", 1, "Servers that may be killed or paused at the same time")
+	flag.IntVar(&cfg.Workers, "c", 4, "Concurrent client workers")
+	flag.IntVar(&cfg.Keys, "keys", 50, "Keys per worker")
+	flag.Float64Var(&cfg.ReadRatio, "reads", 0.5, "Fraction of operations that are gets (0-1)")
+	flag.DurationVar(&cfg.OpTimeout, "timeout", client.DefaultOpTimeout, "Deadline for each client operation")
+	flag.StringVar(&cfg.LogDir, "logs", "./log/chaos", "Directory for the servers' output")
+	flag.Uint64Var(&cfg.Seed, "seed", uint64(time.Now().UnixNano()), "Seed for fault and workload choices")
+	flag.Parse()
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *verbose {
+		// Retries are logged at debug level
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	fmt.Printf("Starting chaos run\n")
+	fmt.Printf("Servers=%d Duration=%v FaultEvery=%v Outage=%v-%v MaxDown=%d Workers=%d Seed=%d\n",
+		cfg.Servers, cfg.Duration, cfg.FaultEvery, cfg.MinOutage, cfg.MaxOutage, cfg.MaxDown, cfg.Workers, cfg.Seed)
+
+	// Stop injecting early on interrupt, but still heal and verify
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := chaos.Run(ctx, cfg, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n")
+	f, err := os.Create(*reportFile)
+	if err != nil {
+		fmt.Printf("Failed to write report: %v\n", err)
+	} else {
+		report.Write(f)
+		f.Close()
+		fmt.Printf("Report written to %s\n", *reportFile)
+	}
+	report.WriteSummary(os.Stdout)
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
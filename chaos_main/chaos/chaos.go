@@ -0,0 +1,467 @@
+// Package chaos soaks a real multi-process cluster: it launches the view service
+// and KV server binaries, kills, stops, restarts, pauses and resumes KV servers at random
+// while a client workload runs, then checks that no acknowledged write was lost.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	pb "goDistributedSystemDemo/proto"
+)
+
+// Config describes one chaos run
+type Config struct {
+	ViewServerBin string        // path of the view service binary
+	KVServerBin   string        // path of the KV server binary
+	Host          string        // host all servers listen on
+	BasePort      int           // view service port; KV servers use the following ports
+	Servers       int           // number of KV servers
+	Duration      time.Duration // how long to inject faults and run the workload
+	FaultEvery    time.Duration // mean time between faults
+	MinOutage     time.Duration // shortest time a server stays killed or paused
+	MaxOutage     time.Duration // longest time a server stays killed or paused
+	MaxDown       int           // servers that may be killed or paused at the same time
+	Workers       int           // concurrent client workers
+	Keys          int           // keys per worker
+	ReadRatio     float64       // fraction of operations that are gets, 0 to 1
+	OpTimeout     time.Duration // deadline for each client operation
+	LogDir        string        // directory for the servers' output
+	Seed          uint64        // seed for fault and workload choices
+}
+
+// Validate checks that the configuration can be run
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.Servers < 1:
+		return fmt.Errorf("chaos: at least 1 server is required")
+	case cfg.BasePort < 1 || cfg.BasePort+cfg.Servers > 65535:
+		return fmt.Errorf("chaos: ports %d-%d are out of range", cfg.BasePort, cfg.BasePort+cfg.Servers)
+	case cfg.Duration <= 0:
+		return fmt.Errorf("chaos: duration must be positive")
+	case cfg.FaultEvery <= 0:
+		return fmt.Errorf("chaos: fault interval must be positive")
+	case cfg.MinOutage <= 0 || cfg.MaxOutage < cfg.MinOutage:
+		return fmt.Errorf("chaos: outages must satisfy 0 < min <= max")
+	case cfg.MaxDown < 0:
+		return fmt.Errorf("chaos: max down must not be negative")
+	case cfg.Workers < 1:
+		return fmt.Errorf("chaos: at least 1 worker is required")
+	case cfg.Keys < 1:
+		return fmt.Errorf("chaos: at least 1 key per worker is required")
+	case cfg.ReadRatio < 0 || cfg.ReadRatio > 1:
+		return fmt.Errorf("chaos: read ratio must be between 0 and 1")
+	case cfg.OpTimeout <= 0:
+		return fmt.Errorf("chaos: operation timeout must be positive")
+	}
+	return nil
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Start      time.Time
+	Elapsed    time.Duration
+	Acked      int      // writes acknowledged to the client
+	Failed     int      // writes that returned an error
+	Reads      int      // successful reads
+	StaleReads int      // reads that missed an acknowledged write
+	Faults     int      // faults injected
+	Views      int      // view changes observed
+	Keys       int      // keys written and verified at the end
+	Lost       []string // keys whose last acknowledged write is gone
+	Unverified int      // keys that could not be read back
+	Timeline   []Event
+}
+
+// Passed reports whether every acknowledged write survived
+func (r Report) Passed() bool {
+	return len(r.Lost) == 0 && r.StaleReads == 0 && r.Unverified == 0
+}
+
+// Write prints the summary followed by the timeline
+func (r Report) Write(w io.Writer) {
+	r.WriteSummary(w)
+	fmt.Fprintf(w, "\nTimeline:\n")
+	for _, e := range r.Timeline {
+		fmt.Fprintf(w, "%10s  %-6s %s\n", e.Elapsed.Round(time.Millisecond), e.Kind, e.Detail)
+	}
+}
+
+// WriteSummary prints the totals and the verdict
+func (r Report) WriteSummary(w io.Writer) {
+	fmt.Fprintf(w, "Chaos run started %s, ran %v\n", r.Start.Format(time.RFC3339), r.Elapsed.Round(time.Second))
+	fmt.Fprintf(w, "Writes: %d acked, %d failed; reads: %d (%d stale)\n", r.Acked, r.Failed, r.Reads, r.StaleReads)
+	fmt.Fprintf(w, "Faults injected: %d; view changes: %d\n", r.Faults, r.Views)
+	fmt.Fprintf(w, "Verified %d keys: %d lost an acknowledged write, %d unreadable\n", r.Keys, len(r.Lost), r.Unverified)
+	for _, l := range r.Lost {
+		fmt.Fprintf(w, "  lost %s\n", l)
+	}
+	if r.Passed() {
+		fmt.Fprintf(w, "PASS\n")
+	} else {
+		fmt.Fprintf(w, "FAIL\n")
+	}
+}
+
+// Event kinds in the timeline
+const (
+	View  = "view"
+	Fault = "fault"
+	Check = "check"
+	Info  = "info"
+)
+
+// Event is one line of the timeline
+type Event struct {
+	Elapsed time.Duration // time since the run started
+	Kind    string
+	Detail  string
+}
+
+// timeline collects events from all goroutines and echoes them as they happen
+type timeline struct {
+	mu     sync.Mutex
+	start  time.Time
+	events []Event
+	out    io.Writer
+}
+
+func (tl *timeline) add(kind string, format string, args ...any) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	e := Event{Elapsed: time.Since(tl.start), Kind: kind, Detail: fmt.Sprintf(format, args...)}
+	tl.events = append(tl.events, e)
+	if tl.out != nil {
+		fmt.Fprintf(tl.out, "%10s  %-6s %s\n", e.Elapsed.Round(time.Millisecond), e.Kind, e.Detail)
+	}
+}
+
+func (tl *timeline) list() []Event {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]Event(nil), tl.events...)
+}
+
+// Timing of the runner itself
+const (
+	startTimeout  = 30 * time.Second       // time for the cluster to reach a stable view
+	settleTime    = 3 * time.Second        // a view must stay unchanged this long to count as stable
+	pollInterval  = 100 * time.Millisecond // how often views and pending recoveries are checked
+	verifyTimeout = 30 * time.Second       // deadline for reading back each key
+	stopWait      = 15 * time.Second       // time a server gets to hand off after SIGTERM
+)
+
+// recovery undoes a fault when its time comes
+type recovery struct {
+	at   time.Time
+	proc *process
+}
+
+// runner holds the processes of one run
+type runner struct {
+	cfg     Config
+	rng     *rand.Rand
+	vs      *process
+	servers []*process
+	tl      *timeline
+	faults  int
+	pending []recovery
+}
+
+// Run launches the cluster, injects faults for cfg.Duration (or until ctx ends)
+// while the workload runs, heals the cluster and verifies the data. Events are
+// echoed to progress as they happen if it is not nil.
+func Run(ctx context.Context, cfg Config, progress io.Writer) (Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return Report{}, err
+	}
+	if err := os.MkdirAll(cfg.LogDir, 0o755); err != nil {
+		return Report{}, err
+	}
+
+	r := &runner{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, 0)),
+		tl:  &timeline{start: time.Now(), out: progress},
+	}
+	defer r.stopAll()
+	if err := r.launch(); err != nil {
+		return Report{}, err
+	}
+
+	vsAddr := r.vs.name
+	ck, err := client.MakeClient(vsAddr, client.WithOpTimeout(cfg.OpTimeout))
+	if err != nil {
+		return Report{}, err
+	}
+	defer ck.Close()
+
+	if _, err := r.waitStable(ctx, ck); err != nil {
+		return Report{}, err
+	}
+	r.tl.add(Info, "cluster stable, starting workload and faults")
+
+	// Watch the view for the whole run
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	views := make(chan int, 1)
+	go func() { views <- r.watchViews(watchCtx, ck) }()
+
+	// Run the workload while injecting faults
+	runCtx, stopRun := context.WithTimeout(ctx, cfg.Duration)
+	defer stopRun()
+	t := newTracker()
+	n := &counters{}
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Workers; w++ {
+		wck, err := client.MakeClient(vsAddr, client.WithOpTimeout(cfg.OpTimeout))
+		if err != nil {
+			stopRun()
+			wg.Wait()
+			stopWatch()
+			return Report{}, err
+		}
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer wck.Close()
+			worker(runCtx, w, cfg, wck, t, n, r.tl)
+		}(w)
+	}
+	r.injectFaults(runCtx)
+	wg.Wait()
+
+	// Heal everything and check the data
+	r.tl.add(Info, "workload stopped, healing the cluster")
+	r.healAll()
+	if _, err := r.waitStable(context.Background(), ck); err != nil {
+		r.tl.add(Info, "%v", err)
+	}
+	verifyCk, err := client.MakeClient(vsAddr, client.WithOpTimeout(verifyTimeout))
+	if err != nil {
+		stopWatch()
+		return Report{}, err
+	}
+	defer verifyCk.Close()
+	lost, unread := verify(context.Background(), verifyCk, t)
+	r.tl.add(Check, "verified %d keys: %d lost, %d unreadable", len(t.names()), len(lost), unread)
+
+	stopWatch()
+	report := Report{
+		Start:      r.tl.start,
+		Elapsed:    time.Since(r.tl.start),
+		Acked:      n.acked,
+		Failed:     n.failed,
+		Reads:      n.reads,
+		StaleReads: n.staleReads,
+		Faults:     r.faults,
+		Views:      <-views,
+		Keys:       len(t.names()),
+		Lost:       lost,
+		Unverified: unread,
+		Timeline:   r.tl.list(),
+	}
+	return report, nil
+}
+
+// launch starts the view service and the KV servers
+func (r *runner) launch() error {
+	cfg := r.cfg
+	vsAddr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.BasePort))
+	vs, err := newProcess(vsAddr, cfg.ViewServerBin, []string{"-addr", vsAddr}, cfg.LogDir, "view_server")
+	if err != nil {
+		return err
+	}
+	r.vs = vs
+	if err := vs.start(); err != nil {
+		return err
+	}
+	r.tl.add(Info, "started view service %s (pid %d)", vsAddr, vs.pid())
+
+	for i := 1; i <= cfg.Servers; i++ {
+		addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.BasePort+i))
+		p, err := newProcess(addr, cfg.KVServerBin, []string{"-addr", addr, "-vs", vsAddr}, cfg.LogDir, fmt.Sprintf("kv_server_%d", cfg.BasePort+i))
+		if err != nil {
+			return err
+		}
+		r.servers = append(r.servers, p)
+		if err := p.start(); err != nil {
+			return err
+		}
+		r.tl.add(Info, "started KV server %s (pid %d)", addr, p.pid())
+	}
+	return nil
+}
+
+// waitStable waits until the view has a primary and a backup and stays unchanged
+// for settleTime
+func (r *runner) waitStable(ctx context.Context, ck *client.Client) (*pb.View, error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	var last *pb.View
+	var since time.Time
+	for {
+		if v, err := ck.View(ctx); err == nil {
+			wantBackup := r.cfg.Servers > 1
+			if last == nil || v.ViewNumber != last.ViewNumber {
+				last, since = v, time.Now()
+			} else if v.Primary != "" && (v.Backup != "" || !wantBackup) && time.Since(since) >= settleTime {
+				return v, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return last, fmt.Errorf("chaos: no stable view within %v", startTimeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// watchViews records the view whenever it changes until ctx ends and returns the
+// number of view changes
+func (r *runner) watchViews(ctx context.Context, ck *client.Client) int {
+	var last uint64
+	changes := 0
+	for ctx.Err() == nil {
+		if v, err := ck.View(ctx); err == nil && v.ViewNumber != last {
+			if last != 0 {
+				changes += int(v.ViewNumber - last) // views between polls are counted too
+			}
+			last = v.ViewNumber
+			r.tl.add(View, "view %d: primary=%s backup=%s", v.ViewNumber, v.Primary, v.Backup)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(pollInterval):
+		}
+	}
+	return changes
+}
+
+// injectFaults kills, stops or pauses a random running server every FaultEvery on average,
+// and undoes each fault after a random outage, until ctx ends
+func (r *runner) injectFaults(ctx context.Context) {
+	next := time.Now().Add(r.between(r.cfg.FaultEvery/2, r.cfg.FaultEvery*3/2))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+
+		r.recoverDue(time.Now())
+		if time.Now().Before(next) {
+			continue
+		}
+		next = time.Now().Add(r.between(r.cfg.FaultEvery/2, r.cfg.FaultEvery*3/2))
+		r.injectOne()
+	}
+}
+
+// injectOne applies one fault to a random running server, if the MaxDown budget allows
+func (r *runner) injectOne() {
+	var running []*process
+	down := 0
+	for _, p := range r.servers {
+		if p.status() == Running {
+			running = append(running, p)
+		} else {
+			down++
+		}
+	}
+	if down >= r.cfg.MaxDown || len(running) == 0 {
+		return
+	}
+
+	p := running[r.rng.IntN(len(running))]
+	outage := r.between(r.cfg.MinOutage, r.cfg.MaxOutage)
+	pid := p.pid()
+	var err error
+	switch r.rng.IntN(3) {
+	case 0:
+		if err = p.kill(); err == nil {
+			r.tl.add(Fault, "kill %s (pid %d) for %v", p.name, pid, outage.Round(time.Millisecond))
+		}
+	case 1:
+		start := time.Now()
+		if err = p.stop(stopWait); err == nil {
+			r.tl.add(Fault, "stop %s (pid %d) with SIGTERM, exited after %v, down for %v",
+				p.name, pid, time.Since(start).Round(time.Millisecond), outage.Round(time.Millisecond))
+		}
+	case 2:
+		if err = p.pause(); err == nil {
+			r.tl.add(Fault, "pause %s (pid %d) for %v", p.name, pid, outage.Round(time.Millisecond))
+		}
+	}
+	if err != nil {
+		r.tl.add(Fault, "%v", err)
+		return
+	}
+	r.faults++
+	r.pending = append(r.pending, recovery{at: time.Now().Add(outage), proc: p})
+}
+
+// recoverDue undoes the faults whose outage has ended
+func (r *runner) recoverDue(now time.Time) {
+	kept := r.pending[:0]
+	for _, rec := range r.pending {
+		if now.Before(rec.at) {
+			kept = append(kept, rec)
+			continue
+		}
+		r.recover(rec.proc)
+	}
+	r.pending = kept
+}
+
+// recover restarts a killed server or resumes a paused one
+func (r *runner) recover(p *process) {
+	var err error
+	switch p.status() {
+	case Killed:
+		if err = p.start(); err == nil {
+			r.tl.add(Fault, "restart %s (pid %d)", p.name, p.pid())
+		}
+	case Paused:
+		if err = p.resume(); err == nil {
+			r.tl.add(Fault, "resume %s (pid %d)", p.name, p.pid())
+		}
+	}
+	if err != nil {
+		r.tl.add(Fault, "%v", err)
+	}
+}
+
+// healAll undoes every outstanding fault and restarts servers that exited
+func (r *runner) healAll() {
+	r.pending = nil
+	for _, p := range r.servers {
+		r.recover(p)
+	}
+}
+
+// stopAll kills every process
+func (r *runner) stopAll() {
+	for _, p := range r.servers {
+		p.kill()
+	}
+	if r.vs != nil {
+		r.vs.kill()
+	}
+}
+
+// between returns a random duration in [lo, hi]
+func (r *runner) between(lo time.Duration, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(r.rng.Int64N(int64(hi-lo)+1))
+}
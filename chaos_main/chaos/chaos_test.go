@@ -0,0 +1,133 @@
+package chaos
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Config{
+		BasePort:   8000,
+		Servers:    3,
+		Duration:   time.Minute,
+		FaultEvery: 5 * time.Second,
+		MinOutage:  time.Second,
+		MaxOutage:  5 * time.Second,
+		MaxDown:    1,
+		Workers:    4,
+		Keys:       10,
+		ReadRatio:  0.5,
+		OpTimeout:  time.Second,
+	}
+	tests := []struct {
+		name    string
+		change  func(cfg *Config)
+		wantErr string // substring of the error, "" for none
+	}{
+		{"valid", func(cfg *Config) {}, ""},
+		{"one server", func(cfg *Config) { cfg.Servers = 1 }, ""},
+		{"no servers", func(cfg *Config) { cfg.Servers = 0 }, "at least 1 server"},
+		{"no base port", func(cfg *Config) { cfg.BasePort = 0 }, "out of range"},
+		{"servers past the last port", func(cfg *Config) { cfg.BasePort = 65533 }, "out of range"},
+		{"servers up to the last port", func(cfg *Config) { cfg.BasePort = 65532 }, ""},
+		{"no duration", func(cfg *Config) { cfg.Duration = 0 }, "duration"},
+		{"no fault interval", func(cfg *Config) { cfg.FaultEvery = 0 }, "fault interval"},
+		{"no minimum outage", func(cfg *Config) { cfg.MinOutage = 0 }, "outages"},
+		{"maximum outage below minimum", func(cfg *Config) { cfg.MaxOutage = cfg.MinOutage - 1 }, "outages"},
+		{"fixed outage", func(cfg *Config) { cfg.MaxOutage = cfg.MinOutage }, ""},
+		{"no faults at once", func(cfg *Config) { cfg.MaxDown = 0 }, ""},
+		{"negative max down", func(cfg *Config) { cfg.MaxDown = -1 }, "max down"},
+		{"no workers", func(cfg *Config) { cfg.Workers = 0 }, "worker"},
+		{"no keys", func(cfg *Config) { cfg.Keys = 0 }, "key"},
+		{"only writes", func(cfg *Config) { cfg.ReadRatio = 0 }, ""},
+		{"only reads", func(cfg *Config) { cfg.ReadRatio = 1 }, ""},
+		{"read ratio above 1", func(cfg *Config) { cfg.ReadRatio = 1.1 }, "read ratio"},
+		{"negative read ratio", func(cfg *Config) { cfg.ReadRatio = -0.1 }, "read ratio"},
+		{"no operation timeout", func(cfg *Config) { cfg.OpTimeout = 0 }, "operation timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.change(&cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want an error about %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRecoverDue undoes the faults whose outage has ended and keeps the rest
+// pending. Each server is a sleep process standing in for a KV server.
+func TestRecoverDue(t *testing.T) {
+	bin, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep binary to run as a server")
+	}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		state     string        // state of the server when recoverDue runs
+		at        time.Duration // end of the outage, relative to now
+		wantState string
+		wantKept  bool
+		wantEvent string // timeline event logged, "" for none
+	}{
+		{"killed server past its outage is restarted", Killed, -time.Second, Running, false, "restart"},
+		{"killed server at the end of its outage is restarted", Killed, 0, Running, false, "restart"},
+		{"paused server past its outage is resumed", Paused, -time.Second, Running, false, "resume"},
+		{"killed server during its outage stays down", Killed, time.Second, Killed, true, ""},
+		{"paused server during its outage stays paused", Paused, time.Second, Paused, true, ""},
+		{"server that came back by itself is left alone", Running, -time.Second, Running, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newProcess("kv1", bin, []string{"60"}, t.TempDir(), "kv1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { p.kill() })
+			if tt.state != Killed {
+				if err := p.start(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.state == Paused {
+				if err := p.pause(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := &runner{tl: &timeline{start: now}, servers: []*process{p}}
+			later := recovery{at: now.Add(time.Hour), proc: p}
+			r.pending = []recovery{{at: now.Add(tt.at), proc: p}, later}
+			r.recoverDue(now)
+
+			if got := p.status(); got != tt.wantState {
+				t.Errorf("server is %s, want %s", got, tt.wantState)
+			}
+			wantPending := 1
+			if tt.wantKept {
+				wantPending = 2
+			}
+			if len(r.pending) != wantPending || r.pending[len(r.pending)-1] != later {
+				t.Errorf("%d recoveries pending, want %d ending with the later one", len(r.pending), wantPending)
+			}
+			events := r.tl.list()
+			switch {
+			case tt.wantEvent == "" && len(events) > 0:
+				t.Errorf("logged %q, want nothing", events[0].Detail)
+			case tt.wantEvent != "" && (len(events) != 1 || !strings.HasPrefix(events[0].Detail, tt.wantEvent+" kv1")):
+				t.Errorf("logged %v, want one %q event", events, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Process states
+const (
+	Running = "running"
+	Killed  = "killed"
+	Paused  = "paused"
+)
+
+// process is one server binary under the runner's control. It can be killed and
+// started again on the same address, and paused with SIGSTOP.
+type process struct {
+	mu    sync.Mutex
+	name  string // address the server listens on
+	bin   string
+	args  []string
+	log   io.Writer // receives the output of every incarnation
+	cmd   *exec.Cmd
+	state string
+	exit  chan struct{} // closed when the current incarnation exits
+}
+
+// newProcess prepares a process whose output is appended to logDir/<file>.log
+func newProcess(name string, bin string, args []string, logDir string, file string) (*process, error) {
+	f, err := os.OpenFile(filepath.Join(logDir, file+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &process{name: name, bin: bin, args: args, log: f, state: Killed}, nil
+}
+
+// start launches a new incarnation
+func (p *process) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != Killed {
+		return fmt.Errorf("chaos: %s is already %s", p.name, p.state)
+	}
+
+	cmd := exec.Command(p.bin, p.args...)
+	cmd.Stdout = p.log
+	cmd.Stderr = p.log
+	// Own process group, so an interrupt from the terminal reaches only the runner,
+	// which then verifies the data before stopping the cluster
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("chaos: starting %s: %w", p.name, err)
+	}
+	exit := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exit)
+	}()
+	p.cmd = cmd
+	p.exit = exit
+	p.state = Running
+	return nil
+}
+
+// kill sends SIGKILL and waits for the process to exit. A paused process is
+// killed too.
+func (p *process) kill() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == Killed {
+		return nil
+	}
+	if err := p.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("chaos: killing %s: %w", p.name, err)
+	}
+	<-p.exit
+	p.state = Killed
+	return nil
+}
+
+// stop sends SIGTERM so the server can hand off, and waits for it to exit. It is
+// killed if it is still running after wait.
+func (p *process) stop(wait time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != Running {
+		return fmt.Errorf("chaos: %s is %s, not %s", p.name, p.state, Running)
+	}
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("chaos: sending %v to %s: %w", syscall.SIGTERM, p.name, err)
+	}
+	select {
+	case <-p.exit:
+	case <-time.After(wait):
+		p.cmd.Process.Kill()
+		<-p.exit
+	}
+	p.state = Killed
+	return nil
+}
+
+// pause stops the process with SIGSTOP
+func (p *process) pause() error {
+	return p.signal(Running, Paused, syscall.SIGSTOP)
+}
+
+// resume continues a paused process with SIGCONT
+func (p *process) resume() error {
+	return p.signal(Paused, Running, syscall.SIGCONT)
+}
+
+// signal sends sig if the process is in state from and moves it to state to
+func (p *process) signal(from string, to string, sig syscall.Signal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != from {
+		return fmt.Errorf("chaos: %s is %s, not %s", p.name, p.state, from)
+	}
+	if err := p.cmd.Process.Signal(sig); err != nil {
+		return fmt.Errorf("chaos: sending %v to %s: %w", sig, p.name, err)
+	}
+	p.state = to
+	return nil
+}
+
+// status returns the process state, noticing incarnations that exited by themselves
+func (p *process) status() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != Killed {
+		select {
+		case <-p.exit:
+			p.state = Killed
+		default:
+		}
+	}
+	return p.state
+}
+
+// pid returns the process id of the current incarnation, or 0
+func (p *process) pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == Killed {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
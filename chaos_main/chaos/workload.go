@@ -0,0 +1,163 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+
+	"goDistributedSystemDemo/client_main/client"
+)
+
+// keyState is what a key may hold given the writes issued to it so far
+type keyState struct {
+	acked     string   // last acknowledged value
+	hasAcked  bool     // whether any write was acknowledged
+	uncertain []string // values written after the last ack whose outcome is unknown
+}
+
+// tracker remembers the writes to every key. Each key is written by one worker
+// only, one operation at a time, so its possible values are known exactly.
+type tracker struct {
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+func newTracker() *tracker {
+	return &tracker{keys: make(map[string]*keyState)}
+}
+
+// begin records that value is about to be written to key
+func (t *tracker) begin(key string, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ks := t.state(key)
+	ks.uncertain = append(ks.uncertain, value)
+}
+
+// ack records that the write of value to key was acknowledged
+func (t *tracker) ack(key string, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ks := t.state(key)
+	ks.acked = value
+	ks.hasAcked = true
+	ks.uncertain = nil
+}
+
+// allowed reports whether a read of key may return value (found) or nothing
+func (t *tracker) allowed(key string, value string, found bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ks := t.state(key)
+	if !found {
+		return !ks.hasAcked
+	}
+	return (ks.hasAcked && value == ks.acked) || slices.Contains(ks.uncertain, value)
+}
+
+// expected describes what key should hold, for reports
+func (t *tracker) expected(key string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ks := t.state(key)
+	if len(ks.uncertain) == 0 {
+		return fmt.Sprintf("%q", ks.acked)
+	}
+	return fmt.Sprintf("%q or one of %q", ks.acked, ks.uncertain)
+}
+
+// names returns the keys written so far, sorted
+func (t *tracker) names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := make([]string, 0, len(t.keys))
+	for k := range t.keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// state returns the entry for key, creating it; t.mu must be held
+func (t *tracker) state(key string) *keyState {
+	ks, ok := t.keys[key]
+	if !ok {
+		ks = &keyState{}
+		t.keys[key] = ks
+	}
+	return ks
+}
+
+// counters are the workload's operation totals
+type counters struct {
+	mu         sync.Mutex
+	acked      int
+	failed     int
+	reads      int
+	staleReads int
+}
+
+func (c *counters) add(f func(c *counters)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(c)
+}
+
+// worker writes and reads its own keys in a closed loop until ctx ends
+func worker(ctx context.Context, id int, cfg Config, ck *client.Client, t *tracker, n *counters, tl *timeline) {
+	rng := rand.New(rand.NewPCG(cfg.Seed, uint64(id)))
+	for seq := 1; ctx.Err() == nil; seq++ {
+		key := fmt.Sprintf("chaos-%d-%d", id, rng.IntN(cfg.Keys))
+
+		if rng.Float64() < cfg.ReadRatio {
+			value, found, err := ck.Get(ctx, key)
+			if err != nil {
+				continue
+			}
+			if !t.allowed(key, value, found) {
+				n.add(func(c *counters) { c.reads++; c.staleReads++ })
+				tl.add(Check, "stale read of %s: got %q, expected %s", key, value, t.expected(key))
+				continue
+			}
+			n.add(func(c *counters) { c.reads++ })
+			continue
+		}
+
+		value := fmt.Sprintf("%d-%d", id, seq)
+		t.begin(key, value)
+		if err := ck.Put(ctx, key, value); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				n.add(func(c *counters) { c.failed++ })
+			}
+			continue
+		}
+		t.ack(key, value)
+		n.add(func(c *counters) { c.acked++ })
+	}
+}
+
+// verify reads back every key written and returns those that lost an acknowledged
+// write, plus the number of keys that could not be read
+func verify(ctx context.Context, ck *client.Client, t *tracker) ([]string, int) {
+	var lost []string
+	unread := 0
+	for _, key := range t.names() {
+		value, found, err := ck.Get(ctx, key)
+		if err != nil {
+			unread++
+			continue
+		}
+		if !t.allowed(key, value, found) {
+			got := fmt.Sprintf("%q", value)
+			if !found {
+				got = "<no key>"
+			}
+			lost = append(lost, fmt.Sprintf("%s: got %s, expected %s", key, got, t.expected(key)))
+		}
+	}
+	return lost, unread
+}
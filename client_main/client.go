@@ -20,13 +20,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"goDistributedSystemDemo/auth"
 	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/client_main/shell"
+	"goDistributedSystemDemo/logging"
+	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
+
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func main() {
@@ -42,27 +53,145 @@ func main() {
 	opsStr := flag.String("ops", "", "Comma-separated operations sequence, e.g. get,put")
 	keysStr := flag.String("keys", "", "Comma-separated keys corresponding to ops (optional)")
 	valuesStr := flag.String("values", "", "Comma-separated values for put ops (optional)")
+	timeout := flag.Duration("timeout", client.DefaultOpTimeout, "Deadline for each operation")
+	forcePrimary := flag.String("force-primary", "", "Operator override: make this server primary of a new view, then exit")
+	exportViews := flag.String("export-views", "", "Write the view service's recorded view changes to this file as JSON lines, then exit")
+
+	// Interactive mode flags
+	interactive := flag.Bool("i", false, "Start an interactive shell instead of running -op/-ops")
+	historyFile := flag.String("history", defaultHistoryFile(), "File for interactive shell history (empty to disable)")
+
+	// Retry policy flags
+	retry := client.DefaultRetryPolicy
+	flag.DurationVar(&retry.InitialBackoff, "retry-initial", retry.InitialBackoff, "Backoff after the first failed attempt")
+	flag.DurationVar(&retry.MaxBackoff, "retry-max", retry.MaxBackoff, "Maximum backoff between attempts")
+	flag.Float64Var(&retry.Multiplier, "retry-multiplier", retry.Multiplier, "Backoff growth factor per failed attempt")
+	flag.Float64Var(&retry.Jitter, "retry-jitter", retry.Jitter, "Fraction of each backoff that is randomised (0-1)")
+	flag.IntVar(&retry.MaxAttempts, "retry-attempts", retry.MaxAttempts, "Maximum attempts per operation, 0 for no limit")
+
+	logLevel := flag.String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	traceFile := flag.String("trace-file", "", "Append trace spans to this file as JSON lines (disabled if empty)")
+	traceOTLP := flag.String("trace-otlp", "", "Send trace spans to this OTLP/gRPC collector, host:port without TLS (disabled if empty)")
+	traceSample := flag.Float64("trace-sample", 1, "Fraction of new traces recorded (0-1)")
+	tlsCert := flag.String("tls-cert", "", "Client certificate to present to servers that ask for one (PEM, optional)")
+	tlsKey := flag.String("tls-key", "", "Private key of -tls-cert (PEM)")
+	tlsCA := flag.String("tls-ca", "", "CA certificates that sign the servers' certificates (PEM); setting any -tls flag enables TLS")
+	tokenFile := flag.String("token-file", "", "File holding a bearer token to authenticate with, instead of the TLS certificate (needs TLS)")
 
 	flag.Parse()
 
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// In the shell, log chatter would interleave with the prompt unless asked for
+	if *interactive && !flagSet("log-level") {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	slog.SetDefault(logger)
+
 	fmt.Printf("Starting test client\n")
 	fmt.Printf("View Service at %s\n", *vsAddr)
 	pid := os.Getpid()
 	fmt.Printf("PID: %d\n", pid)
 
-	ck := client.MakeClient(*vsAddr)
+	opts := []client.Option{client.WithOpTimeout(*timeout), client.WithRetryPolicy(retry)}
+	tlsCfg := tlsconfig.Config{CertFile: *tlsCert, KeyFile: *tlsKey, CAFile: *tlsCA}
+	if tlsCfg.Enabled() {
+		certs, err := tlsconfig.Load(tlsCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, client.WithTLS(certs))
+	}
+
+	if *tokenFile != "" {
+		tokenOpt, err := auth.TokenDialOption(*tokenFile, tlsCfg.Enabled())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, client.WithDialOptions(tokenOpt))
+	}
+
+	traceCfg := tracing.Config{Service: "client", Instance: "", File: *traceFile, OTLPEndpoint: *traceOTLP, SampleRatio: *traceSample}
+	if traceCfg.Enabled() {
+		tp, shutdown, err := tracing.New(context.Background(), traceCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer tracing.Flush(context.Background(), shutdown)
+		opts = append(opts, client.WithTracerProvider(tp))
+	}
+
+	ck, err := client.MakeClient(*vsAddr, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	defer ck.Close()
 
-	//retry indefinitely until we connect to primary
-	for ck.CurrentPrimary == "" {
-		ck.UpdatePrimary()
-		if ck.CurrentPrimary == "" {
-			fmt.Printf("Waiting for primary server...\n")
-			time.Sleep(1 * time.Second)
+	if *forcePrimary != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		view, err := ck.ForcePrimary(ctx, *forcePrimary)
+		cancel()
+		if err != nil {
+			fmt.Printf("Force primary failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Forced new view: ViewNumber=%d Primary=%s Backup=%s\n", view.ViewNumber, view.Primary, view.Backup)
+		return
+	}
+
+	if *exportViews != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		changes, err := ck.ListViews(ctx, 0, 0)
+		cancel()
+		if err == nil {
+			err = writeViews(*exportViews, changes)
+		}
+		if err != nil {
+			fmt.Printf("Export views failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d view changes to %s\n", len(changes), *exportViews)
+		return
+	}
+
+	if *interactive {
+		sh := shell.New(ck, os.Stdin, os.Stdout, *timeout)
+		if *historyFile != "" {
+			if err := sh.SetHistoryFile(*historyFile); err != nil {
+				fmt.Printf("Could not load history from %s: %v\n", *historyFile, err)
+			}
 		}
+		if err := sh.Run(); err != nil {
+			fmt.Printf("Shell error: %v\n", err)
+		}
+		return
 	}
 
-	fmt.Printf("Connected to primary server at %s\n", ck.CurrentPrimary)
+	//retry until we connect to primary or the deadline passes
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	for ck.Primary() == "" {
+		if err := ck.UpdatePrimary(ctx); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			fmt.Printf("No primary server available after %v\n", *timeout)
+			cancel()
+			os.Exit(1)
+		}
+		fmt.Printf("Waiting for primary server...\n")
+		time.Sleep(1 * time.Second)
+	}
+	cancel()
+
+	fmt.Printf("Connected to primary server at %s\n", ck.Primary())
 
 	// Helper to split comma-separated lists into trimmed slices (ignores empty entries)
 	splitTrim := func(s string) []string {
@@ -97,13 +226,62 @@ func main() {
 	// - value to use for put: values[i] (if present) else -value
 	for i, op := range ops {
 		if op == "get" {
-			val := ck.Get(keys[i])
-			fmt.Printf("Get(%s) = %s\n", keys[i], val)
+			val, found, err := ck.Get(context.Background(), keys[i])
+			if err != nil {
+				fmt.Printf("Get(%s) failed: %v\n", keys[i], err)
+			} else if !found {
+				fmt.Printf("Get(%s) = <no key>\n", keys[i])
+			} else {
+				fmt.Printf("Get(%s) = %s\n", keys[i], val)
+			}
 		} else if op == "put" {
-			ck.Put(keys[i], values[i])
-			fmt.Printf("Put(%s, %s) completed\n", keys[i], values[i])
+			if err := ck.Put(context.Background(), keys[i], values[i]); err != nil {
+				fmt.Printf("Put(%s, %s) failed: %v\n", keys[i], values[i], err)
+			} else {
+				fmt.Printf("Put(%s, %s) completed\n", keys[i], values[i])
+			}
 		} else {
 			fmt.Printf("Unknown client operation: %s\n", op)
 		}
 	}
 }
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// defaultHistoryFile returns the interactive history path in the user's home directory
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kv_client_history")
+}
+
+// writeViews writes view changes to path, one JSON object per line
+func writeViews(path string, changes []*pb.ViewChange) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		line, err := protojson.Marshal(change)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
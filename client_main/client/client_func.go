@@ -2,158 +2,570 @@ package client
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/logging"
 	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// Client is a client for the KV service
+const (
+	DefaultOpTimeout  = 10 * time.Second // Overall deadline for an operation whose context has none
+	DefaultRPCTimeout = 2 * time.Second  // Deadline for a single RPC attempt
+)
+
+// Client is a client for the KV service. It is safe for concurrent use; all
+// goroutines share one view service connection and one primary connection.
 type Client struct {
-	vsAddress      string
-	vsClient       pb.ViewServiceClient
-	vsConn         *grpc.ClientConn
-	CurrentPrimary string
-	primaryClient  pb.KVServerClient
-	primaryConn    *grpc.ClientConn
+	vsAddress string
+	vsClient  pb.ViewServiceClient
+	vsConn    *grpc.ClientConn
+
+	primary atomic.Pointer[primaryConn] // current primary, nil when unknown
+
+	mu         sync.Mutex
+	refreshing *refreshCall // in-flight view refresh shared by concurrent callers
+	closed     bool
+
+	opTimeout  time.Duration // applied when the caller's context has no deadline
+	rpcTimeout time.Duration // applied to every individual RPC attempt
+	retry      RetryPolicy   // backoff between attempts
+
+	dialOptions []grpc.DialOption     // extra options for the view service and primary connections
+	clock       clock.Clock           // source of time for deadlines and backoff
+	registerer  prometheus.Registerer // receives the client's metrics
+	metrics     *clientMetrics
+	logger      *slog.Logger
+	traces      trace.TracerProvider // receives spans for operations and the RPCs they make
+	tracer      trace.Tracer
+	tls         *tlsconfig.Certs // dials over TLS if not nil
+}
+
+// primaryConn is a connection to one primary; it is replaced as a whole on view change
+type primaryConn struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client pb.KVServerClient
+}
+
+// refreshCall is a GetView request that concurrent callers wait on together
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithOpTimeout sets the overall deadline used for operations whose context has none.
+// A zero duration disables the default deadline.
+func WithOpTimeout(d time.Duration) Option {
+	return func(ck *Client) { ck.opTimeout = d }
+}
+
+// WithRPCTimeout sets the deadline for each individual RPC attempt
+func WithRPCTimeout(d time.Duration) Option {
+	return func(ck *Client) { ck.rpcTimeout = d }
+}
+
+// WithDialOptions adds gRPC dial options to the client's connections
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(ck *Client) { ck.dialOptions = append(ck.dialOptions, opts...) }
+}
+
+// WithClock makes the client measure deadlines and backoff on c instead of the wall clock
+func WithClock(c clock.Clock) Option {
+	return func(ck *Client) { ck.clock = c }
 }
 
-// MakeClient creates a new client
-func MakeClient(vsAddress string) *Client {
+// WithMetrics registers the client's metrics with reg
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(ck *Client) { ck.registerer = reg }
+}
+
+// WithLogger makes the client log to l instead of the default logger. Retries
+// and connections are logged at debug level, failures at warn.
+func WithLogger(l *slog.Logger) Option {
+	return func(ck *Client) { ck.logger = l }
+}
+
+// WithTracerProvider makes the client record spans with tp instead of the global
+// tracer provider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(ck *Client) { ck.traces = tp }
+}
+
+// WithTLS makes the client connect over TLS, verifying servers against the CA in
+// certs and presenting its certificate, if any, to servers that ask for one
+func WithTLS(certs *tlsconfig.Certs) Option {
+	return func(ck *Client) { ck.tls = certs }
+}
+
+// MakeClient creates a new client. Connections are made lazily, so it fails only
+// when vsAddress or the dial options are invalid, not when the view service is down.
+func MakeClient(vsAddress string, opts ...Option) (*Client, error) {
 	ck := &Client{
-		vsAddress:      vsAddress,
-		CurrentPrimary: "",
+		vsAddress:  vsAddress,
+		opTimeout:  DefaultOpTimeout,
+		rpcTimeout: DefaultRPCTimeout,
+		retry:      DefaultRetryPolicy,
+		clock:      clock.Real,
+		logger:     slog.Default(),
+		traces:     otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(ck)
 	}
+	ck.tracer = ck.traces.Tracer("goDistributedSystemDemo/client")
+	if ck.registerer == nil {
+		ck.registerer = prometheus.NewRegistry()
+	}
+	ck.metrics = newClientMetrics(ck.registerer)
 
 	// Connect to view service
-	for {
-		conn, err := grpc.Dial(vsAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		if err == nil {
-			ck.vsConn = conn
-			ck.vsClient = pb.NewViewServiceClient(conn)
-			log.Printf("Client connected to view service at %s\n", vsAddress)
-			break
-		}
-		log.Printf("Failed to connect to view service, retrying...\n")
-		time.Sleep(1 * time.Second)
+	conn, err := ck.dial(vsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("client: view service %s: %w", vsAddress, err)
 	}
-
-	return ck
+	ck.vsConn = conn
+	ck.vsClient = pb.NewViewServiceClient(conn)
+	ck.logger.Debug("Client connected to view service", "addr", vsAddress)
+	return ck, nil
 }
 
-// Get retrieves the value for a key
-func (ck *Client) Get(key string) string {
+// Get retrieves the value for a key. found is false when the key does not exist.
+func (ck *Client) Get(ctx context.Context, key string) (value string, found bool, err error) {
 	req := &pb.GetRequest{Key: key}
 
-	for {
-		// Get current primary
-		if ck.CurrentPrimary == "" {
-			ck.UpdatePrimary()
-			if ck.CurrentPrimary == "" {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
+	err = ck.call(ctx, "Get", func(ctx context.Context, primary pb.KVServerClient) error {
+		resp, err := primary.Get(ctx, req)
+		if err != nil {
+			return err
 		}
-
-		// Try to call Get on primary
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		resp, err := ck.primaryClient.Get(ctx, req)
-		cancel()
-
-		if err == nil && resp.Ok {
-			return resp.Value
-		} else if err == nil && resp.Error == "ErrNoKey" {
-			return ""
-		} else if err != nil || resp.Error == "ErrNotPrimary" {
-			// Primary changed or failed, update and retry
-			log.Printf("Get failed, updating primary and retrying...\n")
-			ck.CurrentPrimary = ""
-			if ck.primaryConn != nil {
-				ck.primaryConn.Close()
-				ck.primaryConn = nil
-				ck.primaryClient = nil
-			}
-			time.Sleep(500 * time.Millisecond)
+		if err := replyError(resp.Ok, resp.Error); err != nil {
+			return err
 		}
+		value = resp.Value
+		return nil
+	})
+	if errors.Is(err, ErrNoKey) {
+		return "", false, nil
 	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
 }
 
 // Put stores a key-value pair
-func (ck *Client) Put(key string, value string) {
+func (ck *Client) Put(ctx context.Context, key string, value string) error {
 	req := &pb.PutRequest{Key: key, Value: value}
 
-	for {
+	return ck.call(ctx, "Put", func(ctx context.Context, primary pb.KVServerClient) error {
+		resp, err := primary.Put(ctx, req)
+		if err != nil {
+			return err
+		}
+		return replyError(resp.Ok, resp.Error)
+	})
+}
+
+// Delete removes a key. Deleting a key that does not exist succeeds.
+func (ck *Client) Delete(ctx context.Context, key string) error {
+	req := &pb.DeleteRequest{Key: key}
+
+	return ck.call(ctx, "Delete", func(ctx context.Context, primary pb.KVServerClient) error {
+		resp, err := primary.Delete(ctx, req)
+		if err != nil {
+			return err
+		}
+		return replyError(resp.Ok, resp.Error)
+	})
+}
+
+// KeyValue is one entry returned by Scan
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Scan returns up to limit entries whose keys start with prefix, in key order.
+// A limit of 0 returns every match.
+func (ck *Client) Scan(ctx context.Context, prefix string, limit int) ([]KeyValue, error) {
+	req := &pb.ScanRequest{Prefix: prefix, Limit: uint32(max(limit, 0))}
+
+	var entries []KeyValue
+	err := ck.call(ctx, "Scan", func(ctx context.Context, primary pb.KVServerClient) error {
+		resp, err := primary.Scan(ctx, req)
+		if err != nil {
+			return err
+		}
+		if err := replyError(resp.Ok, resp.Error); err != nil {
+			return err
+		}
+		entries = make([]KeyValue, 0, len(resp.Entries))
+		for _, e := range resp.Entries {
+			entries = append(entries, KeyValue{Key: e.Key, Value: e.Value})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// View returns the current view as reported by the view service
+func (ck *Client) View(ctx context.Context) (*pb.View, error) {
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	defer cancel()
+
+	resp, err := ck.vsClient.GetView(ctx, &pb.GetViewRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.View, nil
+}
+
+// ViewStatus returns the view service's full answer: the view, whether its
+// primary acknowledged it, and why the view service is blocked, if it is
+func (ck *Client) ViewStatus(ctx context.Context) (*pb.GetViewResponse, error) {
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	defer cancel()
+
+	return ck.vsClient.GetView(ctx, &pb.GetViewRequest{})
+}
+
+// ListServers returns every server the view service knows, in join order, with
+// the metadata each reported in its last ping
+func (ck *Client) ListServers(ctx context.Context) ([]*pb.ServerStatus, error) {
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	defer cancel()
+
+	resp, err := ck.vsClient.ListServers(ctx, &pb.ListServersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Servers, nil
+}
+
+// ListViews returns the view changes the view service recorded for views numbered
+// above afterView, oldest first, at most limit of them (0 for all it kept)
+func (ck *Client) ListViews(ctx context.Context, afterView uint64, limit uint32) ([]*pb.ViewChange, error) {
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	defer cancel()
+
+	resp, err := ck.vsClient.ListViews(ctx, &pb.ListViewsRequest{AfterView: afterView, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Changes, nil
+}
+
+// ForcePrimary asks the view service to make server primary at once. This is an
+// operator override for a blocked view service; writes held only by the replaced
+// primary are lost.
+func (ck *Client) ForcePrimary(ctx context.Context, server string) (*pb.View, error) {
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	defer cancel()
+
+	resp, err := ck.vsClient.ForcePrimary(ctx, &pb.ForcePrimaryRequest{ServerName: server})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Ok {
+		return resp.View, fmt.Errorf("client: cannot force %s to be primary: %s", server, resp.Error)
+	}
+	return resp.View, nil
+}
+
+// call runs attempt against the current primary until it succeeds, fails with a
+// non-retryable error, or the operation's deadline passes. Every attempt carries
+// the same request ID, the one in ctx if set with logging.WithRequestID, so the
+// operation can be found in the servers' logs.
+func (ck *Client) call(ctx context.Context, op string, attempt func(context.Context, pb.KVServerClient) error) (err error) {
+	ctx, cancel := ck.withDeadline(ctx)
+	defer cancel()
+	if logging.RequestID(ctx) == "" {
+		ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	}
+	ctx, span := ck.tracer.Start(ctx, "client."+op, trace.WithAttributes(tracing.RequestID(logging.RequestID(ctx))))
+
+	reachedPrimary := false
+	var lastErr error
+	start, attempts := ck.clock.Now(), 0
+	defer func() {
+		ck.metrics.observe(op, attempts, clock.Since(ck.clock, start), err)
+		span.SetAttributes(attribute.Int("attempts", attempts))
+		tracing.End(span, err)
+	}()
+
+	for n := 1; ; n++ {
+		attempts = n
+		if ctx.Err() != nil {
+			return deadlineError(ctx, reachedPrimary, lastErr)
+		}
+
 		// Get current primary
-		if ck.CurrentPrimary == "" {
-			ck.UpdatePrimary()
-			if ck.CurrentPrimary == "" {
-				time.Sleep(500 * time.Millisecond)
+		p := ck.primary.Load()
+		if p == nil {
+			var err error
+			if p, err = ck.refreshPrimary(ctx, nil); err != nil {
+				if denied(err) {
+					return err
+				}
+				lastErr = err
+				if ck.retry.exhausted(n) {
+					return exhaustedError(n, reachedPrimary, lastErr)
+				}
+				if !ck.backoff(ctx, n) {
+					return deadlineError(ctx, reachedPrimary, lastErr)
+				}
 				continue
 			}
 		}
 
-		// Try to call Put on primary
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		resp, err := ck.primaryClient.Put(ctx, req)
-		cancel()
-
-		if err == nil && resp.Ok {
-			return
-		} else if err != nil || resp.Error == "ErrNotPrimary" {
-			// Primary changed or failed, update and retry
-			log.Printf("Put failed, updating primary and retrying...\n")
-			ck.CurrentPrimary = ""
-			if ck.primaryConn != nil {
-				ck.primaryConn.Close()
-				ck.primaryConn = nil
-				ck.primaryClient = nil
-			}
-			time.Sleep(500 * time.Millisecond)
+		// Try the operation on the primary
+		rpcCtx, rpcCancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+		err := attempt(rpcCtx, p.client)
+		rpcCancel()
+
+		if err == nil {
+			return nil
+		}
+		if !retryable(ctx, rpcCtx, err) && !ck.replaced(ctx, p, err) {
+			return err
+		}
+		// A server that answered, even slowly, counts as reached; a refused connection does not
+		if status.Code(err) != codes.Unavailable {
+			reachedPrimary = true
+		}
+		lastErr = err
+		if ck.retry.exhausted(n) {
+			return exhaustedError(n, reachedPrimary, lastErr)
 		}
+
+		// Primary changed or failed, update and retry. Retry at once if the view moved on.
+		logging.For(ctx, ck.logger).Debug("Operation failed, updating primary and retrying",
+			"op", op, "attempt", n, "primary", p.addr, "err", err)
+		if next, err := ck.refreshPrimary(ctx, p); err == nil && next != p {
+			continue
+		}
+		if !ck.backoff(ctx, n) {
+			return deadlineError(ctx, reachedPrimary, lastErr)
+		}
+	}
+}
+
+// withDeadline applies the client's default operation timeout when ctx has no deadline
+func (ck *Client) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || ck.opTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return clock.WithTimeout(ctx, ck.clock, ck.opTimeout)
+}
+
+// Primary returns the address of the primary the client currently talks to,
+// or "" if it does not know one
+func (ck *Client) Primary() string {
+	if p := ck.primary.Load(); p != nil {
+		return p.addr
 	}
+	return ""
 }
 
-// UpdatePrimary queries the view service for the current primary
-func (ck *Client) UpdatePrimary() {
-	req := &pb.GetViewRequest{}
+// UpdatePrimary queries the view service for the current primary.
+// It returns ErrUnavailable when the view has no primary.
+func (ck *Client) UpdatePrimary(ctx context.Context) error {
+	_, err := ck.refreshPrimary(ctx, ck.primary.Load())
+	return err
+}
+
+// refreshPrimary asks the view service for the primary, unless stale has already
+// been replaced by another caller. Concurrent callers share a single GetView.
+func (ck *Client) refreshPrimary(ctx context.Context, stale *primaryConn) (p *primaryConn, err error) {
+	ctx, span := ck.tracer.Start(ctx, "client.UpdatePrimary")
+	defer func() { tracing.End(span, err) }()
+
+	ck.mu.Lock()
+	if cur := ck.primary.Load(); cur != nil && cur != stale {
+		ck.mu.Unlock()
+		return cur, nil
+	}
+	call := ck.refreshing
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		ck.refreshing = call
+		go ck.runRefresh(ctx, call)
+	}
+	ck.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		if p := ck.primary.Load(); p != nil {
+			return p, nil
+		}
+		return nil, ErrUnavailable
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	resp, err := ck.vsClient.GetView(ctx, req)
+// runRefresh performs a shared refresh for the caller that started it and any that
+// join. It is not cancelled with that caller's context, so that one caller giving
+// up does not fail the others, but is traced as part of its operation. Metadata
+// the caller attached, such as a forwarded bearer token, is left off: the refresh
+// is made with the client's own credentials.
+func (ck *Client) runRefresh(caller context.Context, call *refreshCall) {
+	ctx := metadata.NewOutgoingContext(context.WithoutCancel(caller), metadata.MD{})
+	ctx, cancel := clock.WithTimeout(ctx, ck.clock, ck.rpcTimeout)
+	call.err = ck.fetchPrimary(ctx)
 	cancel()
 
+	ck.mu.Lock()
+	ck.refreshing = nil
+	ck.mu.Unlock()
+	close(call.done)
+}
+
+// fetchPrimary calls GetView and swaps in a connection to the primary it names
+func (ck *Client) fetchPrimary(ctx context.Context) error {
+	resp, err := ck.vsClient.GetView(ctx, &pb.GetViewRequest{})
 	if err != nil {
-		log.Printf("GetView failed: %v\n", err)
-		return
+		ck.metrics.refreshes.WithLabelValues("failed").Inc()
+		ck.logger.Warn("GetView failed", "err", err)
+		return err
 	}
+	ck.metrics.refreshes.WithLabelValues("ok").Inc()
 
-	if resp.View.Primary != "" && resp.View.Primary != ck.CurrentPrimary {
-		ck.CurrentPrimary = resp.View.Primary
-		if ck.primaryConn != nil {
-			ck.primaryConn.Close()
-		}
+	addr := resp.View.Primary
+	if addr == "" {
+		ck.swapPrimary(nil)
+		return ErrUnavailable
+	}
+	if cur := ck.primary.Load(); cur != nil && cur.addr == addr {
+		return nil
+	}
 
-		// Connect to new primary
-		conn, err := grpc.Dial(ck.CurrentPrimary, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		if err != nil {
-			log.Printf("Failed to connect to primary %s: %v\n", ck.CurrentPrimary, err)
-			ck.CurrentPrimary = ""
-			return
-		}
-		ck.primaryConn = conn
-		ck.primaryClient = pb.NewKVServerClient(conn)
-		log.Printf("Client connected to primary %s\n", ck.CurrentPrimary)
+	// Connect to new primary
+	_, span := ck.tracer.Start(ctx, "client.DialPrimary", trace.WithAttributes(attribute.String("primary", addr)))
+	conn, err := ck.dial(addr)
+	tracing.End(span, err)
+	if err != nil {
+		ck.logger.Warn("Failed to connect to primary", "primary", addr, "err", err)
+		ck.swapPrimary(nil)
+		return err
+	}
+	ck.swapPrimary(&primaryConn{addr: addr, conn: conn, client: pb.NewKVServerClient(conn)})
+	ck.metrics.primaryChanges.Inc()
+	ck.logger.Debug("Client connected to primary", "primary", addr)
+	return nil
+}
+
+// swapPrimary installs next as the primary and closes the connection it replaces.
+// RPCs still running on the old connection fail with Canceled; call retries them
+// on the new one, see replaced.
+func (ck *Client) swapPrimary(next *primaryConn) {
+	ck.mu.Lock()
+	defer ck.mu.Unlock()
+	if ck.closed && next != nil {
+		next.conn.Close()
+		return
+	}
+	if old := ck.primary.Swap(next); old != nil && old != next {
+		old.conn.Close()
+	}
+}
+
+// dial opens a connection using the configured dial options
+func (ck *Client) dial(address string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if ck.tls != nil {
+		creds = ck.tls.ClientCredentials()
 	}
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds), logging.DialOption(), tracing.DialOption(ck.traces)}, ck.dialOptions...)
+	return grpc.Dial(address, opts...)
 }
 
 // Close closes the client connections
 func (ck *Client) Close() {
+	ck.swapPrimary(nil)
+	ck.mu.Lock()
+	ck.closed = true
+	ck.mu.Unlock()
 	if ck.vsConn != nil {
 		ck.vsConn.Close()
 	}
-	if ck.primaryConn != nil {
-		ck.primaryConn.Close()
+}
+
+// replyError converts the ok/error fields of a KVServer reply into an error
+func replyError(ok bool, code string) error {
+	if ok {
+		return nil
+	}
+	if code == "" {
+		return errors.New("client: request rejected by server")
+	}
+	return errorFromReply(code)
+}
+
+// retryable reports whether a failed attempt should be retried on a fresh primary:
+// the server was not the primary, could not be reached, aborted the request or
+// ran out of the attempt's time, rpcCtx, while the operation, ctx, has time left.
+// Any other error is the answer to the operation.
+func retryable(ctx, rpcCtx context.Context, err error) bool {
+	if errors.Is(err, ErrNotPrimary) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted:
+		return true
+	case codes.DeadlineExceeded, codes.Canceled:
+		// A context on a fake clock is canceled, not timed out, when it expires
+		return errors.Is(context.Cause(rpcCtx), context.DeadlineExceeded) && ctx.Err() == nil
+	}
+	return false
+}
+
+// replaced reports whether err is an attempt on p cut short because another caller
+// swapped p out and closed its connection, while the operation, ctx, has time left
+// and the client is open. The server may or may not have applied the request, as
+// with any attempt that times out, so it is retried like one.
+func (ck *Client) replaced(ctx context.Context, p *primaryConn, err error) bool {
+	if status.Code(err) != codes.Canceled || ctx.Err() != nil || ck.primary.Load() == p {
+		return false
+	}
+	ck.mu.Lock()
+	defer ck.mu.Unlock()
+	return !ck.closed
+}
+
+// denied reports whether a server refused the caller's identity or permissions,
+// which another server or attempt will not grant either
+func denied(err error) bool {
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return true
 	}
+	return false
 }
@@ -0,0 +1,201 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/faults"
+	"goDistributedSystemDemo/harness"
+	pb "goDistributedSystemDemo/proto"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// timeout bounds each test; failovers take a few DeadIntervals
+const timeout = 30 * time.Second
+
+// stable waits until the view has a primary that knows its backup
+func stable(ctx context.Context, t *testing.T, c *harness.Cluster) *pb.View {
+	t.Helper()
+	view, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" && v.Backup != "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return view
+}
+
+// refreshes returns how many times the client asked the view service for the
+// primary, from the metrics it registered with reg
+func refreshes(t *testing.T, reg *prometheus.Registry) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, f := range families {
+		if f.GetName() != "client_view_refreshes_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			total += m.GetCounter().GetValue()
+		}
+	}
+	return total
+}
+
+// TestRetryOnReplacedConnection runs a Get on the primary's connection while
+// another caller moves the client to a new primary and closes that connection.
+// The Get is cut short by the close, not by the server, and must be retried.
+func TestRetryOnReplacedConnection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 2)
+	ck := c.ClientT(t)
+
+	view := stable(ctx, t, c)
+	if err := ck.Put(ctx, "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// Hold the Get in the client until the connection has been replaced
+	if _, err := c.Faults().Add(faults.Rule{
+		From: harness.ClientName, To: view.Primary, Method: "Get", Action: faults.Delay, Delay: time.Second,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		value, found, err := ck.Get(ctx, "a")
+		if err == nil && (!found || value != "1") {
+			err = fmt.Errorf("read %q, %v; want %q", value, found, "1")
+		}
+		got <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	if _, err := ck.ForcePrimary(ctx, view.Backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := ck.UpdatePrimary(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ck.Primary() != view.Backup {
+		t.Fatalf("client talks to %q, want the forced primary %q", ck.Primary(), view.Backup)
+	}
+	if err := <-got; err != nil {
+		t.Fatalf("Get(a) during the primary change: %v", err)
+	}
+}
+
+// TestConcurrentFailover has several goroutines write and read their own keys
+// through one client while the primary is killed twice. Every operation must
+// succeed, and every read must return the goroutine's last write.
+func TestConcurrentFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 3)
+	ck := c.ClientT(t)
+	view := stable(ctx, t, c)
+
+	stop := make(chan struct{})
+	errs := make(chan error, 8)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprint("k", w)
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				value := fmt.Sprint(i)
+				if err := ck.Put(ctx, key, value); err != nil {
+					errs <- fmt.Errorf("Put(%s, %s): %w", key, value, err)
+					return
+				}
+				got, found, err := ck.Get(ctx, key)
+				if err != nil || !found || got != value {
+					errs <- fmt.Errorf("Get(%s) = %q, %v, %v; want %q", key, got, found, err, value)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 2 {
+		time.Sleep(500 * time.Millisecond)
+		c.KillByName(view.Primary)
+		var err error
+		if view, err = c.WaitForAcked(ctx, func(v *pb.View) bool {
+			return v.Primary == view.Backup && v.Backup != ""
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddServer(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestSharedRefreshOnFailover starts several operations through one client at
+// once after its primary was killed. Each fails on the dead primary, and all of
+// them must wait for the same GetView rather than ask the view service in turn.
+func TestSharedRefreshOnFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 2)
+	reg := prometheus.NewRegistry()
+	ck := c.ClientT(t, client.WithMetrics(reg))
+
+	view := stable(ctx, t, c)
+	if err := ck.Put(ctx, "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	c.KillByName(view.Primary)
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary == view.Backup }); err != nil {
+		t.Fatal(err)
+	}
+
+	before := refreshes(t, reg)
+	start := make(chan struct{})
+	errs := make(chan error, 8)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			value, found, err := ck.Get(ctx, "a")
+			if err != nil || !found || value != "1" {
+				errs <- fmt.Errorf("Get(a) = %q, %v, %v; want %q", value, found, err, "1")
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := refreshes(t, reg) - before; n != 1 {
+		t.Errorf("%v view refreshes after the failover, want 1 shared by all operations", n)
+	}
+	if ck.Primary() != view.Backup {
+		t.Errorf("client talks to %q, want the new primary %q", ck.Primary(), view.Backup)
+	}
+}
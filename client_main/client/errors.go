@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoKey is reported by the primary when the key does not exist
+	ErrNoKey = errors.New("client: no such key")
+	// ErrNotPrimary is reported by a server that is not the current primary
+	ErrNotPrimary = errors.New("client: server is not the primary")
+	// ErrTimeout is returned when an operation misses its deadline
+	ErrTimeout = errors.New("client: operation timed out")
+	// ErrUnavailable is returned when no primary could be reached before the deadline
+	ErrUnavailable = errors.New("client: no primary available")
+	// ErrRetriesExhausted is returned when the retry policy's attempt limit is reached
+	ErrRetriesExhausted = errors.New("client: retry attempts exhausted")
+)
+
+// errorFromReply maps the error string carried in a KVServer reply to a typed error
+func errorFromReply(code string) error {
+	switch code {
+	case "":
+		return nil
+	case "ErrNoKey":
+		return ErrNoKey
+	case "ErrNotPrimary":
+		return ErrNotPrimary
+	default:
+		return fmt.Errorf("client: server error %q", code)
+	}
+}
+
+// deadlineError converts the end of an operation's context into a typed error.
+// reachedPrimary tells whether any attempt got as far as a primary server.
+func deadlineError(ctx context.Context, reachedPrimary bool, lastErr error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	if !reachedPrimary {
+		if errors.Is(lastErr, ErrUnavailable) {
+			return lastErr
+		}
+		if lastErr != nil {
+			return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+		}
+		return ErrUnavailable
+	}
+	if lastErr != nil {
+		return fmt.Errorf("%w: %v", ErrTimeout, lastErr)
+	}
+	return ErrTimeout
+}
+
+// exhaustedError reports an operation that used up its attempts before its deadline
+func exhaustedError(attempts int, reachedPrimary bool, lastErr error) error {
+	if !reachedPrimary {
+		return fmt.Errorf("%w after %d attempts: %v", ErrUnavailable, attempts, lastErr)
+	}
+	return fmt.Errorf("%w after %d attempts: %v", ErrRetriesExhausted, attempts, lastErr)
+}
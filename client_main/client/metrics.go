@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goDistributedSystemDemo/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientMetrics are the client's Prometheus metrics
+type clientMetrics struct {
+	operations     *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	attempts       *prometheus.CounterVec
+	refreshes      *prometheus.CounterVec
+	primaryChanges prometheus.Counter
+}
+
+// newClientMetrics creates the client's metrics and registers them with reg
+func newClientMetrics(reg prometheus.Registerer) *clientMetrics {
+	m := &clientMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "client",
+			Name:      "operations_total",
+			Help:      "Operations completed, by operation and result.",
+		}, []string{"op", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "client",
+			Name:      "operation_duration_seconds",
+			Help:      "Time taken by operations including retries, by operation.",
+			Buckets:   metrics.LatencyBuckets,
+		}, []string{"op"}),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "client",
+			Name:      "attempts_total",
+			Help:      "Attempts made by operations; attempts beyond one per operation are retries.",
+		}, []string{"op"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "client",
+			Name:      "view_refreshes_total",
+			Help:      "Requests to the view service for the current primary, by result.",
+		}, []string{"result"}),
+		primaryChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "client",
+			Name:      "primary_changes_total",
+			Help:      "Times the client switched to a different primary.",
+		}),
+	}
+	reg.MustRegister(m.operations, m.duration, m.attempts, m.refreshes, m.primaryChanges)
+	return m
+}
+
+// observe records one finished operation
+func (m *clientMetrics) observe(op string, attempts int, d time.Duration, err error) {
+	m.operations.WithLabelValues(op, result(err)).Inc()
+	m.duration.WithLabelValues(op).Observe(d.Seconds())
+	m.attempts.WithLabelValues(op).Add(float64(attempts))
+}
+
+// result labels the outcome of an operation
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNoKey):
+		return "no_key"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrRetriesExhausted):
+		return "retries_exhausted"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	if denied(err) {
+		return "denied"
+	}
+	return "error"
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"goDistributedSystemDemo/clock"
+)
+
+// RetryPolicy controls how the client backs off between attempts of one operation
+type RetryPolicy struct {
+	InitialBackoff time.Duration // pause after the first failed attempt
+	MaxBackoff     time.Duration // upper bound for any single pause
+	Multiplier     float64       // growth factor applied after every failed attempt
+	Jitter         float64       // fraction of each pause that is randomised, 0 to 1
+	MaxAttempts    int           // attempts before giving up, 0 for no limit besides the deadline
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxAttempts:    0,
+}
+
+// WithRetryPolicy sets the backoff policy used between attempts
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(ck *Client) { ck.retry = p }
+}
+
+// Backoff returns the pause before the attempt following failed attempt n (counting from 1)
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(n-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	// Spread the pause over [d*(1-jitter), d*(1+jitter)] so clients do not retry in lockstep
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d *= 1 - jitter + 2*jitter*rand.Float64()
+		if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+			d = float64(p.MaxBackoff)
+		}
+	}
+	return time.Duration(d)
+}
+
+// exhausted reports whether n attempts use up the policy's attempt budget
+func (p RetryPolicy) exhausted(n int) bool {
+	return p.MaxAttempts > 0 && n >= p.MaxAttempts
+}
+
+// backoff pauses after failed attempt n. It reports false without sleeping when the
+// pause would run past the deadline, and false if ctx ends while waiting.
+func (ck *Client) backoff(ctx context.Context, n int) bool {
+	d := ck.retry.Backoff(n)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(ck.clock.Now()) < d {
+		return false
+	}
+	return clock.Sleep(ctx, ck.clock, d)
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/clock"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		n      int
+		want   time.Duration
+	}{
+		{"first attempt", RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2}, 1, 100 * time.Millisecond},
+		{"grows by the multiplier", RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2}, 4, 800 * time.Millisecond},
+		{"attempt below 1 counts as the first", RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2}, -3, 100 * time.Millisecond},
+		{"capped at MaxBackoff", RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}, 10, time.Second},
+		{"no cap without MaxBackoff", RetryPolicy{InitialBackoff: time.Second, Multiplier: 10}, 4, 1000 * time.Second},
+		{"capped long after overflowing", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 10}, 1000, time.Minute},
+		{"multiplier below 1 keeps the pause constant", RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 0.5}, 5, 100 * time.Millisecond},
+		{"zero multiplier keeps the pause constant", RetryPolicy{InitialBackoff: 100 * time.Millisecond}, 5, 100 * time.Millisecond},
+		{"negative jitter is none", RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1, Jitter: -1}, 3, 100 * time.Millisecond},
+		{"zero initial backoff", RetryPolicy{Multiplier: 2, Jitter: 0.5}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.n); got != tt.want {
+				t.Fatalf("Backoff(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBackoffJitter draws many pauses and checks they stay within the jitter
+// range, are capped at MaxBackoff, and are actually spread
+func TestBackoffJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		n        int
+		min, max time.Duration
+	}{
+		{"20% jitter", RetryPolicy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.2}, 1, 800 * time.Millisecond, 1200 * time.Millisecond},
+		{"jitter above 1 is clamped to 1", RetryPolicy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 3}, 1, 0, 2 * time.Second},
+		{"jitter does not exceed MaxBackoff", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}, 5, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lowest, highest := time.Duration(1<<62), time.Duration(0)
+			for range 1000 {
+				d := tt.policy.Backoff(tt.n)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", tt.n, d, tt.min, tt.max)
+				}
+				lowest, highest = min(lowest, d), max(highest, d)
+			}
+			if spread := tt.max - tt.min; highest-lowest < spread/2 {
+				t.Errorf("pauses spread over [%v, %v] only, want most of [%v, %v]", lowest, highest, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		maxAttempts int
+		n           int
+		want        bool
+	}{
+		{0, 1, false},
+		{0, 1000, false},
+		{3, 2, false},
+		{3, 3, true},
+		{3, 4, true},
+		{1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d of %d", tt.n, tt.maxAttempts), func(t *testing.T) {
+			if got := (RetryPolicy{MaxAttempts: tt.maxAttempts}).exhausted(tt.n); got != tt.want {
+				t.Fatalf("exhausted(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientBackoff refuses to pause past the operation's deadline or after the
+// operation was canceled
+func TestClientBackoff(t *testing.T) {
+	ck := &Client{retry: RetryPolicy{InitialBackoff: 10 * time.Millisecond, Multiplier: 1}, clock: clock.Real}
+
+	if !ck.backoff(context.Background(), 1) {
+		t.Error("backoff without a deadline = false, want true")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if ck.backoff(ctx, 1) {
+		t.Error("backoff past the deadline = true, want false")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("backoff past the deadline slept %v, want no pause", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if ck.backoff(ctx, 1) {
+		t.Error("backoff after cancel = true, want false")
+	}
+}
+
+// TestRetryable classifies the errors of one attempt: those another attempt may
+// get past are retried, the rest are returned to the caller
+func TestRetryable(t *testing.T) {
+	live := context.Background()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timedOut, cancelTimeout := context.WithTimeout(context.Background(), 0)
+	defer cancelTimeout()
+	<-timedOut.Done()
+	sim := clock.NewSim(time.Unix(0, 0))
+	simExpired, cancelExpired := clock.WithTimeout(context.Background(), sim, time.Second)
+	defer cancelExpired()
+	sim.Advance(time.Second)
+
+	tests := []struct {
+		name  string
+		ctx   context.Context // the operation
+		rpc   context.Context // the attempt
+		err   error
+		retry bool
+		deny  bool
+	}{
+		{"not primary", live, live, ErrNotPrimary, true, false},
+		{"wrapped not primary", live, live, fmt.Errorf("get: %w", ErrNotPrimary), true, false},
+		{"unavailable", live, live, status.Error(codes.Unavailable, "connection refused"), true, false},
+		{"aborted", live, live, status.Error(codes.Aborted, "view changed"), true, false},
+		{"attempt timed out", live, timedOut, status.Error(codes.DeadlineExceeded, "deadline"), true, false},
+		{"attempt timed out on a simulated clock", live, simExpired, status.Error(codes.Canceled, "canceled"), true, false},
+		{"attempt timed out with the operation", canceled, timedOut, status.Error(codes.DeadlineExceeded, "deadline"), false, false},
+		{"operation canceled", canceled, canceled, status.Error(codes.Canceled, "canceled"), false, false},
+		{"permission denied", live, live, status.Error(codes.PermissionDenied, "no grant"), false, true},
+		{"unauthenticated", live, live, status.Error(codes.Unauthenticated, "no token"), false, true},
+		{"invalid argument", live, live, status.Error(codes.InvalidArgument, "bad key"), false, false},
+		{"internal", live, live, status.Error(codes.Internal, "bug"), false, false},
+		{"no key", live, live, ErrNoKey, false, false},
+		{"other error", live, live, errors.New("boom"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.ctx, tt.rpc, tt.err); got != tt.retry {
+				t.Errorf("retryable = %v, want %v", got, tt.retry)
+			}
+			if got := denied(tt.err); got != tt.deny {
+				t.Errorf("denied = %v, want %v", got, tt.deny)
+			}
+		})
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+	<-expired.Done()
+	cause := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"canceled", deadlineError(canceled, true, cause), context.Canceled},
+		{"timed out before reaching a primary", deadlineError(expired, false, cause), ErrUnavailable},
+		{"timed out without an attempt", deadlineError(expired, false, nil), ErrUnavailable},
+		{"timed out at a primary", deadlineError(expired, true, cause), ErrTimeout},
+		{"exhausted before reaching a primary", exhaustedError(3, false, cause), ErrUnavailable},
+		{"exhausted at a primary", exhaustedError(3, true, cause), ErrRetriesExhausted},
+		{"no key", errorFromReply("ErrNoKey"), ErrNoKey},
+		{"not primary", errorFromReply("ErrNotPrimary"), ErrNotPrimary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.want) {
+				t.Fatalf("got %v, want %v", tt.err, tt.want)
+			}
+		})
+	}
+	if err := errorFromReply("ErrWrongView"); err == nil || errors.Is(err, ErrNotPrimary) {
+		t.Errorf("errorFromReply(ErrWrongView) = %v, want an untyped error", err)
+	}
+}
@@ -0,0 +1,368 @@
+// Package shell implements an interactive command shell on top of client.Client.
+// One client session is kept open across all commands.
+package shell
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	pb "goDistributedSystemDemo/proto"
+)
+
+const helpText = `Commands:
+  get <key>                 print the value of key
+  put <key> <value>         store value under key
+  delete <key>              remove key (alias: del)
+  scan [prefix] [limit]     list keys starting with prefix, in key order
+  view                      show the current view and whether the view service is blocked
+  servers                   list the servers the view service knows, with their metadata
+  views [n]                 show the last n view changes and why they happened (default 10)
+  force-primary <server>    operator override: make server primary of a new view
+  timing [on|off]           print how long each command takes
+  history                   list previous commands
+  !! / !<n>                 run the previous command / command number n
+  help                      show this text
+  quit                      leave the shell (alias: exit, Ctrl-D)
+Arguments may be quoted with "..." or '...'; backslash escapes the next character.
+`
+
+// Shell reads commands from in and writes results to out
+type Shell struct {
+	ck      *client.Client
+	in      *bufio.Scanner
+	out     io.Writer
+	timeout time.Duration // deadline for each command
+
+	timing      bool
+	history     []string
+	historyFile string // file history is loaded from and appended to, "" for none
+}
+
+// New creates a shell that runs commands against ck
+func New(ck *client.Client, in io.Reader, out io.Writer, timeout time.Duration) *Shell {
+	return &Shell{
+		ck:      ck,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		timeout: timeout,
+		history: make([]string, 0),
+	}
+}
+
+// SetHistoryFile loads earlier history from path and appends new commands to it
+func (sh *Shell) SetHistoryFile(path string) error {
+	sh.historyFile = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			sh.history = append(sh.history, line)
+		}
+	}
+	return nil
+}
+
+// Run reads and executes commands until quit or end of input
+func (sh *Shell) Run() error {
+	fmt.Fprint(sh.out, "Type \"help\" for a list of commands.\n")
+	for {
+		fmt.Fprint(sh.out, "kv> ")
+		if !sh.in.Scan() {
+			fmt.Fprintln(sh.out)
+			return sh.in.Err()
+		}
+		line := strings.TrimSpace(sh.in.Text())
+		if line == "" {
+			continue
+		}
+
+		// History expansion
+		if strings.HasPrefix(line, "!") {
+			expanded, err := sh.expand(line)
+			if err != nil {
+				fmt.Fprintf(sh.out, "error: %v\n", err)
+				continue
+			}
+			line = expanded
+			fmt.Fprintln(sh.out, line)
+		}
+		sh.record(line)
+
+		args, err := Split(line)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return nil
+		}
+
+		start := time.Now()
+		sh.execute(args)
+		if sh.timing {
+			fmt.Fprintf(sh.out, "(%v)\n", time.Since(start).Round(time.Microsecond))
+		}
+	}
+}
+
+// execute runs one parsed command
+func (sh *Shell) execute(args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sh.timeout)
+	defer cancel()
+
+	switch cmd := args[0]; cmd {
+	case "get":
+		if !sh.arity(args, 2, 2, "get <key>") {
+			return
+		}
+		value, found, err := sh.ck.Get(ctx, args[1])
+		switch {
+		case err != nil:
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		case !found:
+			fmt.Fprintln(sh.out, "(no key)")
+		default:
+			fmt.Fprintln(sh.out, strconv.Quote(value))
+		}
+
+	case "put":
+		if !sh.arity(args, 3, 3, "put <key> <value>") {
+			return
+		}
+		if err := sh.ck.Put(ctx, args[1], args[2]); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintln(sh.out, "OK")
+
+	case "delete", "del":
+		if !sh.arity(args, 2, 2, "delete <key>") {
+			return
+		}
+		if err := sh.ck.Delete(ctx, args[1]); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintln(sh.out, "OK")
+
+	case "scan":
+		if !sh.arity(args, 1, 3, "scan [prefix] [limit]") {
+			return
+		}
+		prefix, limit := "", 0
+		if len(args) > 1 {
+			prefix = args[1]
+		}
+		if len(args) > 2 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 0 {
+				fmt.Fprintf(sh.out, "error: invalid limit %q\n", args[2])
+				return
+			}
+			limit = n
+		}
+		entries, err := sh.ck.Scan(ctx, prefix, limit)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		for _, e := range entries {
+			fmt.Fprintf(sh.out, "%s = %s\n", strconv.Quote(e.Key), strconv.Quote(e.Value))
+		}
+		fmt.Fprintf(sh.out, "(%d entries)\n", len(entries))
+
+	case "view":
+		if !sh.arity(args, 1, 1, "view") {
+			return
+		}
+		status, err := sh.ck.ViewStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		view := status.View
+		fmt.Fprintf(sh.out, "ViewNumber=%d Primary=%s Backup=%s Acked=%v\n",
+			view.ViewNumber, orNone(view.Primary), orNone(view.Backup), status.PrimaryAcked)
+		if status.Blocked != "" {
+			fmt.Fprintf(sh.out, "Blocked: %s\n", status.Blocked)
+		}
+
+	case "servers":
+		if !sh.arity(args, 1, 1, "servers") {
+			return
+		}
+		servers, err := sh.ck.ListServers(ctx)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		for _, s := range servers {
+			sh.printServer(s)
+		}
+
+	case "views":
+		if !sh.arity(args, 1, 2, "views [n]") {
+			return
+		}
+		limit := uint64(10)
+		if len(args) == 2 {
+			n, err := strconv.ParseUint(args[1], 10, 32)
+			if err != nil {
+				fmt.Fprintf(sh.out, "error: invalid count %q\n", args[1])
+				return
+			}
+			limit = n
+		}
+		changes, err := sh.ck.ListViews(ctx, 0, uint32(limit))
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		for _, c := range changes {
+			sh.printViewChange(c)
+		}
+
+	case "force-primary":
+		if !sh.arity(args, 2, 2, "force-primary <server>") {
+			return
+		}
+		view, err := sh.ck.ForcePrimary(ctx, args[1])
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintf(sh.out, "ViewNumber=%d Primary=%s Backup=%s\n", view.ViewNumber, orNone(view.Primary), orNone(view.Backup))
+
+	case "timing":
+		if !sh.arity(args, 1, 2, "timing [on|off]") {
+			return
+		}
+		if len(args) == 1 {
+			sh.timing = !sh.timing
+		} else if args[1] == "on" || args[1] == "off" {
+			sh.timing = args[1] == "on"
+		} else {
+			fmt.Fprintln(sh.out, "usage: timing [on|off]")
+			return
+		}
+		fmt.Fprintf(sh.out, "timing %s\n", map[bool]string{true: "on", false: "off"}[sh.timing])
+
+	case "history":
+		for i, line := range sh.history {
+			fmt.Fprintf(sh.out, "%5d  %s\n", i+1, line)
+		}
+
+	case "help":
+		fmt.Fprint(sh.out, helpText)
+
+	default:
+		fmt.Fprintf(sh.out, "unknown command %q, type \"help\" for a list of commands\n", cmd)
+	}
+}
+
+// arity checks the argument count and prints usage when it is wrong
+func (sh *Shell) arity(args []string, min, max int, usage string) bool {
+	if len(args) < min || len(args) > max {
+		fmt.Fprintf(sh.out, "usage: %s\n", usage)
+		return false
+	}
+	return true
+}
+
+// expand resolves a "!!" or "!n" history reference
+func (sh *Shell) expand(line string) (string, error) {
+	if len(sh.history) == 0 {
+		return "", fmt.Errorf("history is empty")
+	}
+	if line == "!!" {
+		return sh.history[len(sh.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(sh.history) {
+		return "", fmt.Errorf("no such history entry %q", line)
+	}
+	return sh.history[n-1], nil
+}
+
+// record adds a command to the history and the history file
+func (sh *Shell) record(line string) {
+	sh.history = append(sh.history, line)
+	if sh.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(sh.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// printServer prints one line of the servers command
+func (sh *Shell) printServer(s *pb.ServerStatus) {
+	state := "alive"
+	if !s.Alive {
+		state = "dead"
+	} else if s.Leaving {
+		state = "leaving"
+	}
+	age := time.Since(time.Unix(0, s.LastPingUnixNano)).Round(time.Millisecond)
+	fmt.Fprintf(sh.out, "%s %s %s zone=%s last-ping=%v ago", s.Name, s.Role, state, orNone(s.Zone), age)
+	if md := s.Metadata; md != nil {
+		fmt.Fprintf(sh.out, " version=%s seq=%d keys=%d bytes=%d willing=%v",
+			md.Version, md.AppliedSeq, md.Keys, md.DataBytes, md.Willing)
+		if md.PeerAddress != "" {
+			fmt.Fprintf(sh.out, " peer=%s", md.PeerAddress)
+		}
+		labels := make([]string, 0, len(md.Labels))
+		for k, v := range md.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		if len(labels) > 0 {
+			sort.Strings(labels)
+			fmt.Fprintf(sh.out, " labels=%s", strings.Join(labels, ","))
+		}
+	}
+	fmt.Fprintln(sh.out)
+}
+
+// printViewChange prints one line of the views command
+func (sh *Shell) printViewChange(c *pb.ViewChange) {
+	alive := make([]string, 0)
+	dead := make([]string, 0)
+	for _, s := range c.Servers {
+		if s.Alive {
+			alive = append(alive, s.Name)
+		} else {
+			dead = append(dead, s.Name)
+		}
+	}
+	fmt.Fprintf(sh.out, "%s view %d Primary=%s Backup=%s [%s] %s (alive: %s; dead: %s)\n",
+		time.Unix(0, c.TimeUnixNano).Format("2006-01-02 15:04:05.000"), c.View.ViewNumber,
+		orNone(c.View.Primary), orNone(c.View.Backup), c.Reason, c.Detail,
+		orNone(strings.Join(alive, ",")), orNone(strings.Join(dead, ",")))
+}
+
+// orNone renders an empty server name readably
+func orNone(name string) string {
+	if name == "" {
+		return "(none)"
+	}
+	return name
+}
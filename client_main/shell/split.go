@@ -0,0 +1,73 @@
+package shell
+
+import "fmt"
+
+// Split breaks a command line into arguments. Arguments are separated by spaces or
+// tabs; "double" and 'single' quotes group text, and a backslash escapes the next
+// character outside single quotes. Inside double quotes \n and \t are also expanded.
+func Split(line string) ([]string, error) {
+	args := make([]string, 0)
+	var cur []rune
+	inArg := false
+	var quote rune // 0, '"' or '\''
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur = append(cur, r)
+			}
+
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			next := runes[i]
+			if quote == '"' {
+				switch next {
+				case 'n':
+					next = '\n'
+				case 't':
+					next = '\t'
+				}
+			}
+			cur = append(cur, next)
+			inArg = true
+
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur = append(cur, r)
+			}
+
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, string(cur))
+				cur = cur[:0]
+				inArg = false
+			}
+
+		default:
+			cur = append(cur, r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, string(cur))
+	}
+	return args, nil
+}
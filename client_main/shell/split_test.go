@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{"empty line", "", []string{}, ""},
+		{"only blanks", " \t  ", []string{}, ""},
+		{"words", "put k v", []string{"put", "k", "v"}, ""},
+		{"runs of blanks", "  put\t k \t\tv  ", []string{"put", "k", "v"}, ""},
+		{"double quotes group words", `put k "a b"`, []string{"put", "k", "a b"}, ""},
+		{"single quotes group words", `put k 'a b'`, []string{"put", "k", "a b"}, ""},
+		{"empty double quotes", `put k ""`, []string{"put", "k", ""}, ""},
+		{"empty single quotes", `put '' v`, []string{"put", "", "v"}, ""},
+		{"quotes inside a word", `put k a"b c"d`, []string{"put", "k", "ab cd"}, ""},
+		{"adjacent quotes join", `'a'"b"c`, []string{"abc"}, ""},
+		{"single quote inside double quotes", `"it's"`, []string{"it's"}, ""},
+		{"double quote inside single quotes", `'say "hi"'`, []string{`say "hi"`}, ""},
+		{"escaped blank", `put a\ b v`, []string{"put", "a b", "v"}, ""},
+		{"escaped quote", `put k \"v\"`, []string{"put", "k", `"v"`}, ""},
+		{"escaped backslash", `put k a\\b`, []string{"put", "k", `a\b`}, ""},
+		{"escaped quote inside double quotes", `"a \"b\""`, []string{`a "b"`}, ""},
+		{"newline and tab inside double quotes", `"a\nb\tc"`, []string{"a\nb\tc"}, ""},
+		{"n and t escaped outside quotes", `a\nb\t`, []string{"anbt"}, ""},
+		{"backslash is literal inside single quotes", `'a\nb\'`, []string{`a\nb\`}, ""},
+		{"escaped blank alone", `\ `, []string{" "}, ""},
+		{"unicode", "put ключ 'значение 値'", []string{"put", "ключ", "значение 値"}, ""},
+		{"unterminated double quote", `put k "v`, nil, `unterminated " quote`},
+		{"unterminated single quote", `put k 'v`, nil, `unterminated ' quote`},
+		{"quote closed by the other kind", `"a'`, nil, `unterminated " quote`},
+		{"trailing backslash", `put k v\`, nil, "trailing backslash"},
+		{"trailing backslash inside double quotes", `"v\`, nil, "trailing backslash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.line)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Split(%q) = %q, %v; want error %q", tt.line, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("Split(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+			}
+		})
+	}
+}
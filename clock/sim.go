@@ -0,0 +1,189 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Sim is a simulated clock. Time stands still until Advance or Step moves it, and
+// timers fire in deadline order, ties broken by creation order, so a program
+// driven only by a Sim clock behaves the same on every run.
+type Sim struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers timerHeap
+	seq    uint64
+}
+
+// NewSim creates a simulated clock reading start
+func NewSim(start time.Time) *Sim {
+	return &Sim{now: start}
+}
+
+// Now returns the simulated time
+func (s *Sim) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// NewTimer creates a timer that delivers on its channel after d of simulated time
+func (s *Sim) NewTimer(d time.Duration) Timer {
+	t := &simTimer{sim: s, ch: make(chan time.Time, 1)}
+	s.schedule(t, d)
+	return t
+}
+
+// AfterFunc calls f, from the goroutine advancing the clock, after d of simulated time
+func (s *Sim) AfterFunc(d time.Duration, f func()) Timer {
+	t := &simTimer{sim: s, fn: f}
+	s.schedule(t, d)
+	return t
+}
+
+// NewTicker creates a ticker that delivers on its channel every d of simulated time
+func (s *Sim) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive ticker interval")
+	}
+	t := &simTimer{sim: s, ch: make(chan time.Time, 1), period: d}
+	s.schedule(t, d)
+	return simTicker{t}
+}
+
+// Advance moves time forward by d, firing every timer that falls due on the way
+func (s *Sim) Advance(d time.Duration) {
+	s.mu.Lock()
+	end := s.now.Add(d)
+	s.mu.Unlock()
+	for s.stepUntil(end) {
+	}
+	s.mu.Lock()
+	if s.now.Before(end) {
+		s.now = end
+	}
+	s.mu.Unlock()
+}
+
+// Step jumps to the earliest pending timer and fires it. It reports false if no
+// timer is pending.
+func (s *Sim) Step() bool {
+	return s.stepUntil(time.Time{})
+}
+
+// Pending returns the number of timers that have not fired or been stopped
+func (s *Sim) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
+
+// stepUntil fires the earliest timer if it is due no later than end (or at all,
+// for a zero end) and reports whether one fired
+func (s *Sim) stepUntil(end time.Time) bool {
+	s.mu.Lock()
+	if len(s.timers) == 0 || (!end.IsZero() && s.timers[0].when.After(end)) {
+		s.mu.Unlock()
+		return false
+	}
+	t := heap.Pop(&s.timers).(*simTimer)
+	if t.when.After(s.now) {
+		s.now = t.when
+	}
+	now := s.now
+	if t.period > 0 {
+		s.scheduleLocked(t, t.period)
+	}
+	s.mu.Unlock()
+
+	// Fire outside the lock so the callback can use the clock
+	if t.fn != nil {
+		t.fn()
+	} else {
+		select {
+		case t.ch <- now:
+		default: // like time.Ticker, drop ticks nobody is waiting for
+		}
+	}
+	return true
+}
+
+// schedule arms t to fire d from now
+func (s *Sim) schedule(t *simTimer, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleLocked(t, d)
+}
+
+func (s *Sim) scheduleLocked(t *simTimer, d time.Duration) {
+	s.seq++
+	t.when = s.now.Add(d)
+	t.seq = s.seq
+	heap.Push(&s.timers, t)
+}
+
+// stop disarms t and reports whether it was pending
+func (s *Sim) stop(t *simTimer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&s.timers, t.index)
+	return true
+}
+
+// simTimer is a timer, ticker or AfterFunc on a Sim clock
+type simTimer struct {
+	sim    *Sim
+	when   time.Time
+	seq    uint64
+	period time.Duration // non-zero for tickers
+	ch     chan time.Time
+	fn     func()
+	index  int // position in the heap, -1 when not scheduled
+}
+
+func (t *simTimer) C() <-chan time.Time { return t.ch }
+
+func (t *simTimer) Stop() bool { return t.sim.stop(t) }
+
+// simTicker adapts a periodic simTimer to the Ticker interface
+type simTicker struct{ t *simTimer }
+
+func (t simTicker) C() <-chan time.Time { return t.t.ch }
+func (t simTicker) Stop()               { t.t.Stop() }
+
+// timerHeap orders timers by deadline, then by creation
+type timerHeap []*simTimer
+
+func (h timerHeap) Len() int { return len(h) }
+
+func (h timerHeap) Less(i, j int) bool {
+	if !h[i].when.Equal(h[j].when) {
+		return h[i].when.Before(h[j].when)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*simTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
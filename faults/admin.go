@@ -0,0 +1,145 @@
+package faults
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler returns an HTTP handler for changing rules at runtime:
+//
+//	GET    /faults        list the active rules
+//	POST   /faults        add the rule in the JSON body, reply with it including its ID
+//	DELETE /faults        remove every rule
+//	DELETE /faults/{id}   remove one rule
+//
+// Durations in the JSON body are nanoseconds or Go duration strings such as "200ms".
+func (inj *Injector) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/faults", inj.serveRules)
+	mux.HandleFunc("/faults/", inj.serveRule)
+	return mux
+}
+
+// ListenAndServe runs the admin handler on address in the background
+func (inj *Injector) ListenAndServe(address string) {
+	go func() {
+		slog.Info("Fault injection admin endpoint listening", "url", "http://"+address+"/faults")
+		if err := http.ListenAndServe(address, inj.Handler()); err != nil {
+			slog.Error("Fault injection admin endpoint failed", "err", err)
+		}
+	}()
+}
+
+// serveRules handles the rule collection
+func (inj *Injector) serveRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, inj.Rules())
+	case http.MethodPost:
+		var body ruleJSON
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rule, err := body.rule()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := inj.Add(rule)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rule.ID = id
+		slog.Info("Fault rule added", "id", id, "rule", fmt.Sprintf("%+v", rule))
+		writeJSON(w, http.StatusCreated, rule)
+	case http.MethodDelete:
+		inj.Clear()
+		slog.Info("Fault rules cleared")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveRule handles a single rule
+func (inj *Injector) serveRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/faults/"))
+	if err != nil {
+		http.Error(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+	if !inj.Remove(id) {
+		http.Error(w, "no such rule", http.StatusNotFound)
+		return
+	}
+	slog.Info("Fault rule removed", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ruleJSON is a Rule whose durations may also be given as strings
+type ruleJSON struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Method      string          `json:"method"`
+	Action      Action          `json:"action"`
+	Probability float64         `json:"probability"`
+	Delay       json.RawMessage `json:"delay"`
+	Jitter      json.RawMessage `json:"jitter"`
+	Window      json.RawMessage `json:"window"`
+}
+
+// rule converts the request body into a Rule
+func (b ruleJSON) rule() (Rule, error) {
+	r := Rule{
+		From:        b.From,
+		To:          b.To,
+		Method:      b.Method,
+		Action:      b.Action,
+		Probability: b.Probability,
+	}
+	var err error
+	if r.Delay, err = parseDuration(b.Delay); err != nil {
+		return r, err
+	}
+	if r.Jitter, err = parseDuration(b.Jitter); err != nil {
+		return r, err
+	}
+	if r.Window, err = parseDuration(b.Window); err != nil {
+		return r, err
+	}
+	return r, r.Validate()
+}
+
+// writeJSON writes v with the given status code
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// parseDuration accepts a JSON number of nanoseconds or a duration string
+func parseDuration(raw json.RawMessage) (time.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return time.ParseDuration(s)
+	}
+	var n int64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return 0, fmt.Errorf("faults: invalid duration %s", raw)
+	}
+	return time.Duration(n), nil
+}
@@ -0,0 +1,200 @@
+// Package faults injects network faults into gRPC traffic between the view
+// service, KV servers and clients. Rules drop, delay, duplicate or reorder
+// messages on chosen links and methods, and can be changed at runtime from
+// tests or through the HTTP admin handler.
+package faults
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Action is what a rule does to a matching message
+type Action string
+
+const (
+	DropRequest  Action = "drop-request"  // the request never arrives; the caller times out
+	DropResponse Action = "drop-response" // the request is handled but the reply is lost
+	Delay        Action = "delay"         // the request is held for Delay plus up to Jitter
+	Duplicate    Action = "duplicate"     // the request is delivered twice
+	Reorder      Action = "reorder"       // the request is held for a random time up to Window, so later ones can overtake it
+)
+
+// Any matches every node in Rule.From and Rule.To
+const Any = ""
+
+// Rule selects messages by link and method and applies an action to them
+type Rule struct {
+	ID          int           `json:"id"`
+	From        string        `json:"from,omitempty"`   // sending node, Any for all
+	To          string        `json:"to,omitempty"`     // receiving node, Any for all
+	Method      string        `json:"method,omitempty"` // method name such as "Ping" or full "/proto.ViewService/Ping", empty for all
+	Action      Action        `json:"action"`
+	Probability float64       `json:"probability,omitempty"` // chance of applying to a matching message, 0 means always
+	Delay       time.Duration `json:"delay,omitempty"`
+	Jitter      time.Duration `json:"jitter,omitempty"`
+	Window      time.Duration `json:"window,omitempty"`
+}
+
+// Validate checks that the rule is complete
+func (r Rule) Validate() error {
+	switch r.Action {
+	case DropRequest, DropResponse, Duplicate:
+	case Delay:
+		if r.Delay <= 0 && r.Jitter <= 0 {
+			return fmt.Errorf("faults: delay rule needs a delay or jitter")
+		}
+	case Reorder:
+		if r.Window <= 0 {
+			return fmt.Errorf("faults: reorder rule needs a window")
+		}
+	default:
+		return fmt.Errorf("faults: unknown action %q", r.Action)
+	}
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("faults: probability must be between 0 and 1")
+	}
+	return nil
+}
+
+// matches reports whether the rule selects a message
+func (r Rule) matches(from string, to string, method string) bool {
+	if r.From != Any && r.From != from {
+		return false
+	}
+	if r.To != Any && r.To != to {
+		return false
+	}
+	if r.Method != "" && r.Method != method && !strings.HasSuffix(method, "/"+r.Method) {
+		return false
+	}
+	return true
+}
+
+// Injector holds the active rules. It is safe for concurrent use.
+type Injector struct {
+	mu     sync.Mutex
+	rules  map[int]Rule
+	nextID int
+	rng    *rand.Rand
+	token  string // marks requests this injector's client side already applied the rules to
+}
+
+// New creates an injector without rules; seed makes probabilistic rules repeatable
+func New(seed uint64) *Injector {
+	return &Injector{
+		rules: make(map[int]Rule),
+		rng:   rand.New(rand.NewPCG(seed, seed>>1|1)),
+		token: cryptorand.Text(),
+	}
+}
+
+// Add installs a rule and returns its ID
+func (inj *Injector) Add(r Rule) (int, error) {
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.nextID++
+	r.ID = inj.nextID
+	inj.rules[r.ID] = r
+	return r.ID, nil
+}
+
+// Remove deletes a rule and reports whether it existed
+func (inj *Injector) Remove(id int) bool {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	_, ok := inj.rules[id]
+	delete(inj.rules, id)
+	return ok
+}
+
+// Clear deletes every rule
+func (inj *Injector) Clear() {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.rules = make(map[int]Rule)
+}
+
+// Rules returns the active rules ordered by ID
+func (inj *Injector) Rules() []Rule {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	rules := make([]Rule, 0, len(inj.rules))
+	for _, r := range inj.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// Cut drops every request from one node to another; the reverse direction is untouched
+func (inj *Injector) Cut(from string, to string) int {
+	id, _ := inj.Add(Rule{From: from, To: to, Action: DropRequest})
+	return id
+}
+
+// Partition cuts every link between the two groups, in both directions
+func (inj *Injector) Partition(a []string, b []string) []int {
+	ids := make([]int, 0, 2*len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			ids = append(ids, inj.Cut(x, y), inj.Cut(y, x))
+		}
+	}
+	return ids
+}
+
+// decision is what happens to one message
+type decision struct {
+	dropRequest  bool
+	dropResponse bool
+	duplicate    bool
+	hold         time.Duration
+}
+
+// decide applies every matching rule to a message, in rule order
+func (inj *Injector) decide(from string, to string, method string) decision {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	var d decision
+	ids := make([]int, 0, len(inj.rules))
+	for id := range inj.rules {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		r := inj.rules[id]
+		if !r.matches(from, to, method) {
+			continue
+		}
+		if r.Probability > 0 && inj.rng.Float64() >= r.Probability {
+			continue
+		}
+		switch r.Action {
+		case DropRequest:
+			d.dropRequest = true
+		case DropResponse:
+			d.dropResponse = true
+		case Duplicate:
+			d.duplicate = true
+		case Delay:
+			d.hold += r.Delay
+			if r.Jitter > 0 {
+				d.hold += time.Duration(inj.rng.Int64N(int64(r.Jitter)))
+			}
+		case Reorder:
+			d.hold += time.Duration(inj.rng.Int64N(int64(r.Window)))
+		}
+	}
+	return d
+}
@@ -0,0 +1,174 @@
+package faults
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuleMatches(t *testing.T) {
+	const ping = "/proto.ViewService/Ping"
+	tests := []struct {
+		name   string
+		rule   Rule
+		from   string
+		to     string
+		method string
+		want   bool
+	}{
+		{"empty rule matches everything", Rule{}, "kv1", "vs", ping, true},
+		{"sender", Rule{From: "kv1"}, "kv1", "vs", ping, true},
+		{"other sender", Rule{From: "kv1"}, "kv2", "vs", ping, false},
+		{"receiver", Rule{To: "vs"}, "kv1", "vs", ping, true},
+		{"other receiver", Rule{To: "vs"}, "kv1", "kv2", ping, false},
+		{"link", Rule{From: "kv1", To: "vs"}, "kv1", "vs", ping, true},
+		{"reverse link", Rule{From: "kv1", To: "vs"}, "vs", "kv1", ping, false},
+		{"short method name", Rule{Method: "Ping"}, "kv1", "vs", ping, true},
+		{"full method name", Rule{Method: ping}, "kv1", "vs", ping, true},
+		{"other method", Rule{Method: "Get"}, "kv1", "vs", ping, false},
+		{"partial method name", Rule{Method: "ing"}, "kv1", "vs", ping, false},
+		{"method of another service", Rule{Method: "/proto.KVServer/Ping"}, "kv1", "vs", ping, false},
+		{"unknown sender", Rule{From: "kv1"}, unknownNode, "vs", ping, false},
+		{"everything given", Rule{From: "kv1", To: "vs", Method: "Ping"}, "kv1", "vs", ping, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.matches(tt.from, tt.to, tt.method); got != tt.want {
+				t.Fatalf("matches(%q, %q, %q) = %v, want %v", tt.from, tt.to, tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"drop request", Rule{Action: DropRequest}, false},
+		{"drop response", Rule{Action: DropResponse}, false},
+		{"duplicate", Rule{Action: Duplicate}, false},
+		{"delay", Rule{Action: Delay, Delay: time.Second}, false},
+		{"delay of jitter only", Rule{Action: Delay, Jitter: time.Second}, false},
+		{"delay without a duration", Rule{Action: Delay}, true},
+		{"reorder", Rule{Action: Reorder, Window: time.Second}, false},
+		{"reorder without a window", Rule{Action: Reorder}, true},
+		{"no action", Rule{}, true},
+		{"unknown action", Rule{Action: "corrupt"}, true},
+		{"probability 1", Rule{Action: DropRequest, Probability: 1}, false},
+		{"probability above 1", Rule{Action: DropRequest, Probability: 1.5}, true},
+		{"negative probability", Rule{Action: DropRequest, Probability: -0.1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// decisions returns what happens to n messages from kv1 to vs under rules
+func decisions(t *testing.T, seed uint64, n int, rules ...Rule) []decision {
+	t.Helper()
+	inj := New(seed)
+	for _, r := range rules {
+		if _, err := inj.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ds := make([]decision, n)
+	for i := range ds {
+		ds[i] = inj.decide("kv1", "vs", "/proto.ViewService/Ping")
+	}
+	return ds
+}
+
+// TestProbabilitySeed applies a probabilistic rule to about its share of messages,
+// and to the same messages again for the same seed
+func TestProbabilitySeed(t *testing.T) {
+	rules := []Rule{
+		{Action: DropRequest, Probability: 0.3},
+		{Action: Delay, Delay: time.Millisecond, Jitter: time.Second, Probability: 0.5},
+	}
+	first := decisions(t, 42, 1000, rules...)
+	again := decisions(t, 42, 1000, rules...)
+	other := decisions(t, 43, 1000, rules...)
+
+	dropped, differ := 0, 0
+	for i := range first {
+		if first[i] != again[i] {
+			t.Fatalf("message %d: %+v, then %+v with the same seed", i, first[i], again[i])
+		}
+		if first[i] != other[i] {
+			differ++
+		}
+		if first[i].dropRequest {
+			dropped++
+		}
+	}
+	if dropped < 250 || dropped > 350 {
+		t.Errorf("dropped %d of 1000 messages with probability 0.3", dropped)
+	}
+	if differ == 0 {
+		t.Error("another seed made the same decisions")
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		check func(d decision) bool
+	}{
+		{"no rules", nil, func(d decision) bool { return d == decision{} }},
+		{"non-matching rule", []Rule{{To: "kv2", Action: DropRequest}},
+			func(d decision) bool { return d == decision{} }},
+		{"actions combine", []Rule{{Action: DropResponse}, {Action: Duplicate}},
+			func(d decision) bool { return d.dropResponse && d.duplicate && !d.dropRequest }},
+		{"delays add up", []Rule{{Action: Delay, Delay: time.Second}, {Action: Delay, Delay: 2 * time.Second}},
+			func(d decision) bool { return d.hold == 3*time.Second }},
+		{"jitter stays below its bound", []Rule{{Action: Delay, Delay: time.Second, Jitter: time.Second}},
+			func(d decision) bool { return d.hold >= time.Second && d.hold < 2*time.Second }},
+		{"reorder holds within its window", []Rule{{Action: Reorder, Window: time.Second}},
+			func(d decision) bool { return d.hold >= 0 && d.hold < time.Second }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, d := range decisions(t, 1, 100, tt.rules...) {
+				if !tt.check(d) {
+					t.Fatalf("message %d: unexpected decision %+v", i, d)
+				}
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	inj := New(1)
+	if _, err := inj.Add(Rule{Action: "corrupt"}); err == nil {
+		t.Fatal("Add accepted an invalid rule")
+	}
+	ids := inj.Partition([]string{"kv1"}, []string{"kv2", "vs"})
+	if len(ids) != 4 {
+		t.Fatalf("Partition added %d rules, want 4", len(ids))
+	}
+	rules := inj.Rules()
+	want := []struct{ from, to string }{{"kv1", "kv2"}, {"kv2", "kv1"}, {"kv1", "vs"}, {"vs", "kv1"}}
+	for i, r := range rules {
+		if r.ID != ids[i] || r.From != want[i].from || r.To != want[i].to || r.Action != DropRequest {
+			t.Errorf("rule %d = %+v, want %s -> %s dropped", i, r, want[i].from, want[i].to)
+		}
+	}
+
+	if !inj.Remove(ids[0]) || inj.Remove(ids[0]) {
+		t.Error("Remove should report a rule once")
+	}
+	if d := inj.decide("kv1", "kv2", "/proto.KVServer/Get"); d.dropRequest {
+		t.Error("removed rule still applies")
+	}
+	inj.Clear()
+	if n := len(inj.Rules()); n != 0 {
+		t.Errorf("%d rules after Clear", n)
+	}
+}
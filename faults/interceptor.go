@@ -0,0 +1,139 @@
+package faults
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	fromHeader    = "x-fault-from"    // name of the sending node
+	toHeader      = "x-fault-to"      // name of the receiving node, set by Target
+	handledHeader = "x-fault-handled" // the sending injector's token, once its interceptor applied the rules
+	unknownNode   = "unknown"         // sender name used for callers without an interceptor
+)
+
+// duplicateTimeout bounds the extra copy sent by a Duplicate rule
+const duplicateTimeout = 5 * time.Second
+
+// UnaryClientInterceptor applies the rules to requests sent by node from. The
+// receiving node is the one named by Target, or else the connection's target
+// address.
+func (inj *Injector) UnaryClientInterceptor(from string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		to := cc.Target()
+		if md, ok := metadata.FromOutgoingContext(ctx); ok {
+			if v := md.Get(toHeader); len(v) > 0 {
+				to = v[len(v)-1]
+			}
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, fromHeader, from, handledHeader, inj.token)
+		d := inj.decide(from, to, method)
+
+		var duplicate func()
+		if d.duplicate {
+			if msg, ok := reply.(proto.Message); ok {
+				extra := proto.Clone(msg)
+				duplicate = func() {
+					dupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), duplicateTimeout)
+					defer cancel()
+					invoker(dupCtx, method, req, extra, cc, opts...)
+				}
+			}
+		}
+		_, err := deliver(ctx, d, from, to, func(ctx context.Context) (any, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		}, duplicate)
+		return err
+	}
+}
+
+// UnaryServerInterceptor applies the rules to requests received by node to. Requests
+// this injector's client interceptor already applied the rules to pass straight
+// through. The mark is a token only this injector knows, so a caller cannot set it
+// to get past the rules; requests sent through another injector, such as one in
+// another process, have the rules applied again on this side.
+func (inj *Injector) UnaryServerInterceptor(to string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if marks := md.Get(handledHeader); len(marks) > 0 {
+			md = md.Copy()
+			delete(md, handledHeader)
+			ctx = metadata.NewIncomingContext(ctx, md)
+			if slices.Contains(marks, inj.token) {
+				return handler(ctx, req)
+			}
+		}
+		from := unknownNode
+		if v := md.Get(fromHeader); len(v) > 0 {
+			from = v[0]
+		}
+		d := inj.decide(from, to, info.FullMethod)
+
+		var duplicate func()
+		if d.duplicate {
+			duplicate = func() {
+				handler(context.WithoutCancel(ctx), req)
+			}
+		}
+		return deliver(ctx, d, from, to, func(ctx context.Context) (any, error) {
+			return handler(ctx, req)
+		}, duplicate)
+	}
+}
+
+// deliver performs call as decided: holding, dropping or duplicating it
+func deliver(ctx context.Context, d decision, from string, to string, call func(context.Context) (any, error), duplicate func()) (any, error) {
+	if d.dropRequest {
+		<-ctx.Done()
+		return nil, status.Error(codes.DeadlineExceeded, fmt.Sprintf("faults: request %s -> %s dropped", from, to))
+	}
+
+	if d.hold > 0 {
+		timer := time.NewTimer(d.hold)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	if duplicate != nil {
+		go duplicate()
+	}
+	resp, err := call(ctx)
+
+	if d.dropResponse {
+		<-ctx.Done()
+		return nil, status.Error(codes.DeadlineExceeded, fmt.Sprintf("faults: response %s -> %s dropped", to, from))
+	}
+	return resp, err
+}
+
+// Target names the node a connection reaches, for connections that dial a node at
+// an address other than its name, such as a KV server's separate replication
+// address. Rules then match the node's name rather than the address. It must be
+// given before the DialOption of the sending node.
+func Target(name string) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, toHeader, name), method, req, reply, cc, opts...)
+	})
+}
+
+// DialOption installs the client interceptor for connections opened by node from
+func (inj *Injector) DialOption(from string) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(inj.UnaryClientInterceptor(from))
+}
+
+// ServerOption installs the server interceptor for node name
+func (inj *Injector) ServerOption(name string) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(inj.UnaryServerInterceptor(name))
+}
@@ -0,0 +1,103 @@
+package faults
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// server runs a health service as node kv1 behind inj's server interceptor and
+// counts the requests that reach the service. marks receives every value of the
+// handled header the service sees.
+type server struct {
+	addr  string
+	calls atomic.Int32
+	marks atomic.Int32
+}
+
+func startServer(t *testing.T, inj *Injector) *server {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{addr: lis.Addr().String()}
+	count := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		s.calls.Add(1)
+		md, _ := metadata.FromIncomingContext(ctx)
+		s.marks.Add(int32(len(md.Get(handledHeader))))
+		return handler(ctx, req)
+	}
+	srv := grpc.NewServer(inj.ServerOption("kv1"), grpc.ChainUnaryInterceptor(count))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return s
+}
+
+// check calls the health service at addr through opts and returns the error code
+func check(t *testing.T, addr string, md metadata.MD, opts ...grpc.DialOption) codes.Code {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), 300*time.Millisecond)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return status.Code(err)
+}
+
+// TestHandledHeader applies the server's rules to every request except those its
+// own client interceptor already applied them to. Under one duplicate rule every
+// request reaches the service twice, and four times if both sides applied it.
+func TestHandledHeader(t *testing.T) {
+	inj := New(1)
+	s := startServer(t, inj)
+	inj.Add(Rule{To: "kv1", Action: Duplicate})
+
+	other := New(1)
+	other.Add(Rule{To: "kv2", Action: DropRequest})
+	tests := []struct {
+		name string
+		md   metadata.MD
+		opts []grpc.DialOption
+	}{
+		{"plain caller", nil, nil},
+		{"caller forging the header", metadata.Pairs(handledHeader, "1"), nil},
+		{"caller using another injector's token", metadata.Pairs(handledHeader, other.token), nil},
+		{"another injector's client", nil, []grpc.DialOption{Target("kv1"), other.DialOption("c")}},
+		{"own client", nil, []grpc.DialOption{Target("kv1"), inj.DialOption("c")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.calls.Store(0)
+			s.marks.Store(0)
+			if got := check(t, s.addr, tt.md, tt.opts...); got != codes.OK {
+				t.Fatalf("got %v, want OK", got)
+			}
+			deadline := time.Now().Add(time.Second)
+			for s.calls.Load() < 2 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond) // let any further copy arrive
+			if got := s.calls.Load(); got != 2 {
+				t.Errorf("service got %d copies, want 2", got)
+			}
+			if got := s.marks.Load(); got != 0 {
+				t.Errorf("service saw the handled header %d times", got)
+			}
+		})
+	}
+}
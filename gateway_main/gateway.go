@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/gateway_main/gateway"
+	"goDistributedSystemDemo/logging"
+	"goDistributedSystemDemo/metrics"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
+)
+
+func main() {
+	httpAddr := flag.String("addr", "localhost:8080", "Address to serve the HTTP/JSON API on (host:port)")
+	vsAddr := flag.String("vs", "localhost:8000", "View service address (host:port)")
+	timeout := flag.Duration("timeout", client.DefaultOpTimeout, "Deadline for each operation, including retries through a failover")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics (disabled if empty)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "Time allowed for requests in progress to finish on SIGTERM or interrupt")
+	logLevel := flag.String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	traceFile := flag.String("trace-file", "", "Append trace spans to this file as JSON lines (disabled if empty)")
+	traceOTLP := flag.String("trace-otlp", "", "Send trace spans to this OTLP/gRPC collector, host:port without TLS (disabled if empty)")
+	traceSample := flag.Float64("trace-sample", 1, "Fraction of new traces recorded (0-1)")
+	tlsCert := flag.String("tls-cert", "", "Client certificate to present to servers that ask for one (PEM, optional)")
+	tlsKey := flag.String("tls-key", "", "Private key of -tls-cert (PEM)")
+	tlsCA := flag.String("tls-ca", "", "CA certificates that sign the servers' certificates (PEM); setting any -tls flag enables TLS")
+	tokenFile := flag.String("token-file", "", "File holding a bearer token to authenticate with, instead of the TLS certificate (needs TLS)")
+	forwardTokens := flag.Bool("forward-tokens", false, "Require a bearer token on every HTTP request and pass it on, so the cluster authorizes the caller (needs -https-cert and -tls-ca)")
+	httpsCert := flag.String("https-cert", "", "Certificate to serve HTTPS with (PEM); plain HTTP if empty")
+	httpsKey := flag.String("https-key", "", "Private key of -https-cert (PEM)")
+	flag.Parse()
+
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	tlsCfg := tlsconfig.Config{CertFile: *tlsCert, KeyFile: *tlsKey, CAFile: *tlsCA}
+	if err := checkIdentity(*forwardTokens, *httpsCert, *httpsKey, tlsCfg, *tokenFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Starting HTTP gateway on %s\n", *httpAddr)
+	fmt.Printf("View Service at %s\n", *vsAddr)
+	fmt.Printf("PID: %d\n", os.Getpid())
+
+	opts := []client.Option{client.WithOpTimeout(*timeout)}
+	var gatewayOpts []gateway.Option
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		opts = append(opts, client.WithMetrics(reg))
+		gatewayOpts = append(gatewayOpts, gateway.WithMetrics(reg))
+		metrics.ListenAndServe(*metricsAddr, reg)
+	}
+
+	if tlsCfg.Enabled() {
+		certs, err := tlsconfig.Load(tlsCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, client.WithTLS(certs))
+	}
+
+	if *tokenFile != "" {
+		tokenOpt, err := auth.TokenDialOption(*tokenFile, tlsCfg.Enabled())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, client.WithDialOptions(tokenOpt))
+	}
+
+	traceCfg := tracing.Config{Service: "gateway", Instance: *httpAddr, File: *traceFile, OTLPEndpoint: *traceOTLP, SampleRatio: *traceSample}
+	if traceCfg.Enabled() {
+		tp, shutdown, err := tracing.New(context.Background(), traceCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer tracing.Flush(context.Background(), shutdown)
+		opts = append(opts, client.WithTracerProvider(tp))
+	}
+
+	ck, err := client.MakeClient(*vsAddr, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer ck.Close()
+
+	if *forwardTokens {
+		gatewayOpts = append(gatewayOpts, gateway.WithForwardedTokens())
+	}
+	srv := &http.Server{
+		Addr:              *httpAddr,
+		Handler:           gateway.New(ck, gatewayOpts...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		serve := srv.ListenAndServe
+		if *httpsCert != "" {
+			serve = func() error { return srv.ListenAndServeTLS(*httpsCert, *httpsKey) }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Gateway failed to serve", "addr", *httpAddr, "err", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for interrupt signal, then let the requests in progress finish
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	fmt.Println("\nShutting down gateway...")
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Printf("Graceful shutdown incomplete: %v\n", err)
+	}
+}
+
+// checkIdentity refuses flag combinations that would let HTTP callers act with an
+// identity that is not theirs. Forwarded tokens need TLS on both sides, since they
+// would otherwise cross the network in the clear, and replace -token-file. Without
+// them the gateway may not present credentials of its own, which every caller
+// would share; it then only works with a cluster that has no auth policy.
+func checkIdentity(forward bool, httpsCert string, httpsKey string, tlsCfg tlsconfig.Config, tokenFile string) error {
+	if (httpsCert == "") != (httpsKey == "") {
+		return errors.New("gateway: -https-cert and -https-key must be set together")
+	}
+	if forward {
+		switch {
+		case httpsCert == "":
+			return errors.New("gateway: -forward-tokens needs -https-cert, callers' tokens would be sent in the clear")
+		case !tlsCfg.Enabled():
+			return errors.New("gateway: -forward-tokens needs TLS to the cluster, callers' tokens would be sent in the clear")
+		case tokenFile != "":
+			return errors.New("gateway: -token-file cannot be used with -forward-tokens, each caller brings its own token")
+		}
+		return nil
+	}
+	if tokenFile != "" || tlsCfg.CertFile != "" {
+		return errors.New("gateway: -token-file and -tls-cert would give every HTTP caller the gateway's identity; use -forward-tokens")
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+// Package gateway serves the KV API over HTTP with JSON bodies, for tooling that
+// cannot speak gRPC. Every request goes through a client.Client, which follows the
+// primary through failovers, so callers are never redirected to another server:
+//
+//	GET    /v1/keys/{key}              {"key": "a", "value": "1"}, 404 if the key does not exist
+//	PUT    /v1/keys/{key}              body {"value": "1"}, 204
+//	DELETE /v1/keys/{key}              204, also if the key does not exist
+//	GET    /v1/keys?prefix=a&limit=10  {"entries": [{"key": "a", "value": "1"}]} in key order
+//
+// Keys may contain slashes. Errors come back as {"error": "..."} with a status
+// saying what went wrong: 400 for a malformed request, 401 if the cluster did not
+// accept the caller's identity, 403 if it denied the request, 503 with Retry-After
+// while no primary is reachable and 504 if the operation timed out.
+//
+// By default the gateway calls the cluster as itself, so every HTTP caller gets
+// the gateway's grants. WithForwardedTokens passes each caller's bearer token on
+// instead, so the cluster's policy applies to the caller.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/logging"
+	"goDistributedSystemDemo/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxBodyBytes bounds a request body; the servers accept no larger gRPC message
+const maxBodyBytes = 4 << 20
+
+// Gateway is an http.Handler serving the KV API through a client
+type Gateway struct {
+	ck         *client.Client
+	mux        *http.ServeMux
+	logger     *slog.Logger
+	registerer prometheus.Registerer // receives the gateway's metrics
+	metrics    *gatewayMetrics
+	forward    bool // pass each caller's bearer token on to the cluster
+}
+
+// Option configures a Gateway
+type Option func(*Gateway)
+
+// WithLogger makes the gateway log to l instead of the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(g *Gateway) {
+		g.logger = l
+	}
+}
+
+// WithMetrics registers the gateway's metrics with reg, to be served on /metrics
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(g *Gateway) {
+		g.registerer = reg
+	}
+}
+
+// WithForwardedTokens makes every request authenticate with an
+// "Authorization: Bearer <token>" header, which is passed on to the cluster in
+// place of the gateway's own credentials. Requests without one get 401.
+func WithForwardedTokens() Option {
+	return func(g *Gateway) {
+		g.forward = true
+	}
+}
+
+// New returns a gateway forwarding requests through ck
+func New(ck *client.Client, opts ...Option) *Gateway {
+	g := &Gateway{
+		ck:     ck,
+		mux:    http.NewServeMux(),
+		logger: slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.registerer == nil {
+		g.registerer = prometheus.NewRegistry()
+	}
+	g.metrics = newGatewayMetrics(g.registerer)
+
+	g.handle("GET /v1/keys/{key...}", "get", g.get)
+	g.handle("PUT /v1/keys/{key...}", "put", g.put)
+	g.handle("DELETE /v1/keys/{key...}", "delete", g.delete)
+	g.handle("GET /v1/keys", "scan", g.scan)
+	return g
+}
+
+// ServeHTTP implements http.Handler
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// handler serves one route, returning the status and body of the reply, or an
+// error to be turned into one
+type handler func(ctx context.Context, r *http.Request) (int, any, error)
+
+// handle registers h for pattern. Each request carries the caller's request ID,
+// or a new one, and trace context to the servers, and is logged and counted.
+func (g *Gateway) handle(pattern string, route string, h handler) {
+	g.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.RequestIDHeader)
+		if id == "" {
+			id = logging.NewRequestID()
+		}
+		ctx := logging.WithRequestID(tracing.Extract(r.Context(), r.Header), id)
+		w.Header().Set(logging.RequestIDHeader, id)
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+		start := time.Now()
+		var code int
+		var body any
+		var err error
+		if g.forward {
+			ctx, err = forwardToken(ctx, r)
+		}
+		if err == nil {
+			code, body, err = h(ctx, r)
+		}
+		if err != nil {
+			code, body = g.errorReply(w, err)
+		}
+		if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+			code = 499 // the caller went away; nobody reads the reply
+		} else {
+			writeJSON(w, code, body)
+		}
+
+		g.metrics.observe(route, code, time.Since(start))
+		g.logger.LogAttrs(ctx, slog.LevelDebug, "HTTP request handled",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("request_id", id),
+			slog.Int("status", code),
+			slog.Duration("duration", time.Since(start)))
+	})
+}
+
+// keyValue is a key and its value in replies
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// errorBody is the reply to a request that failed
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// badRequest is a malformed request, reported as 400
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+// unauthenticated is a request without the credentials the gateway needs, reported as 401
+type unauthenticated string
+
+func (e unauthenticated) Error() string {
+	return string(e)
+}
+
+// forwardToken attaches the caller's bearer token to the calls made for r
+func forwardToken(ctx context.Context, r *http.Request) (context.Context, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return ctx, unauthenticated("missing bearer token")
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
+
+// get serves GET /v1/keys/{key}
+func (g *Gateway) get(ctx context.Context, r *http.Request) (int, any, error) {
+	key := r.PathValue("key")
+	if key == "" {
+		return 0, nil, badRequest("empty key")
+	}
+	value, found, err := g.ck.Get(ctx, key)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !found {
+		return http.StatusNotFound, errorBody{Error: "no such key"}, nil
+	}
+	return http.StatusOK, keyValue{Key: key, Value: value}, nil
+}
+
+// put serves PUT /v1/keys/{key} with body {"value": ...}
+func (g *Gateway) put(ctx context.Context, r *http.Request) (int, any, error) {
+	key := r.PathValue("key")
+	if key == "" {
+		return 0, nil, badRequest("empty key")
+	}
+	var body struct {
+		Value *string `json:"value"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return http.StatusRequestEntityTooLarge, errorBody{Error: err.Error()}, nil
+		}
+		return 0, nil, badRequest("invalid body: " + err.Error())
+	}
+	if body.Value == nil {
+		return 0, nil, badRequest(`body must be {"value": "..."}`)
+	}
+	if err := g.ck.Put(ctx, key, *body.Value); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// delete serves DELETE /v1/keys/{key}
+func (g *Gateway) delete(ctx context.Context, r *http.Request) (int, any, error) {
+	key := r.PathValue("key")
+	if key == "" {
+		return 0, nil, badRequest("empty key")
+	}
+	if err := g.ck.Delete(ctx, key); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// scan serves GET /v1/keys?prefix=...&limit=...
+func (g *Gateway) scan(ctx context.Context, r *http.Request) (int, any, error) {
+	query := r.URL.Query()
+	limit := 0
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, nil, badRequest("limit must be a number of entries, 0 for all")
+		}
+		limit = n
+	}
+	entries, err := g.ck.Scan(ctx, query.Get("prefix"), limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	reply := struct {
+		Entries []keyValue `json:"entries"`
+	}{Entries: make([]keyValue, 0, len(entries))}
+	for _, e := range entries {
+		reply.Entries = append(reply.Entries, keyValue{Key: e.Key, Value: e.Value})
+	}
+	return http.StatusOK, reply, nil
+}
+
+// errorReply chooses the status and body reporting err, and asks the caller to
+// retry later if the cluster has no primary for now
+func (g *Gateway) errorReply(w http.ResponseWriter, err error) (int, errorBody) {
+	var bad badRequest
+	var unauth unauthenticated
+	code := http.StatusBadGateway
+	switch {
+	case errors.As(err, &bad):
+		code = http.StatusBadRequest
+	case errors.As(err, &unauth), status.Code(err) == codes.Unauthenticated:
+		code = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	case errors.Is(err, client.ErrUnavailable), errors.Is(err, client.ErrRetriesExhausted):
+		code = http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", "1")
+	case errors.Is(err, client.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		code = http.StatusGatewayTimeout
+	case status.Code(err) == codes.PermissionDenied:
+		code = http.StatusForbidden
+	}
+	return code, errorBody{Error: err.Error()}
+}
+
+// writeJSON writes body as the reply, or no body if it is nil
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	if body == nil {
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/harness"
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// request is one HTTP request to a gateway and the reply expected
+type request struct {
+	method   string
+	target   string
+	token    string // sent as a bearer token if not empty
+	body     string
+	wantCode int
+	wantBody string // must appear in the reply body
+}
+
+// serve sends each request to g in order
+func serve(t *testing.T, g *Gateway, requests []request) {
+	t.Helper()
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.target, strings.NewReader(req.body))
+		if req.token != "" {
+			r.Header.Set("Authorization", "Bearer "+req.token)
+		}
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, r)
+		if w.Code != req.wantCode || !strings.Contains(w.Body.String(), req.wantBody) {
+			t.Errorf("%s %s = %d %s, want %d with %q", req.method, req.target, w.Code, w.Body, req.wantCode, req.wantBody)
+		}
+		// The mux refuses a method no route serves before any handler runs
+		if req.wantCode != http.StatusMethodNotAllowed && w.Header().Get("X-Request-Id") == "" {
+			t.Errorf("%s %s: reply has no request ID", req.method, req.target)
+		}
+	}
+}
+
+// cluster starts one KV server with opts and returns a client of it once it serves
+func cluster(t *testing.T, opts ...kvserver.Option) *client.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := harness.StartT(t, 1, harness.WithServerOptions(opts...))
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" }); err != nil {
+		t.Fatal(err)
+	}
+	return c.ClientT(t, client.WithOpTimeout(5*time.Second))
+}
+
+func TestRoutes(t *testing.T) {
+	g := New(cluster(t))
+	serve(t, g, []request{
+		{"PUT", "/v1/keys/app/greeting", "", `{"value": "hello"}`, http.StatusNoContent, ""},
+		{"PUT", "/v1/keys/app/other", "", `{"value": ""}`, http.StatusNoContent, ""},
+		{"GET", "/v1/keys/app/greeting", "", "", http.StatusOK, `{"key":"app/greeting","value":"hello"}`},
+		{"GET", "/v1/keys/missing", "", "", http.StatusNotFound, `"error":"no such key"`},
+		{"GET", "/v1/keys?prefix=app/&limit=1", "", "", http.StatusOK, `{"entries":[{"key":"app/greeting","value":"hello"}]}`},
+		{"GET", "/v1/keys?prefix=none/", "", "", http.StatusOK, `{"entries":[]}`},
+		{"GET", "/v1/keys?limit=-1", "", "", http.StatusBadRequest, "limit"},
+		{"PUT", "/v1/keys/app/greeting", "", `{"value": 1}`, http.StatusBadRequest, "invalid body"},
+		{"PUT", "/v1/keys/app/greeting", "", `{"val": "x"}`, http.StatusBadRequest, "invalid body"},
+		{"PUT", "/v1/keys/app/greeting", "", `{}`, http.StatusBadRequest, "body must be"},
+		{"PUT", "/v1/keys/big", "", fmt.Sprintf(`{"value": "%s"}`, strings.Repeat("x", maxBodyBytes)), http.StatusRequestEntityTooLarge, "too large"},
+		{"PUT", "/v1/keys/", "", `{"value": "x"}`, http.StatusBadRequest, "empty key"},
+		{"POST", "/v1/keys/app/greeting", "", `{"value": "x"}`, http.StatusMethodNotAllowed, ""},
+		{"DELETE", "/v1/keys/app/greeting", "", "", http.StatusNoContent, ""},
+		{"DELETE", "/v1/keys/app/greeting", "", "", http.StatusNoContent, ""},
+		{"GET", "/v1/keys/app/greeting", "", "", http.StatusNotFound, ""},
+	})
+}
+
+// TestForwardedTokens has the KV server authorize the callers' own tokens: alice
+// may use keys under a/, and callers without a known token get 401
+func TestForwardedTokens(t *testing.T) {
+	policy := &auth.Policy{
+		Clients: map[string][]auth.Grant{"alice": {{Prefix: "a/", Read: true, Write: true}}},
+		Tokens:  map[string]string{"alice": auth.HashToken("alice-token")},
+	}
+	authz := auth.New(policy, slog.New(slog.DiscardHandler))
+	g := New(cluster(t, kvserver.WithAuthorizer(authz)), WithForwardedTokens())
+	serve(t, g, []request{
+		{"PUT", "/v1/keys/a/x", "alice-token", `{"value": "1"}`, http.StatusNoContent, ""},
+		{"GET", "/v1/keys/a/x", "alice-token", "", http.StatusOK, `"value":"1"`},
+		{"PUT", "/v1/keys/b/x", "alice-token", `{"value": "1"}`, http.StatusForbidden, "alice may not write"},
+		{"GET", "/v1/keys/a/x", "wrong-token", "", http.StatusUnauthorized, "unknown bearer token"},
+		{"GET", "/v1/keys/a/x", "", "", http.StatusUnauthorized, "missing bearer token"},
+	})
+}
+
+func TestErrorReply(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantHeader string // header the reply must set
+	}{
+		{"bad request", badRequest("empty key"), http.StatusBadRequest, ""},
+		{"no token", unauthenticated("missing bearer token"), http.StatusUnauthorized, "WWW-Authenticate"},
+		{"token refused", status.Error(codes.Unauthenticated, "unknown bearer token"), http.StatusUnauthorized, "WWW-Authenticate"},
+		{"denied", status.Error(codes.PermissionDenied, "may not write"), http.StatusForbidden, ""},
+		{"no primary", fmt.Errorf("get: %w", client.ErrUnavailable), http.StatusServiceUnavailable, "Retry-After"},
+		{"retries exhausted", fmt.Errorf("get: %w", client.ErrRetriesExhausted), http.StatusServiceUnavailable, "Retry-After"},
+		{"timeout", fmt.Errorf("get: %w", client.ErrTimeout), http.StatusGatewayTimeout, ""},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, ""},
+		{"other", errors.New("boom"), http.StatusBadGateway, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			code, body := (&Gateway{}).errorReply(w, tt.err)
+			if code != tt.wantCode {
+				t.Errorf("code %d, want %d", code, tt.wantCode)
+			}
+			if body.Error != tt.err.Error() {
+				t.Errorf("body %q, want %q", body.Error, tt.err.Error())
+			}
+			if tt.wantHeader != "" && w.Header().Get(tt.wantHeader) == "" {
+				t.Errorf("reply has no %s header", tt.wantHeader)
+			}
+		})
+	}
+}
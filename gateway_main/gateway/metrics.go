@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"strconv"
+	"time"
+
+	"goDistributedSystemDemo/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gatewayMetrics are the gateway's Prometheus metrics
+type gatewayMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// newGatewayMetrics creates the gateway's metrics and registers them with reg
+func newGatewayMetrics(reg prometheus.Registerer) *gatewayMetrics {
+	m := &gatewayMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by route and status code.",
+		}, []string{"route", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gateway",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by route.",
+			Buckets:   metrics.LatencyBuckets,
+		}, []string{"route"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// observe records one handled request
+func (m *gatewayMetrics) observe(route string, code int, d time.Duration) {
+	m.requests.WithLabelValues(route, strconv.Itoa(code)).Inc()
+	m.duration.WithLabelValues(route).Observe(d.Seconds())
+}
@@ -0,0 +1,378 @@
+// Package harness runs a view service and a set of KV servers inside one process
+// on ephemeral ports, so failover scenarios can be written as ordinary go tests:
+//
+//	c := harness.StartT(t, 3)
+//	view, _ := c.WaitForStable(ctx)
+//	c.KillByName(view.Primary)
+//	c.WaitForView(ctx, func(v *pb.View) bool { return v.Primary == view.Backup })
+//
+// Links between nodes can be cut to simulate partitions, and Faults() gives access
+// to the full fault injector for delays, duplicates and reordering. Nodes are named
+// by their address; ViewServiceName() names the view service and ClientName all
+// clients created through the cluster.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/faults"
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/view/viewservice"
+)
+
+// ClientName is the node name shared by all clients created with Cluster.Client
+const ClientName = "client"
+
+// pollInterval is how often WaitForView and WaitForAcked look at the view
+const pollInterval = 20 * time.Millisecond
+
+// Cluster is a view service and KV servers running in this process
+type Cluster struct {
+	mu      sync.Mutex
+	vs      *viewservice.ViewServer
+	servers []*Server
+	clients []*client.Client
+	faults  *faults.Injector
+	kvOpts  []kvserver.Option // extra options for every KV server
+}
+
+// Option configures a Cluster
+type Option func(*Cluster)
+
+// WithServerOptions starts every KV server with opts, after the harness's own
+func WithServerOptions(opts ...kvserver.Option) Option {
+	return func(c *Cluster) {
+		c.kvOpts = append(c.kvOpts, opts...)
+	}
+}
+
+// Server is one KV server of the cluster. It keeps its address across restarts.
+type Server struct {
+	Name string // address of the server, also its name in the view
+	kv   *kvserver.KVServer
+}
+
+// Alive reports whether the server is running
+func (s *Server) Alive() bool {
+	return s.kv != nil
+}
+
+// Start launches a view service and n KV servers on ephemeral ports
+func Start(n int, opts ...Option) (*Cluster, error) {
+	c := &Cluster{
+		servers: make([]*Server, 0, n),
+		clients: make([]*client.Client, 0),
+		faults:  faults.New(1),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	lis, err := listen("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	vsAddress := lis.Addr().String()
+	c.vs, err = viewservice.StartServer(vsAddress, viewservice.WithListener(lis),
+		viewservice.WithServerOptions(c.faults.ServerOption(vsAddress)))
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		if _, err := c.AddServer(); err != nil {
+			c.Shutdown()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// StartT is Start for tests: it fails t on error and shuts the cluster down at cleanup
+func StartT(t testing.TB, n int, opts ...Option) *Cluster {
+	t.Helper()
+	c, err := Start(n, opts...)
+	if err != nil {
+		t.Fatalf("harness: %v", err)
+	}
+	t.Cleanup(c.Shutdown)
+	return c
+}
+
+// ViewServiceName returns the view service's address, which is also its node name
+func (c *Cluster) ViewServiceName() string {
+	return c.vs.Addr()
+}
+
+// ViewServer returns the running view service
+func (c *Cluster) ViewServer() *viewservice.ViewServer {
+	return c.vs
+}
+
+// AddServer starts one more KV server on a free port
+func (c *Cluster) AddServer() (*Server, error) {
+	// The server is handed the listener, so no other process can take the port
+	// between choosing it and serving on it
+	lis, err := listen("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &Server{Name: lis.Addr().String()}
+	if s.kv, err = c.startKV(s, lis); err != nil {
+		return nil, err
+	}
+	c.servers = append(c.servers, s)
+	return s, nil
+}
+
+// startKV starts the KV server for s on lis; c.mu must be held
+func (c *Cluster) startKV(s *Server, lis net.Listener) (*kvserver.KVServer, error) {
+	opts := append([]kvserver.Option{
+		kvserver.WithListener(lis),
+		kvserver.WithDialOptions(c.faults.DialOption(s.Name)),
+		kvserver.WithServerOptions(c.faults.ServerOption(s.Name)),
+	}, c.kvOpts...)
+	kv, err := kvserver.StartServer(s.Name, c.vs.Addr(), opts...)
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return kv, nil
+}
+
+// listen opens a TCP listener on address
+func listen(address string) (net.Listener, error) {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("harness: cannot listen on %s: %w", address, err)
+	}
+	return lis, nil
+}
+
+// Servers returns every server of the cluster, alive or not, in start order
+func (c *Cluster) Servers() []*Server {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Server(nil), c.servers...)
+}
+
+// Server returns the i-th server in start order
+func (c *Cluster) Server(i int) *Server {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.servers[i]
+}
+
+// ServerByName returns the server with the given address, or nil
+func (c *Cluster) ServerByName(name string) *Server {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.servers {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Kill stops the i-th server abruptly; its data is lost
+func (c *Cluster) Kill(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kill(c.servers[i])
+}
+
+// KillByName stops the server with the given address
+func (c *Cluster) KillByName(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.servers {
+		if s.Name == name {
+			c.kill(s)
+			return nil
+		}
+	}
+	return fmt.Errorf("harness: no server named %s", name)
+}
+
+// Stop shuts the i-th server down gracefully: a primary hands off to its backup
+// before exiting. The server is stopped even if the handoff fails.
+func (c *Cluster) Stop(ctx context.Context, i int) error {
+	c.mu.Lock()
+	s := c.servers[i]
+	kv := s.kv
+	s.kv = nil
+	c.mu.Unlock()
+	if kv == nil {
+		return nil
+	}
+	return kv.Shutdown(ctx)
+}
+
+// kill stops s if it is running; c.mu must be held
+func (c *Cluster) kill(s *Server) {
+	if s.kv != nil {
+		s.kv.Kill()
+		s.kv = nil
+	}
+}
+
+// Restart starts the i-th server again on its old address with empty state,
+// killing it first if it is still running. It fails if another process took the
+// address while the server was down; the server then stays down.
+func (c *Cluster) Restart(i int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.servers[i]
+	c.kill(s)
+	lis, err := listen(s.Name)
+	if err != nil {
+		return err
+	}
+	s.kv, err = c.startKV(s, lis)
+	return err
+}
+
+// Faults returns the fault injector shared by every node of the cluster
+func (c *Cluster) Faults() *faults.Injector {
+	return c.faults
+}
+
+// Partition cuts every link between a node in one group and a node in the other,
+// in both directions
+func (c *Cluster) Partition(a []string, b []string) {
+	c.faults.Partition(a, b)
+}
+
+// Isolate cuts every link to and from the named node
+func (c *Cluster) Isolate(name string) {
+	others := []string{c.vs.Addr(), ClientName}
+	for _, s := range c.Servers() {
+		if s.Name != name {
+			others = append(others, s.Name)
+		}
+	}
+	c.Partition([]string{name}, others)
+}
+
+// Cut drops requests sent from one node to another, leaving the reverse direction intact
+func (c *Cluster) Cut(from string, to string) {
+	c.faults.Cut(from, to)
+}
+
+// Heal removes every fault rule, restoring all links
+func (c *Cluster) Heal() {
+	c.faults.Clear()
+}
+
+// Client returns a client of the cluster; it is closed on Shutdown
+func (c *Cluster) Client(opts ...client.Option) (*client.Client, error) {
+	opts = append([]client.Option{
+		client.WithDialOptions(c.faults.DialOption(ClientName)),
+	}, opts...)
+	ck, err := client.MakeClient(c.vs.Addr(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.clients = append(c.clients, ck)
+	c.mu.Unlock()
+	return ck, nil
+}
+
+// ClientT is Client for tests: it fails t if the client cannot be made
+func (c *Cluster) ClientT(t testing.TB, opts ...client.Option) *client.Client {
+	t.Helper()
+	ck, err := c.Client(opts...)
+	if err != nil {
+		t.Fatalf("harness: %v", err)
+	}
+	return ck
+}
+
+// View returns the view service's current view
+func (c *Cluster) View() *pb.View {
+	return c.getView().View
+}
+
+// getView asks the view service for its current view
+func (c *Cluster) getView() *pb.GetViewResponse {
+	resp, _ := c.vs.GetView(context.Background(), &pb.GetViewRequest{})
+	return resp
+}
+
+// WaitForView polls the view until cond holds or ctx ends
+func (c *Cluster) WaitForView(ctx context.Context, cond func(*pb.View) bool) (*pb.View, error) {
+	return c.waitFor(ctx, func(resp *pb.GetViewResponse) bool {
+		return cond(resp.View)
+	})
+}
+
+// WaitForAcked waits until cond holds for a view its primary has acknowledged.
+// From then on the primary acts on that view: a write it acknowledges is on the
+// backup the view names.
+func (c *Cluster) WaitForAcked(ctx context.Context, cond func(*pb.View) bool) (*pb.View, error) {
+	return c.waitFor(ctx, func(resp *pb.GetViewResponse) bool {
+		return resp.PrimaryAcked && cond(resp.View)
+	})
+}
+
+// waitFor polls the view service until cond holds or ctx ends
+func (c *Cluster) waitFor(ctx context.Context, cond func(*pb.GetViewResponse) bool) (*pb.View, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		resp := c.getView()
+		if cond(resp) {
+			return resp.View, nil
+		}
+		select {
+		case <-ctx.Done():
+			view := resp.View
+			return view, fmt.Errorf("harness: view condition not met, last view %d (primary %q, backup %q): %w",
+				view.ViewNumber, view.Primary, view.Backup, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitForStable waits until the view has both a primary and a backup
+func (c *Cluster) WaitForStable(ctx context.Context) (*pb.View, error) {
+	return c.WaitForView(ctx, func(v *pb.View) bool {
+		return v.Primary != "" && v.Backup != ""
+	})
+}
+
+// WaitForViewNumber waits until the view number is at least n
+func (c *Cluster) WaitForViewNumber(ctx context.Context, n uint64) (*pb.View, error) {
+	return c.WaitForView(ctx, func(v *pb.View) bool {
+		return v.ViewNumber >= n
+	})
+}
+
+// Shutdown stops every client and server of the cluster
+func (c *Cluster) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ck := range c.clients {
+		ck.Close()
+	}
+	c.clients = nil
+	for _, s := range c.servers {
+		c.kill(s)
+	}
+	if c.vs != nil {
+		c.vs.Kill()
+		c.vs = nil
+	}
+}
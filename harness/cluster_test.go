@@ -0,0 +1,254 @@
+package harness_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+	"goDistributedSystemDemo/harness"
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	"goDistributedSystemDemo/linearizability"
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// timeout bounds each test; failovers take a few DeadIntervals
+const timeout = 30 * time.Second
+
+// mustGet fails t unless key reads as want
+func mustGet(ctx context.Context, t *testing.T, ck *client.Client, key string, want string) {
+	t.Helper()
+	value, found, err := ck.Get(ctx, key)
+	if err != nil || !found || value != want {
+		t.Fatalf("Get(%s) = %q, %v, %v; want %q", key, value, found, err, want)
+	}
+}
+
+// mustPut fails t unless the write is acknowledged
+func mustPut(ctx context.Context, t *testing.T, ck *client.Client, key string, value string) {
+	t.Helper()
+	if err := ck.Put(ctx, key, value); err != nil {
+		t.Fatalf("Put(%s, %s): %v", key, value, err)
+	}
+}
+
+// waitForPrimary waits until name is primary of the view
+func waitForPrimary(ctx context.Context, t *testing.T, c *harness.Cluster, name string) *pb.View {
+	t.Helper()
+	view, err := c.WaitForView(ctx, func(v *pb.View) bool { return v.Primary == name })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return view
+}
+
+// dialServer connects to a KV server directly, past the client and the fault
+// injector; the connection is closed at cleanup
+func dialServer(t *testing.T, address string) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestFailover writes through a chain of primaries: each time the primary is
+// killed its backup takes over with every acknowledged write, and a new server
+// becomes backup.
+func TestFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 1)
+	ck := c.ClientT(t)
+
+	first := c.Server(0).Name
+	waitForPrimary(ctx, t, c, first)
+	mustPut(ctx, t, ck, "a", "1")
+	mustGet(ctx, t, ck, "a", "1")
+
+	second, err := c.AddServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Backup == second.Name }); err != nil {
+		t.Fatal(err)
+	}
+	// The primary knows its backup, so it acknowledges the write only once the
+	// backup received the state and applied the write
+	mustPut(ctx, t, ck, "b", "2")
+
+	c.Kill(0)
+	waitForPrimary(ctx, t, c, second.Name)
+	mustGet(ctx, t, ck, "a", "1")
+	mustGet(ctx, t, ck, "b", "2")
+
+	third, err := c.AddServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Backup == third.Name }); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(ctx, t, ck, "c", "3")
+
+	c.Kill(1)
+	waitForPrimary(ctx, t, c, third.Name)
+	mustGet(ctx, t, ck, "a", "1")
+	mustGet(ctx, t, ck, "b", "2")
+	mustGet(ctx, t, ck, "c", "3")
+}
+
+// TestPrimaryPartitionedFromViewService cuts the primary off from the view
+// service but not from clients. The backup takes over, and the old primary must
+// refuse reads and writes instead of serving clients that still reach it.
+func TestPrimaryPartitionedFromViewService(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 3)
+	ck := c.ClientT(t)
+
+	view, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" && v.Backup != "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPut(ctx, t, ck, "a", "1")
+
+	c.Partition([]string{view.Primary}, []string{c.ViewServiceName()})
+	waitForPrimary(ctx, t, c, view.Backup)
+	mustPut(ctx, t, ck, "a", "2")
+
+	old := pb.NewKVServerClient(dialServer(t, view.Primary))
+	get, err := old.Get(ctx, &pb.GetRequest{Key: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get.Error == "" {
+		t.Errorf("old primary answered Get(a) = %q after the backup took over", get.Value)
+	}
+	put, err := old.Put(ctx, &pb.PutRequest{Key: "a", Value: "stale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put.Error == "" {
+		t.Error("old primary acknowledged Put(a) after the backup took over")
+	}
+
+	c.Heal()
+	mustGet(ctx, t, ck, "a", "2")
+}
+
+// TestPrimaryPartitionedFromBackup cuts the link between primary and backup while
+// both still reach the view service. Writes must fail rather than be acknowledged
+// by the primary alone, and succeed again once the link heals. The link is cut by
+// server name, so it is cut as well when replication has an address of its own.
+func TestPrimaryPartitionedFromBackup(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []harness.Option
+	}{
+		{"shared address", nil},
+		{"peer address", []harness.Option{harness.WithServerOptions(kvserver.WithPeerAddress("127.0.0.1:0"))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			c := harness.StartT(t, 2, tt.opts...)
+			ck := c.ClientT(t)
+
+			view, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" && v.Backup != "" })
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustPut(ctx, t, ck, "a", "1")
+
+			c.Partition([]string{view.Primary}, []string{view.Backup})
+			putCtx, putCancel := context.WithTimeout(ctx, 2*time.Second)
+			err = ck.Put(putCtx, "a", "2")
+			putCancel()
+			if err == nil {
+				t.Fatal("Put(a) was acknowledged while the backup was unreachable")
+			}
+
+			c.Heal()
+			mustPut(ctx, t, ck, "a", "3")
+			c.KillByName(view.Primary)
+			waitForPrimary(ctx, t, c, view.Backup)
+			mustGet(ctx, t, ck, "a", "3")
+		})
+	}
+}
+
+// TestLinearizableAcrossFailover records concurrent clients while the primary is
+// killed twice and checks that the history is linearizable
+func TestLinearizableAcrossFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := harness.StartT(t, 3)
+	ck := c.ClientT(t)
+
+	view, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" && v.Backup != "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := linearizability.NewRecorder()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		rc := rec.Client(ck)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				opCtx, opCancel := context.WithTimeout(ctx, 2*time.Second)
+				key := fmt.Sprint("k", rand.IntN(3))
+				if rand.IntN(2) == 0 {
+					rc.Put(opCtx, key, fmt.Sprintf("w%d-%d", w, i))
+				} else {
+					rc.Get(opCtx, key)
+				}
+				opCancel()
+			}
+		}()
+	}
+
+	for range 2 {
+		time.Sleep(time.Second)
+		c.KillByName(view.Primary)
+		if view, err = c.WaitForAcked(ctx, func(v *pb.View) bool {
+			return v.Primary == view.Backup && v.Backup != ""
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddServer(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Second)
+	close(stop)
+	wg.Wait()
+
+	ops, err := rec.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := linearizability.Check(ctx, ops)
+	if !result.Linearizable {
+		t.Fatalf("%d operations: %s", len(ops), result)
+	}
+	t.Logf("%d operations are linearizable", len(ops))
+}
@@ -1,32 +1,191 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/faults"
 	"goDistributedSystemDemo/kv_server_main/kvserver"
+	"goDistributedSystemDemo/logging"
+	"goDistributedSystemDemo/metrics"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
 	serverAddr := flag.String("addr", "localhost:8001", "KV server address (host:port)")
 	vsAddr := flag.String("vs", "localhost:8000", "View service address (host:port)")
+	peerAddr := flag.String("peer-addr", "", "Address to serve replication from the primary on, apart from clients (on -addr if empty)")
+	peerMaxMsgSize := flag.Int("peer-max-msg-size", 0, "Largest replication message accepted on -peer-addr in bytes, such as a state transfer (gRPC default of 4MiB if 0)")
+	peerMaxStreams := flag.Uint("peer-max-streams", 0, "Most concurrent replication calls per connection on -peer-addr (unlimited if 0)")
+	faultsAdmin := flag.String("faults-admin", "", "Address of the fault injection admin endpoint (disabled if empty)")
+	faultsSeed := flag.Uint64("faults-seed", 1, "Seed for probabilistic fault rules")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics (disabled if empty)")
+	zone := flag.String("zone", "", "Failure domain reported to the view service, for zone-aware placement")
+	labels := flag.String("labels", "", "Comma-separated key=value labels reported to the view service")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "Time allowed for handing off on SIGTERM or interrupt (0 exits at once)")
+	logLevel := flag.String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	traceFile := flag.String("trace-file", "", "Append trace spans to this file as JSON lines (disabled if empty)")
+	traceOTLP := flag.String("trace-otlp", "", "Send trace spans to this OTLP/gRPC collector, host:port without TLS (disabled if empty)")
+	traceSample := flag.Float64("trace-sample", 1, "Fraction of new traces recorded (0-1)")
+	tlsCert := flag.String("tls-cert", "", "Certificate to serve TLS with and present to other servers (PEM, TLS disabled if empty)")
+	tlsKey := flag.String("tls-key", "", "Private key of -tls-cert (PEM)")
+	tlsCA := flag.String("tls-ca", "", "CA certificates that sign the certificates of servers and clients (PEM, system roots if empty)")
+	authPolicy := flag.String("auth-policy", "", "JSON file of the principals allowed to call each RPC (every call allowed if empty)")
+	auditLog := flag.String("audit-log", "", "Append denied calls and admin actions to this file as JSON lines (to the log if empty)")
+	tokenFile := flag.String("token-file", "", "File holding a bearer token to authenticate with, instead of the TLS certificate (needs TLS)")
 	flag.Parse()
 
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
 	fmt.Printf("Starting KV Server on %s\n", *serverAddr)
 	fmt.Printf("View Service at %s\n", *vsAddr)
 	pid := os.Getpid()
 	fmt.Printf("PID: %d\n", pid)
 
-	kv := kvserver.StartServer(*serverAddr, *vsAddr)
+	labelMap, err := parseLabels(*labels)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	opts := []kvserver.Option{kvserver.WithZone(*zone), kvserver.WithLabels(labelMap)}
+	if *peerAddr != "" {
+		opts = append(opts, kvserver.WithPeerAddress(*peerAddr))
+		if *peerMaxMsgSize > 0 {
+			opts = append(opts, kvserver.WithPeerServerOptions(grpc.MaxRecvMsgSize(*peerMaxMsgSize)))
+		}
+		if *peerMaxStreams > 0 {
+			opts = append(opts, kvserver.WithPeerServerOptions(grpc.MaxConcurrentStreams(uint32(*peerMaxStreams))))
+		}
+	} else if *peerMaxMsgSize > 0 || *peerMaxStreams > 0 {
+		fmt.Fprintln(os.Stderr, "-peer-max-msg-size and -peer-max-streams need -peer-addr")
+		os.Exit(2)
+	}
+	if *faultsAdmin != "" {
+		inj := faults.New(*faultsSeed)
+		opts = append(opts,
+			kvserver.WithDialOptions(inj.DialOption(*serverAddr)),
+			kvserver.WithServerOptions(inj.ServerOption(*serverAddr)))
+		inj.ListenAndServe(*faultsAdmin)
+	}
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		opts = append(opts, kvserver.WithMetrics(reg))
+		metrics.ListenAndServe(*metricsAddr, reg)
+	}
+
+	tlsCfg := tlsconfig.Config{CertFile: *tlsCert, KeyFile: *tlsKey, CAFile: *tlsCA}
+	if tlsCfg.Enabled() {
+		if tlsCfg.CertFile == "" {
+			fmt.Fprintln(os.Stderr, "TLS needs -tls-cert and -tls-key to serve with")
+			os.Exit(2)
+		}
+		certs, err := tlsconfig.Load(tlsCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, kvserver.WithTLS(certs))
+	}
+
+	if *authPolicy != "" {
+		policy, err := auth.LoadPolicy(*authPolicy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		audit, err := auth.AuditLogger(*auditLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, kvserver.WithAuthorizer(auth.New(policy, audit)))
+	}
+
+	if *tokenFile != "" {
+		tokenOpt, err := auth.TokenDialOption(*tokenFile, tlsCfg.Enabled())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, kvserver.WithDialOptions(tokenOpt))
+	}
+
+	traceCfg := tracing.Config{Service: "kvserver", Instance: *serverAddr, File: *traceFile, OTLPEndpoint: *traceOTLP, SampleRatio: *traceSample}
+	if traceCfg.Enabled() {
+		tp, shutdown, err := tracing.New(context.Background(), traceCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer tracing.Flush(context.Background(), shutdown)
+		opts = append(opts, kvserver.WithTracerProvider(tp))
+	}
+
+	kv, err := kvserver.StartServer(*serverAddr, *vsAddr, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	select {
+	case <-sigChan:
+	case err := <-kv.Failed():
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *shutdownTimeout <= 0 {
+		fmt.Println("\nShutting down KV Server...")
+		kv.Kill()
+		return
+	}
+
+	// Hand off gracefully; a second signal exits at once
+	fmt.Println("\nShutting down KV Server, handing off (signal again to exit at once)...")
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+	if err := kv.Shutdown(ctx); err != nil {
+		fmt.Printf("Graceful shutdown incomplete: %v\n", err)
+	}
+}
 
-	fmt.Println("\nShutting down KV Server...")
-	kv.Kill()
+// parseLabels parses a comma-separated list of key=value pairs
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", part)
+		}
+		labels[k] = v
+	}
+	return labels, nil
 }
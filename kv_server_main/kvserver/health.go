@@ -0,0 +1,36 @@
+package kvserver
+
+import (
+	pb "goDistributedSystemDemo/proto"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Services reported by the health service. The overall status, service "", is
+// the status of the client service, which is what a load balancer checks.
+var (
+	clientService      = pb.KVServer_ServiceDesc.ServiceName
+	replicationService = pb.Replication_ServiceDesc.ServiceName
+)
+
+// updateHealth reports to health checks whether the server is ready: the client
+// service while it is primary of a view it acknowledged, is not transferring its
+// state to a new backup and is not leaving, the replication service while it is backup and has received
+// the state of its primary. Neither is ready while the view service does not
+// answer, as it may have given the role to another server meanwhile. Caller holds
+// kv.mu or kv is not yet shared.
+func (kv *KVServer) updateHealth() {
+	primary := kv.role == "primary" && kv.viewAcked && !kv.syncing && !kv.flushing && !kv.leaving && !kv.vsLost
+	backup := kv.role == "backup" && kv.stateView >= kv.backupSince && !kv.vsLost
+	kv.health.SetServingStatus("", servingStatus(primary))
+	kv.health.SetServingStatus(clientService, servingStatus(primary))
+	kv.health.SetServingStatus(replicationService, servingStatus(backup))
+}
+
+// servingStatus converts ready into a health status
+func servingStatus(ready bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ready {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
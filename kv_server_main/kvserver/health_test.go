@@ -0,0 +1,174 @@
+package kvserver_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/harness"
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/view/viewservice"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	serving    = healthpb.HealthCheckResponse_SERVING
+	notServing = healthpb.HealthCheckResponse_NOT_SERVING
+)
+
+var (
+	clientService      = pb.KVServer_ServiceDesc.ServiceName
+	replicationService = pb.Replication_ServiceDesc.ServiceName
+)
+
+// dial connects to addr without credentials
+func dial(t *testing.T, addr string) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// wantHealth waits until the server at addr reports want for the overall status
+// and the client service, and wantReplication for the replication service
+func wantHealth(t *testing.T, addr string, want healthpb.HealthCheckResponse_ServingStatus, wantReplication healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	health := healthpb.NewHealthClient(dial(t, addr))
+	services := map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":                 want,
+		clientService:      want,
+		replicationService: wantReplication,
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for service, want := range services {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			cancel()
+			if err == nil && resp.Status == want {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s reports %q as %v (%v), want %v", addr, service, resp.GetStatus(), err, want)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// TestHealthFollowsRole checks the serving status as servers become primary and
+// backup, lose the view service and get it back
+func TestHealthFollowsRole(t *testing.T) {
+	c := harness.StartT(t, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	view, err := c.WaitForStable(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary, backup := view.Primary, view.Backup
+	wantHealth(t, primary, serving, notServing)
+	wantHealth(t, backup, notServing, serving)
+
+	// The primary stops hearing from the view service, which gives its role to the backup
+	c.Cut(primary, c.ViewServiceName())
+	wantHealth(t, primary, notServing, notServing)
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary == backup }); err != nil {
+		t.Fatal(err)
+	}
+	wantHealth(t, backup, serving, notServing)
+
+	// Heard from again, the old primary becomes backup of the new one
+	c.Heal()
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary == backup && v.Backup == primary }); err != nil {
+		t.Fatal(err)
+	}
+	wantHealth(t, primary, notServing, serving)
+
+	// A backup that loses the view service stops serving replication too
+	c.Cut(primary, c.ViewServiceName())
+	wantHealth(t, primary, notServing, notServing)
+}
+
+// TestHealthWithoutCredentials checks health on servers whose policy lets no
+// anonymous caller do anything else
+func TestHealthWithoutCredentials(t *testing.T) {
+	policy := &auth.Policy{
+		Clients: map[string][]auth.Grant{"alice": {{Prefix: "a/", Read: true, Write: true}}},
+		Tokens:  map[string]string{"alice": auth.HashToken("alice-token")},
+	}
+	authz := auth.New(policy, slog.New(slog.DiscardHandler))
+	c := harness.StartT(t, 1, harness.WithServerOptions(kvserver.WithAuthorizer(authz)))
+	vs, err := viewservice.StartServer("127.0.0.1:0", viewservice.WithAuthorizer(authz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(vs.Kill)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := c.WaitForAcked(ctx, func(v *pb.View) bool { return v.Primary != "" }); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		addr    string
+		service string
+		denied  func(conn *grpc.ClientConn) error // a call the policy refuses without credentials
+	}{
+		{"KV server", c.Server(0).Name, clientService, func(conn *grpc.ClientConn) error {
+			_, err := pb.NewKVServerClient(conn).Get(ctx, &pb.GetRequest{Key: "a/x"})
+			return err
+		}},
+		{"view service", vs.Addr(), pb.ViewService_ServiceDesc.ServiceName, func(conn *grpc.ClientConn) error {
+			_, err := pb.NewViewServiceClient(conn).ForcePrimary(ctx, &pb.ForcePrimaryRequest{ServerName: "x"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dial(t, tt.addr)
+			if err := tt.denied(conn); status.Code(err) != codes.Unauthenticated {
+				t.Fatalf("call without credentials: %v, want Unauthenticated", err)
+			}
+
+			health := healthpb.NewHealthClient(conn)
+			resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			if err != nil || resp.Status != serving {
+				t.Errorf("Check = %v, %v; want SERVING", resp.GetStatus(), err)
+			}
+			list, err := health.List(ctx, &healthpb.HealthListRequest{})
+			if err != nil || list.Statuses[tt.service].GetStatus() != serving {
+				t.Errorf("List = %v, %v; want %s SERVING", list, err, tt.service)
+			}
+			watch, err := health.Watch(ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if update, err := watch.Recv(); err != nil || update.Status != serving {
+				t.Errorf("Watch = %v, %v; want SERVING", update.GetStatus(), err)
+			}
+
+			// Reflection needs a known caller
+			stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("reflection without credentials: %v, want Unauthenticated", err)
+			}
+		})
+	}
+}
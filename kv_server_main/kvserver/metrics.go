@@ -0,0 +1,100 @@
+package kvserver
+
+import (
+	"goDistributedSystemDemo/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serverMetrics are the events the KV server counts; its state is read at scrape
+// time by stateCollector
+type serverMetrics struct {
+	rpc              *metrics.RPC
+	pingDuration     prometheus.Histogram
+	pingFailures     prometheus.Counter
+	transfers        *prometheus.CounterVec
+	transferDuration prometheus.Histogram
+	transferBytes    prometheus.Counter
+	forwardFailures  prometheus.Counter
+}
+
+// newServerMetrics creates the server's metrics and registers them with reg
+func newServerMetrics(kv *KVServer, reg prometheus.Registerer) *serverMetrics {
+	m := &serverMetrics{
+		rpc: metrics.NewRPC("kv", reg),
+		pingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "kv",
+			Name:      "ping_duration_seconds",
+			Help:      "Round trip time of successful pings to the view service.",
+			Buckets:   metrics.LatencyBuckets,
+		}),
+		pingFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kv",
+			Name:      "ping_failures_total",
+			Help:      "Pings to the view service that failed.",
+		}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kv",
+			Name:      "state_transfers_total",
+			Help:      "Full state transfers to a backup, by result.",
+		}, []string{"result"}),
+		transferDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "kv",
+			Name:      "state_transfer_duration_seconds",
+			Help:      "Time taken by successful state transfers.",
+			Buckets:   metrics.LatencyBuckets,
+		}),
+		transferBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kv",
+			Name:      "state_transfer_bytes_total",
+			Help:      "Size of the keys and values sent in successful state transfers.",
+		}),
+		forwardFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kv",
+			Name:      "forward_failures_total",
+			Help:      "Updates the backup did not confirm; the primary refused them without applying them.",
+		}),
+	}
+	reg.MustRegister(m.pingDuration, m.pingFailures, m.transfers, m.transferDuration, m.transferBytes,
+		m.forwardFailures, stateCollector{kv})
+	return m
+}
+
+var (
+	viewNumberDesc = prometheus.NewDesc("kv_view_number", "Number of the view the server knows.", nil, nil)
+	roleDesc       = prometheus.NewDesc("kv_role", "1 for the server's current role, 0 for the others.", []string{"role"}, nil)
+	keysDesc       = prometheus.NewDesc("kv_keys", "Number of keys held.", nil, nil)
+	dataBytesDesc  = prometheus.NewDesc("kv_data_bytes", "Total size of the keys and values held.", nil, nil)
+	pendingDesc    = prometheus.NewDesc("kv_pending_updates", "Writes queued while a state transfer is in progress.", nil, nil)
+	appliedDesc    = prometheus.NewDesc("kv_applied_seq", "Highest replication sequence applied.", nil, nil)
+)
+
+// stateCollector reports the server's state when metrics are scraped
+type stateCollector struct {
+	kv *KVServer
+}
+
+func (c stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{viewNumberDesc, roleDesc, keysDesc, dataBytesDesc, pendingDesc, appliedDesc} {
+		ch <- d
+	}
+}
+
+func (c stateCollector) Collect(ch chan<- prometheus.Metric) {
+	kv := c.kv
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(viewNumberDesc, prometheus.GaugeValue, float64(kv.currentView.ViewNumber))
+	for _, role := range []string{"primary", "backup", "default"} {
+		value := 0.0
+		if kv.role == role {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(roleDesc, prometheus.GaugeValue, value, role)
+	}
+	ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(len(kv.data)))
+	ch <- prometheus.MustNewConstMetric(dataBytesDesc, prometheus.GaugeValue, float64(kv.dataBytes))
+	ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(len(kv.pendingQueue)))
+	ch <- prometheus.MustNewConstMetric(appliedDesc, prometheus.GaugeValue, float64(kv.appliedSeq))
+}
@@ -0,0 +1,120 @@
+package kvserver
+
+import (
+	"log/slog"
+	"net"
+
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/tlsconfig"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Option configures a KVServer
+type Option func(*KVServer)
+
+// WithDialOptions adds gRPC dial options to every connection the server opens,
+// to the view service as well as to the backup
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(kv *KVServer) {
+		kv.dialOptions = append(kv.dialOptions, opts...)
+	}
+}
+
+// WithServerOptions adds gRPC server options, such as interceptors, to the server
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(kv *KVServer) {
+		kv.serverOptions = append(kv.serverOptions, opts...)
+	}
+}
+
+// WithListener serves clients on lis instead of listening on the server's name,
+// so a caller that picked the port keeps it until the server owns it. The server
+// closes lis when it is killed.
+func WithListener(lis net.Listener) Option {
+	return func(kv *KVServer) {
+		kv.listener = lis
+	}
+}
+
+// WithPeerAddress serves replication from the primary on addr instead of on the
+// server's own address, so it can be firewalled off from clients. Port 0 picks a
+// free port. The view service passes the address on to the primary.
+func WithPeerAddress(addr string) Option {
+	return func(kv *KVServer) {
+		kv.peerAddress = addr
+	}
+}
+
+// WithPeerServerOptions adds gRPC server options, such as message size or
+// concurrency limits, to the replication server only. They apply only together
+// with WithPeerAddress.
+func WithPeerServerOptions(opts ...grpc.ServerOption) Option {
+	return func(kv *KVServer) {
+		kv.peerOptions = append(kv.peerOptions, opts...)
+	}
+}
+
+// WithClock makes the server time its pings and RPCs on c instead of the wall clock
+func WithClock(c clock.Clock) Option {
+	return func(kv *KVServer) {
+		kv.clock = c
+	}
+}
+
+// WithZone reports zone to the view service as the server's failure domain, so a
+// zone-aware policy can keep the primary and backup apart
+func WithZone(zone string) Option {
+	return func(kv *KVServer) {
+		kv.zone = zone
+	}
+}
+
+// WithLabels reports operator-defined labels to the view service
+func WithLabels(labels map[string]string) Option {
+	return func(kv *KVServer) {
+		kv.labels = labels
+	}
+}
+
+// WithMetrics registers the server's metrics with reg, to be served on /metrics
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(kv *KVServer) {
+		kv.registerer = reg
+	}
+}
+
+// WithLogger makes the server log to l instead of the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(kv *KVServer) {
+		kv.logger = l
+	}
+}
+
+// WithTracerProvider makes the server record spans with tp instead of the global
+// tracer provider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(kv *KVServer) {
+		kv.traces = tp
+	}
+}
+
+// WithTLS makes the server serve and dial over TLS with the certificates in certs.
+// Only callers presenting a certificate signed by the CA may replicate to it, and
+// it presents its own to the view service and the backup.
+func WithTLS(certs *tlsconfig.Certs) Option {
+	return func(kv *KVServer) {
+		kv.tls = certs
+	}
+}
+
+// WithAuthorizer makes the server refuse calls a that does not allow. It replaces
+// the client certificate check of WithTLS.
+func WithAuthorizer(a *auth.Authorizer) Option {
+	return func(kv *KVServer) {
+		kv.authz = a
+	}
+}
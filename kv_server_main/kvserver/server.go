@@ -2,72 +2,182 @@ package kvserver
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/faults"
+	"goDistributedSystemDemo/logging"
 	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 const (
 	PingInterval = 500 * time.Millisecond // Ping viewservice every 0.5 seconds
+
+	// Without a ping reply for this long the view service may have declared the
+	// server dead and replaced it, so health checks report it not serving
+	viewServiceTimeout = 3 * PingInterval
 )
 
+// Version is the build version reported to the view service. Set it when building:
+// -ldflags "-X goDistributedSystemDemo/kv_server_main/kvserver.Version=v1.2.0"
+var Version = "dev"
+
 // KVServer is a key-value server that can act as Primary or Backup
 type KVServer struct {
 	pb.UnimplementedKVServerServer
-	mu         sync.Mutex
-	listener   net.Listener
-	grpcServer *grpc.Server
-	dead       bool
-	me         string // my server name/address
-
-	vsAddress string // view service address
-	vsClient  pb.ViewServiceClient
-	vsConn    *grpc.ClientConn
-
-	currentView  *pb.View
-	data         map[string]string
-	role         string           // "primary", "backup", or "default"
-	lastBackup   string           // last known backup address
-	syncing      bool             // true when state transfer is in progress
-	pendingQueue []*pb.PutRequest // queue for puts during state transfer
-}
-
-// StartServer creates and starts a new KV server
-func StartServer(serverName string, vsAddress string) *KVServer {
+	pb.UnimplementedReplicationServer
+	mu          sync.Mutex
+	listener    net.Listener // serves clients, and replication without a peer address
+	grpcServer  *grpc.Server
+	peerAddress string       // serves replication here if set, else on the client address
+	peerLis     net.Listener // listener of peerAddress, nil without one
+	peerServer  *grpc.Server // serves replication on peerLis, nil without one
+	dead        atomic.Bool
+	failed      chan error        // receives the error that stopped a gRPC server
+	me          string            // my server name/address
+	incarnation uint64            // random ID of this process start, sent in every ping
+	zone        string            // failure domain reported to the view service
+	labels      map[string]string // operator-defined labels reported to the view service
+
+	vsAddress   string // view service address
+	vsClient    pb.ViewServiceClient
+	vsConn      *grpc.ClientConn
+	dialOptions []grpc.DialOption // extra options for outgoing connections
+
+	serverOptions []grpc.ServerOption   // extra options for the gRPC server
+	peerOptions   []grpc.ServerOption   // extra options for the replication server on peerAddress
+	clock         clock.Clock           // source of time for pings and RPC timeouts
+	registerer    prometheus.Registerer // receives the server's metrics
+	metrics       *serverMetrics
+	logger        *slog.Logger
+	traces        trace.TracerProvider // receives spans for RPCs, replication and state transfer
+	tracer        trace.Tracer
+	tls           *tlsconfig.Certs // serves and dials over TLS if not nil
+	authz         *auth.Authorizer // checks every call if not nil
+	health        *health.Server   // tells load balancers whether the server is ready
+
+	currentView   *pb.View
+	viewAcked     bool      // the view service got a ping naming currentView, so as primary this server may serve
+	lastPingReply time.Time // when the view service last answered a ping
+	vsLost        bool      // no ping reply for viewServiceTimeout
+	data          map[string]string
+	dataBytes     uint64           // total size of the keys and values in data
+	appliedSeq    uint64           // highest replication sequence applied to data
+	role          string           // "primary", "backup", or "default"
+	lastBackup    string           // backup that accepted the last state transfer
+	lastBackupInc uint64           // incarnation of lastBackup that received the state
+	syncedBackup  string           // backup whose state transfer completed
+	syncedInc     uint64           // incarnation of syncedBackup
+	syncing       bool             // true when state transfer is in progress
+	backupSince   uint64           // view in which this server last became backup
+	stateView     uint64           // view of the last state transfer received
+	pendingQueue  []*pendingUpdate // queue for updates during state transfer
+	flushing      bool             // true while updates queued during state transfer are applied
+	leaving       bool             // true once Shutdown started; client writes are refused
+	left          bool             // true once the view service accepted Leave; every client RPC is refused
+	writes        sync.WaitGroup   // client writes in progress
+	replicate     sync.Mutex       // held by a write while it is applied, and by a state transfer while it copies the data
+}
+
+// pendingUpdate is a client write queued during a state transfer. Its writer
+// waits for the reply code on done, sent once the update is replicated or the
+// transfer failed.
+type pendingUpdate struct {
+	req  *pb.ForwardUpdateRequest
+	done chan string
+}
+
+// StartServer creates and starts a new KV server. If serverName has port 0 an
+// ephemeral port is chosen and the server is named after the address it got.
+// With WithListener the server serves on that listener instead of listening on
+// serverName. It fails if an address cannot be listened on.
+func StartServer(serverName string, vsAddress string, opts ...Option) (*KVServer, error) {
 	kv := &KVServer{
 		me:           serverName,
+		incarnation:  newIncarnation(),
 		vsAddress:    vsAddress,
+		failed:       make(chan error, 2),
 		data:         make(map[string]string),
 		role:         "default",
 		lastBackup:   "",
 		syncing:      false,
-		pendingQueue: make([]*pb.PutRequest, 0),
+		pendingQueue: make([]*pendingUpdate, 0),
 		currentView:  &pb.View{},
+		clock:        clock.Real,
+		logger:       slog.Default(),
+		traces:       otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(kv)
 	}
+	kv.tracer = kv.traces.Tracer("goDistributedSystemDemo/kvserver")
+	if kv.registerer == nil {
+		kv.registerer = prometheus.NewRegistry()
+	}
+	kv.metrics = newServerMetrics(kv, kv.registerer)
 
 	// Start listening
-	lis, err := net.Listen("tcp", serverName)
-	if err != nil {
-		log.Fatalf("KVServer failed to listen: %v", err)
+	lis := kv.listener
+	if lis == nil {
+		var err error
+		lis, err = net.Listen("tcp", serverName)
+		if err != nil {
+			return nil, fmt.Errorf("KVServer failed to listen on %s: %w", serverName, err)
+		}
+		kv.listener = lis
 	}
-	kv.listener = lis
+	if _, port, _ := net.SplitHostPort(serverName); port == "0" || kv.me == "" {
+		kv.me = lis.Addr().String()
+	}
+	kv.logger = kv.logger.With("server", kv.me)
+
+	kv.health = health.NewServer()
+	kv.updateHealth()
 
-	// Create gRPC server
-	kv.grpcServer = grpc.NewServer()
+	// Create gRPC servers; replication gets one of its own if it has its own address
+	kv.grpcServer = kv.newGRPCServer(kv.serverOptions)
 	pb.RegisterKVServerServer(kv.grpcServer, kv)
+	if kv.peerAddress != "" {
+		peerLis, err := net.Listen("tcp", kv.peerAddress)
+		if err != nil {
+			lis.Close()
+			return nil, fmt.Errorf("KVServer failed to listen for replication on %s: %w", kv.peerAddress, err)
+		}
+		kv.peerLis = peerLis
+		if _, port, _ := net.SplitHostPort(kv.peerAddress); port == "0" {
+			kv.peerAddress = peerLis.Addr().String()
+		}
+		kv.peerServer = kv.newGRPCServer(slices.Concat(kv.serverOptions, kv.peerOptions))
+		pb.RegisterReplicationServer(kv.peerServer, kv)
+		go kv.serve(kv.peerServer, peerLis)
+	} else {
+		pb.RegisterReplicationServer(kv.grpcServer, kv)
+	}
 
 	// Start gRPC server in background
-	go func() {
-		if err := kv.grpcServer.Serve(lis); err != nil && !kv.dead {
-			log.Fatalf("KVServer failed to serve: %v", err)
-		}
-	}()
+	go kv.serve(kv.grpcServer, lis)
 
 	// Connect to view service
 	go kv.connectToViewService()
@@ -75,177 +185,415 @@ func StartServer(serverName string, vsAddress string) *KVServer {
 	// Start pinging view service
 	go kv.pingLoop()
 
-	log.Printf("KVServer %s started\n", serverName)
-	log.Printf("KVServer Configuration: PingInterval=%v\n", PingInterval)
-	return kv
+	kv.logger.Info("KVServer started", "version", Version, "incarnation", kv.incarnation,
+		"peer_addr", kv.peerAddress, "ping_interval", PingInterval)
+	return kv, nil
+}
+
+// newGRPCServer creates a gRPC server with opts followed by the server's own
+// tracing, metrics, logging, TLS and authorization, serving health checks and
+// reflection
+func (kv *KVServer) newGRPCServer(opts []grpc.ServerOption) *grpc.Server {
+	opts = append(slices.Clip(opts), tracing.ServerOption(kv.traces),
+		kv.metrics.rpc.ServerOption(), logging.ServerOption(kv.logger))
+	if kv.tls != nil {
+		opts = append(opts, grpc.Creds(kv.tls.ServerCredentials()))
+	}
+	switch {
+	case kv.authz != nil:
+		opts = append(opts, kv.authz.ServerOptions()...)
+	case kv.tls != nil:
+		// Only another server may replicate to this one
+		opts = append(opts,
+			tlsconfig.RequireServerCert(pb.Replication_ForwardUpdate_FullMethodName, pb.Replication_SyncState_FullMethodName,
+				pb.Replication_ConfirmView_FullMethodName))
+	}
+	srv := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(srv, kv.health)
+	reflection.Register(srv)
+	return srv
+}
+
+// serve runs srv on lis until the server is killed. If srv stops on its own the
+// server is killed, so the view service replaces it, and the error is reported
+// on Failed.
+func (kv *KVServer) serve(srv *grpc.Server, lis net.Listener) {
+	if err := srv.Serve(lis); err != nil && !kv.dead.Load() {
+		kv.logger.Error("KVServer failed to serve", "addr", lis.Addr().String(), "err", err)
+		kv.failed <- fmt.Errorf("KVServer failed to serve on %s: %w", lis.Addr(), err)
+		kv.Kill()
+	}
+}
+
+// Failed receives the error if the server stopped serving without being killed
+func (kv *KVServer) Failed() <-chan error {
+	return kv.failed
+}
+
+// newIncarnation picks a random non-zero incarnation ID
+func newIncarnation() uint64 {
+	for {
+		if id := rand.Uint64(); id != 0 {
+			return id
+		}
+	}
+}
+
+// Addr returns the address the server listens on, which is also its name in the view
+func (kv *KVServer) Addr() string {
+	return kv.me
+}
+
+// dial opens a connection to another server using opts followed by the
+// configured dial options
+func (kv *KVServer) dial(address string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if kv.tls != nil {
+		creds = kv.tls.ClientCredentials()
+	}
+	opts = slices.Concat([]grpc.DialOption{grpc.WithTransportCredentials(creds), tracing.DialOption(kv.traces)}, opts, kv.dialOptions)
+	return grpc.Dial(address, opts...)
+}
+
+// dialBackup opens a connection to the backup named backup at addr, its
+// replication address. Fault rules for the backup's name apply to it.
+func (kv *KVServer) dialBackup(backup string, addr string) (*grpc.ClientConn, error) {
+	return kv.dial(addr, faults.Target(backup))
 }
 
 // connectToViewService establishes connection to view service
 func (kv *KVServer) connectToViewService() {
-	for !kv.dead {
-		conn, err := grpc.Dial(kv.vsAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	for !kv.dead.Load() {
+		conn, err := kv.dial(kv.vsAddress)
 		if err == nil {
 			kv.mu.Lock()
 			kv.vsConn = conn
 			kv.vsClient = pb.NewViewServiceClient(conn)
 			kv.mu.Unlock()
-			log.Printf("Connected to view service at %s\n", kv.vsAddress)
+			kv.logger.Info("Connected to view service", "addr", kv.vsAddress)
 			return
 		}
-		time.Sleep(1 * time.Second)
+		clock.Sleep(context.Background(), kv.clock, 1*time.Second)
 	}
 }
 
 // pingLoop periodically pings the view service
 func (kv *KVServer) pingLoop() {
-	ticker := time.NewTicker(PingInterval)
+	ticker := kv.clock.NewTicker(PingInterval)
 	defer ticker.Stop()
 
-	for !kv.dead {
-		<-ticker.C
-		kv.ping()
+	for !kv.dead.Load() {
+		<-ticker.C()
+		// Acknowledge a new view at once, a new primary serves no client until then
+		if kv.ping() {
+			kv.ping()
+		}
 	}
 }
 
-// ping sends a ping to the view service and updates the view
-func (kv *KVServer) ping() {
+// ping sends a ping to the view service and updates the view. It reports whether
+// the view changed.
+func (kv *KVServer) ping() bool {
 	kv.mu.Lock()
 	if kv.vsClient == nil {
 		kv.mu.Unlock()
-		return
+		return false
 	}
 
 	req := &pb.PingRequest{
-		ServerName: kv.me,
-		ViewNumber: kv.currentView.ViewNumber,
+		ServerName:   kv.me,
+		ViewNumber:   kv.currentView.ViewNumber,
+		Incarnation:  kv.incarnation,
+		Zone:         kv.zone,
+		BackupSynced: kv.backupSynced(),
+		Metadata:     kv.metadata(),
 	}
 	client := kv.vsClient
 	kv.mu.Unlock()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := clock.WithTimeout(context.Background(), kv.clock, 2*time.Second)
 	defer cancel()
 
+	start := kv.clock.Now()
 	resp, err := client.Ping(ctx, req)
 	if err != nil {
-		log.Printf("Ping error: %v\n", err)
-		return
+		kv.metrics.pingFailures.Inc()
+		kv.logger.Warn("Ping failed", "err", err)
+		kv.mu.Lock()
+		kv.vsLost = clock.Since(kv.clock, kv.lastPingReply) >= viewServiceTimeout
+		kv.updateHealth()
+		kv.mu.Unlock()
+		return false
 	}
+	kv.metrics.pingDuration.Observe(clock.Since(kv.clock, start).Seconds())
 
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
+	kv.lastPingReply = kv.clock.Now()
+	kv.vsLost = false
 
 	oldView := kv.currentView
 	kv.currentView = resp.View
 
+	// The view service takes a ping naming its current view from the primary as
+	// the acknowledgement of that view. Until it has one it may still fall back to
+	// the previous primary, which lacks any write this server would serve.
+	kv.viewAcked = req.ViewNumber == resp.View.ViewNumber
+
 	// Check if view has changed
-	if oldView.ViewNumber != kv.currentView.ViewNumber {
+	changed := oldView.ViewNumber != kv.currentView.ViewNumber
+	if changed {
 		kv.handleViewChange(oldView)
 	}
+	// Also retries a state transfer that failed
+	kv.syncBackup()
+	kv.updateHealth()
+	return changed
 }
 
 // handleViewChange handles changes in the view
 func (kv *KVServer) handleViewChange(oldView *pb.View) {
-	log.Printf("View changed from %d to %d (Primary: %s, Backup: %s)\n",
-		oldView.ViewNumber, kv.currentView.ViewNumber,
-		kv.currentView.Primary, kv.currentView.Backup)
+	kv.logger.Info("View changed", "from", oldView.ViewNumber, "view", kv.currentView.ViewNumber,
+		"primary", kv.currentView.Primary, "backup", kv.currentView.Backup)
 
 	oldRole := kv.role
 
-	// Determine new role
-	if kv.currentView.Primary == kv.me {
+	// Determine new role. A view naming this address with another incarnation
+	// refers to a previous run of this server, whose data is gone.
+	if kv.currentView.Primary == kv.me && kv.currentView.PrimaryIncarnation == kv.incarnation {
 		kv.role = "primary"
-	} else if kv.currentView.Backup == kv.me {
+	} else if kv.currentView.Backup == kv.me && kv.currentView.BackupIncarnation == kv.incarnation {
 		kv.role = "backup"
 	} else {
 		kv.role = "default"
 	}
 
 	if oldRole != kv.role {
-		log.Printf("Role changed from %s to %s\n", oldRole, kv.role)
-	}
-
-	// If I became primary or if backup changed, handle state transfer
-	if kv.role == "primary" {
-		// Check if backup changed
-		if kv.currentView.Backup != "" && kv.currentView.Backup != kv.lastBackup {
-			log.Printf("New backup detected: %s, initiating state transfer\n", kv.currentView.Backup)
-			kv.lastBackup = kv.currentView.Backup
-			go kv.transferState(kv.currentView.Backup, kv.currentView.ViewNumber)
-		} else if kv.currentView.Backup == "" {
-			kv.lastBackup = ""
+		kv.logger.Info("Role changed", "from", oldRole, "role", kv.role)
+		if kv.role == "backup" {
+			kv.backupSince = kv.currentView.ViewNumber
 		}
 	}
+
 }
 
-// transferState transfers the entire state to the new backup
-func (kv *KVServer) transferState(backup string, viewNumber uint64) {
-	kv.mu.Lock()
-	kv.syncing = true
-	dataCopy := make(map[string]string)
-	for k, v := range kv.data {
-		dataCopy[k] = v
+// syncBackup starts a state transfer if this server is primary and its backup
+// changed, restarted under the same address or did not accept the last transfer.
+// Caller holds kv.mu.
+func (kv *KVServer) syncBackup() {
+	if kv.role != "primary" || kv.syncing {
+		return
 	}
-	kv.mu.Unlock()
+	v := kv.currentView
+	if v.Backup == "" {
+		kv.lastBackup = ""
+		kv.lastBackupInc = 0
+		return
+	}
+	if kv.backupCurrent() {
+		return
+	}
+	kv.logger.Info("Backup lacks the state, initiating state transfer", "backup", v.Backup, "incarnation", v.BackupIncarnation)
+	kv.syncing = true
+	kv.updateHealth()
+	go kv.transferState(v.Backup, peerAddress(v), v.BackupIncarnation, v.ViewNumber)
+}
 
-	log.Printf("Transferring state to backup %s (view %d)\n", backup, viewNumber)
+// backupCurrent reports whether the backup of the current view accepted a state
+// transfer, so the updates forwarded to it bring it up to date. Caller holds kv.mu.
+func (kv *KVServer) backupCurrent() bool {
+	v := kv.currentView
+	return v.Backup == kv.lastBackup && v.BackupIncarnation == kv.lastBackupInc
+}
 
-	// Connect to backup
-	conn, err := grpc.Dial(backup, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Printf("Failed to connect to backup %s: %v\n", backup, err)
-		kv.mu.Lock()
-		kv.syncing = false
-		kv.mu.Unlock()
-		return
+// metadata describes this server for the view service. Caller holds kv.mu.
+func (kv *KVServer) metadata() *pb.ServerMetadata {
+	return &pb.ServerMetadata{
+		Version:     Version,
+		Labels:      kv.labels,
+		AppliedSeq:  kv.appliedSeq,
+		Keys:        uint64(len(kv.data)),
+		DataBytes:   kv.dataBytes,
+		Willing:     !kv.leaving,
+		PeerAddress: kv.peerAddress,
 	}
-	defer conn.Close()
+}
 
-	client := pb.NewKVServerClient(conn)
+// backupSynced reports whether this server is primary and its backup holds the
+// full state, so the view service may swap their roles. Caller holds kv.mu.
+func (kv *KVServer) backupSynced() bool {
+	v := kv.currentView
+	return kv.role == "primary" && !kv.syncing && !kv.flushing && v.Backup != "" &&
+		v.Backup == kv.syncedBackup && v.BackupIncarnation == kv.syncedInc
+}
 
-	// Send state
-	req := &pb.SyncStateRequest{
-		Data:       dataCopy,
-		ViewNumber: viewNumber,
+// peerAddress returns where the backup of view accepts replication
+func peerAddress(view *pb.View) string {
+	if view.BackupPeerAddress != "" {
+		return view.BackupPeerAddress
 	}
+	return view.Backup
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// transferState transfers the entire state to the new backup, reached at addr.
+// The caller has set kv.syncing, so client writes queue until it completes.
+func (kv *KVServer) transferState(backup string, addr string, backupInc uint64, viewNumber uint64) {
+	ctx, span := kv.tracer.Start(context.Background(), "kvserver.TransferState", trace.WithAttributes(
+		attribute.String("backup", backup), attribute.Int64("view", int64(viewNumber))))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	// Writes applied before the copy are in it; those not yet begun are queued
+	kv.replicate.Lock()
+	kv.lock(ctx)
+	dataCopy := make(map[string]string)
+	for k, v := range kv.data {
+		dataCopy[k] = v
+	}
+	seq := kv.appliedSeq
+	kv.mu.Unlock()
+	kv.replicate.Unlock()
+
+	kv.logger.Info("Transferring state to backup", "backup", backup, "view", viewNumber, "keys", len(dataCopy))
+	span.SetAttributes(attribute.Int("keys", len(dataCopy)), attribute.Int64("seq", int64(seq)))
+
+	sendCtx, cancel := clock.WithTimeout(ctx, kv.clock, 10*time.Second)
 	defer cancel()
 
-	_, err = client.SyncState(ctx, req)
-	if err != nil {
-		log.Printf("SyncState RPC failed: %v\n", err)
+	if err = kv.sendState(sendCtx, backup, addr, dataCopy, seq, viewNumber); err != nil {
+		// The queued writes were never replicated; their clients retry, and the
+		// transfer is retried on the next ping
+		kv.logger.Warn("State transfer failed", "backup", backup, "err", err)
 		kv.mu.Lock()
 		kv.syncing = false
+		pending := kv.pendingQueue
+		kv.pendingQueue = make([]*pendingUpdate, 0)
+		kv.updateHealth()
 		kv.mu.Unlock()
+		for _, p := range pending {
+			p.done <- "ErrNotPrimary"
+		}
 		return
 	}
 
-	log.Printf("State transfer completed successfully\n")
+	kv.logger.Info("State transfer completed", "backup", backup)
 
 	kv.mu.Lock()
 	kv.syncing = false
+	kv.lastBackup = backup
+	kv.lastBackupInc = backupInc
 
-	// Process pending puts
+	// Process pending updates
 	if len(kv.pendingQueue) > 0 {
-		log.Printf("Processing %d pending puts\n", len(kv.pendingQueue))
+		kv.logger.Info("Processing pending updates", "count", len(kv.pendingQueue))
+		span.SetAttributes(attribute.Int("pending", len(kv.pendingQueue)))
 		pending := kv.pendingQueue
-		kv.pendingQueue = make([]*pb.PutRequest, 0)
+		kv.pendingQueue = make([]*pendingUpdate, 0)
+		kv.flushing = true
 		kv.mu.Unlock()
 
-		for _, putReq := range pending {
-			kv.Put(context.Background(), putReq)
+		for _, p := range pending {
+			p.done <- kv.update(ctx, p.req)
 		}
-	} else {
-		kv.mu.Unlock()
+
+		kv.mu.Lock()
+		kv.flushing = false
+	}
+	kv.syncedBackup = backup
+	kv.syncedInc = backupInc
+	kv.updateHealth()
+	kv.mu.Unlock()
+}
+
+// sendState overwrites the data of the backup named backup, reached at addr, with
+// data, which includes every update up to replication sequence seq
+func (kv *KVServer) sendState(ctx context.Context, backup string, addr string, data map[string]string, seq uint64, viewNumber uint64) (err error) {
+	start := kv.clock.Now()
+	defer func() {
+		if err != nil {
+			kv.metrics.transfers.WithLabelValues("failed").Inc()
+			return
+		}
+		size := 0
+		for k, v := range data {
+			size += len(k) + len(v)
+		}
+		kv.metrics.transfers.WithLabelValues("ok").Inc()
+		kv.metrics.transferDuration.Observe(clock.Since(kv.clock, start).Seconds())
+		kv.metrics.transferBytes.Add(float64(size))
+	}()
+
+	conn, err := kv.dialBackup(backup, addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to backup %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewReplicationClient(conn)
+	req := &pb.SyncStateRequest{
+		Data:               data,
+		ViewNumber:         viewNumber,
+		Seq:                seq,
+		Primary:            kv.me,
+		PrimaryIncarnation: kv.incarnation,
+	}
+	resp, err := client.SyncState(ctx, req)
+	if err != nil {
+		return fmt.Errorf("SyncState RPC failed: %w", err)
+	}
+	if !resp.Ok {
+		return fmt.Errorf("backup %s refused the state, it is not backup of view %d yet", addr, viewNumber)
+	}
+	return nil
+}
+
+// confirmView asks the backup of view, if it has one, whether the view is still
+// current. A primary the view service replaced cannot tell until its next ping,
+// but its backup, promoted or gone, no longer confirms.
+func (kv *KVServer) confirmView(ctx context.Context, view *pb.View) error {
+	if view.Backup == "" {
+		return nil
+	}
+	ctx, span := kv.tracer.Start(ctx, "kvserver.ConfirmView", trace.WithAttributes(
+		attribute.String("backup", view.Backup), attribute.Int64("view", int64(view.ViewNumber))))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := kv.dialBackup(view.Backup, peerAddress(view))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	confirmCtx, cancel := clock.WithTimeout(ctx, kv.clock, 2*time.Second)
+	defer cancel()
+	resp, err := pb.NewReplicationClient(conn).ConfirmView(confirmCtx, &pb.ConfirmViewRequest{ViewNumber: view.ViewNumber})
+	if err != nil {
+		return err
 	}
+	if !resp.Ok {
+		err = fmt.Errorf("backup %s did not confirm view %d", view.Backup, view.ViewNumber)
+	}
+	return err
 }
 
 // Get RPC handler
 func (kv *KVServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
-	kv.mu.Lock()
-	defer kv.mu.Unlock()
+	kv.lock(ctx)
+	if kv.role != "primary" || !kv.viewAcked || kv.left {
+		kv.mu.Unlock()
+		return &pb.GetResponse{
+			Value: "",
+			Ok:    false,
+			Error: "ErrNotPrimary",
+		}, nil
+	}
+	value, ok := kv.data[req.Key]
+	view := kv.currentView
+	kv.mu.Unlock()
 
-	if kv.role != "primary" {
+	if err := kv.confirmView(ctx, view); err != nil {
+		logging.For(ctx, kv.logger).Warn("Refusing read, view not confirmed", "err", err)
 		return &pb.GetResponse{
 			Value: "",
 			Ok:    false,
@@ -253,7 +601,6 @@ func (kv *KVServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetRespons
 		}, nil
 	}
 
-	value, ok := kv.data[req.Key]
 	if ok {
 		return &pb.GetResponse{
 			Value: value,
@@ -271,80 +618,254 @@ func (kv *KVServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetRespons
 
 // Put RPC handler
 func (kv *KVServer) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
-	kv.mu.Lock()
+	errCode := kv.write(ctx, &pb.ForwardUpdateRequest{
+		Key:       req.Key,
+		Value:     req.Value,
+		RequestId: logging.RequestID(ctx),
+	})
+	return &pb.PutResponse{
+		Ok:    errCode == "",
+		Error: errCode,
+	}, nil
+}
+
+// Delete RPC handler
+func (kv *KVServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	errCode := kv.write(ctx, &pb.ForwardUpdateRequest{
+		Key:       req.Key,
+		Delete:    true,
+		RequestId: logging.RequestID(ctx),
+	})
+	return &pb.DeleteResponse{
+		Ok:    errCode == "",
+		Error: errCode,
+	}, nil
+}
 
-	if kv.role != "primary" {
+// Scan RPC handler
+func (kv *KVServer) Scan(ctx context.Context, req *pb.ScanRequest) (*pb.ScanResponse, error) {
+	kv.lock(ctx)
+	if kv.role != "primary" || !kv.viewAcked || kv.left {
 		kv.mu.Unlock()
-		return &pb.PutResponse{
+		return &pb.ScanResponse{
 			Ok:    false,
 			Error: "ErrNotPrimary",
 		}, nil
 	}
 
-	// If state transfer is in progress, queue the request
+	keys := make([]string, 0)
+	for k := range kv.data {
+		if strings.HasPrefix(k, req.Prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if req.Limit > 0 && len(keys) > int(req.Limit) {
+		keys = keys[:req.Limit]
+	}
+
+	entries := make([]*pb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, &pb.KeyValue{Key: k, Value: kv.data[k]})
+	}
+	view := kv.currentView
+	kv.mu.Unlock()
+
+	if err := kv.confirmView(ctx, view); err != nil {
+		logging.For(ctx, kv.logger).Warn("Refusing read, view not confirmed", "err", err)
+		return &pb.ScanResponse{
+			Ok:    false,
+			Error: "ErrNotPrimary",
+		}, nil
+	}
+	return &pb.ScanResponse{
+		Entries: entries,
+		Ok:      true,
+		Error:   "",
+	}, nil
+}
+
+// write serves a client write. Writes are refused once the server is leaving, so
+// Shutdown can wait for the ones in progress and hand over a complete state.
+func (kv *KVServer) write(ctx context.Context, req *pb.ForwardUpdateRequest) string {
+	kv.mu.Lock()
+	if kv.leaving {
+		kv.mu.Unlock()
+		return "ErrNotPrimary"
+	}
+	kv.writes.Add(1)
+	kv.mu.Unlock()
+	defer kv.writes.Done()
+
+	return kv.update(ctx, req)
+}
+
+// update applies a client write on the primary: it is forwarded to the backup and
+// then applied locally. It returns the error code for the reply, "" on success.
+func (kv *KVServer) update(ctx context.Context, req *pb.ForwardUpdateRequest) string {
+	ctx, span := kv.tracer.Start(ctx, "kvserver.Replicate", trace.WithAttributes(
+		attribute.String("key", req.Key), tracing.RequestID(req.RequestId)))
+	defer span.End()
+
+	// Writes are applied one at a time, so a state transfer copies none half done
+	kv.replicate.Lock()
+	kv.lock(ctx)
+
+	if kv.role != "primary" || !kv.viewAcked {
+		kv.mu.Unlock()
+		kv.replicate.Unlock()
+		span.SetAttributes(attribute.String("error", "ErrNotPrimary"))
+		return "ErrNotPrimary"
+	}
+
+	// If state transfer is in progress, queue the request and wait for its outcome
 	if kv.syncing {
-		kv.pendingQueue = append(kv.pendingQueue, req)
+		p := &pendingUpdate{req: req, done: make(chan string, 1)}
+		kv.pendingQueue = append(kv.pendingQueue, p)
 		kv.mu.Unlock()
-		return &pb.PutResponse{
-			Ok:    true,
-			Error: "",
-		}, nil
+		kv.replicate.Unlock()
+		span.AddEvent("queued until the state transfer completes")
+		return <-p.done
 	}
 
+	// A backup that has not accepted a state transfer would lose the update
+	if kv.currentView.Backup != "" && !kv.backupCurrent() {
+		kv.mu.Unlock()
+		kv.replicate.Unlock()
+		span.SetAttributes(attribute.String("error", "ErrNotPrimary"))
+		return "ErrNotPrimary"
+	}
+	defer kv.replicate.Unlock()
+
+	// Number the update so servers can tell how up to date their data is. Writes
+	// are applied one at a time, so the backup sees the numbers without gaps.
+	req.Seq = kv.appliedSeq + 1
+	req.ViewNumber = kv.currentView.ViewNumber
+
 	backup := kv.currentView.Backup
+	addr := peerAddress(kv.currentView)
 	kv.mu.Unlock()
+	span.SetAttributes(attribute.Int64("seq", int64(req.Seq)), attribute.String("backup", backup))
+
+	logger := kv.logger.With("request_id", req.RequestId, "seq", req.Seq)
+	logger.Debug("Applying update", "key", req.Key, "delete", req.Delete, "backup", backup)
 
-	// If there's a backup, forward the update
+	// The write is acknowledged only once the backup applied it, so whichever of
+	// the two serves next holds every write a client saw succeed
 	if backup != "" {
-		conn, err := grpc.Dial(backup, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		if err != nil {
-			log.Printf("Failed to connect to backup %s: %v\n", backup, err)
-			// Continue anyway, update local state
-		} else {
-			defer conn.Close()
-			client := pb.NewKVServerClient(conn)
-
-			forwardReq := &pb.ForwardUpdateRequest{
-				Key:   req.Key,
-				Value: req.Value,
-			}
-
-			forwardCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-
-			_, err = client.ForwardUpdate(forwardCtx, forwardReq)
-			if err != nil {
-				log.Printf("ForwardUpdate RPC failed: %v\n", err)
-				// Continue anyway, update local state
-			}
+		if err := kv.forward(ctx, backup, addr, req); err != nil {
+			kv.metrics.forwardFailures.Inc()
+			logger.Warn("Backup did not apply the update, refusing it", "backup", backup, "err", err)
+			span.SetAttributes(attribute.String("error", "ErrNotPrimary"))
+			return "ErrNotPrimary"
 		}
 	}
 
 	// Update local state
-	kv.mu.Lock()
-	kv.data[req.Key] = req.Value
+	kv.lock(ctx)
+	kv.apply(req)
 	kv.mu.Unlock()
 
-	return &pb.PutResponse{
-		Ok:    true,
-		Error: "",
-	}, nil
+	return ""
+}
+
+// forward sends an update to the backup named backup at addr and returns nil
+// once the backup confirmed applying exactly that update. If the backup may have
+// applied it without confirming, or asks for the full state, the primary
+// transfers its state again before it forwards anything else.
+func (kv *KVServer) forward(ctx context.Context, backup string, addr string, req *pb.ForwardUpdateRequest) error {
+	_, dialSpan := kv.tracer.Start(ctx, "kvserver.DialBackup")
+	conn, err := kv.dialBackup(backup, addr)
+	tracing.End(dialSpan, err)
+	if err != nil {
+		return fmt.Errorf("failed to connect to backup %s: %w", addr, err)
+	}
+	defer conn.Close()
+	client := pb.NewReplicationClient(conn)
+
+	// The caller giving up must not stop the backup from getting the update
+	forwardCtx, cancel := clock.WithTimeout(context.WithoutCancel(ctx), kv.clock, 2*time.Second)
+	defer cancel()
+
+	resp, err := client.ForwardUpdate(forwardCtx, req)
+	switch {
+	case err != nil:
+		kv.resyncBackup()
+		return fmt.Errorf("ForwardUpdate RPC failed: %w", err)
+	case resp.Resync:
+		kv.resyncBackup()
+		return fmt.Errorf("backup is at sequence %d and needs a state transfer", resp.Seq)
+	case !resp.Ok:
+		return fmt.Errorf("backup refused the update, it is not backup of view %d yet", req.ViewNumber)
+	case resp.Seq != req.Seq:
+		kv.resyncBackup()
+		return fmt.Errorf("backup confirmed sequence %d instead of %d", resp.Seq, req.Seq)
+	}
+	return nil
+}
+
+// resyncBackup forgets that the backup holds the state and starts a new transfer
+func (kv *KVServer) resyncBackup() {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.lastBackup = ""
+	kv.lastBackupInc = 0
+	kv.syncBackup()
+}
+
+// lock acquires kv.mu in a span of its own, so time spent waiting for it shows up
+// in traces
+func (kv *KVServer) lock(ctx context.Context) {
+	_, span := kv.tracer.Start(ctx, "kvserver.Lock")
+	kv.mu.Lock()
+	span.End()
+}
+
+// apply writes an update into the local data; kv.mu must be held
+func (kv *KVServer) apply(req *pb.ForwardUpdateRequest) {
+	if old, ok := kv.data[req.Key]; ok {
+		kv.dataBytes -= uint64(len(req.Key) + len(old))
+	}
+	if req.Delete {
+		delete(kv.data, req.Key)
+	} else {
+		kv.data[req.Key] = req.Value
+		kv.dataBytes += uint64(len(req.Key) + len(req.Value))
+	}
+	kv.appliedSeq = req.Seq
 }
 
 // ForwardUpdate RPC handler (called by Primary on Backup)
 func (kv *KVServer) ForwardUpdate(ctx context.Context, req *pb.ForwardUpdateRequest) (*pb.ForwardUpdateResponse, error) {
-	kv.mu.Lock()
+	kv.lock(ctx)
 	defer kv.mu.Unlock()
 
-	if kv.role != "backup" {
+	if kv.role != "backup" || req.ViewNumber != kv.currentView.ViewNumber {
+		logging.For(ctx, kv.logger).Debug("Refusing forwarded update, not backup of its view",
+			"seq", req.Seq, "view", req.ViewNumber)
 		return &pb.ForwardUpdateResponse{
-			Ok: false,
+			Ok:  false,
+			Seq: kv.appliedSeq,
 		}, nil
 	}
 
-	kv.data[req.Key] = req.Value
+	// Applying an update after a gap, or without the primary's state, would leave
+	// this server with data the primary does not have
+	if kv.stateView < kv.backupSince || req.Seq != kv.appliedSeq+1 {
+		logging.For(ctx, kv.logger).Warn("Refusing forwarded update out of sequence, asking for the state",
+			"seq", req.Seq, "applied_seq", kv.appliedSeq)
+		return &pb.ForwardUpdateResponse{
+			Ok:     false,
+			Seq:    kv.appliedSeq,
+			Resync: true,
+		}, nil
+	}
+
+	logging.For(ctx, kv.logger).Debug("Applying forwarded update", "key", req.Key, "delete", req.Delete, "seq", req.Seq)
+	kv.apply(req)
 	return &pb.ForwardUpdateResponse{
-		Ok: true,
+		Ok:  true,
+		Seq: kv.appliedSeq,
 	}, nil
 }
 
@@ -353,29 +874,67 @@ func (kv *KVServer) SyncState(ctx context.Context, req *pb.SyncStateRequest) (*p
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
 
-	log.Printf("Receiving state transfer: %d keys\n", len(req.Data))
+	// Only the primary of the current view may overwrite the data; a primary that
+	// was replaced, or a previous run of it, would bring back lost writes
+	v := kv.currentView
+	if kv.role != "backup" || req.ViewNumber != v.ViewNumber ||
+		req.Primary != v.Primary || req.PrimaryIncarnation != v.PrimaryIncarnation {
+		kv.logger.Warn("Refusing state transfer, sender is not primary of the current view",
+			"from", req.Primary, "view", req.ViewNumber, "current_view", v.ViewNumber, "role", kv.role)
+		return &pb.SyncStateResponse{
+			Ok: false,
+		}, nil
+	}
+
+	kv.logger.Info("Receiving state transfer", "keys", len(req.Data), "view", req.ViewNumber, "seq", req.Seq)
 
 	// Overwrite local state
 	kv.data = make(map[string]string)
+	kv.dataBytes = 0
 	for k, v := range req.Data {
 		kv.data[k] = v
+		kv.dataBytes += uint64(len(k) + len(v))
 	}
+	kv.appliedSeq = req.Seq
+	kv.stateView = max(kv.stateView, req.ViewNumber)
+	kv.updateHealth()
 
 	return &pb.SyncStateResponse{
 		Ok: true,
 	}, nil
 }
 
+// ConfirmView RPC handler (called by Primary on Backup before answering a read)
+func (kv *KVServer) ConfirmView(ctx context.Context, req *pb.ConfirmViewRequest) (*pb.ConfirmViewResponse, error) {
+	kv.lock(ctx)
+	defer kv.mu.Unlock()
+
+	return &pb.ConfirmViewResponse{
+		Ok: kv.role == "backup" && req.ViewNumber == kv.currentView.ViewNumber,
+	}, nil
+}
+
 // Kill shuts down the server
 func (kv *KVServer) Kill() {
-	kv.dead = true
+	kv.dead.Store(true)
+	if kv.health != nil {
+		kv.health.Shutdown()
+	}
 	if kv.grpcServer != nil {
 		kv.grpcServer.GracefulStop()
 	}
+	if kv.peerServer != nil {
+		kv.peerServer.GracefulStop()
+	}
 	if kv.listener != nil {
 		kv.listener.Close()
 	}
+	if kv.peerLis != nil {
+		kv.peerLis.Close()
+	}
+	kv.mu.Lock()
 	if kv.vsConn != nil {
 		kv.vsConn.Close()
 	}
+	kv.mu.Unlock()
 }
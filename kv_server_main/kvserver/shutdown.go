@@ -0,0 +1,118 @@
+package kvserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goDistributedSystemDemo/clock"
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// shutdownPoll is how often Shutdown re-checks a condition it is waiting for
+const shutdownPoll = 50 * time.Millisecond
+
+// Shutdown leaves the cluster gracefully and then kills the server. A primary stops
+// accepting writes, waits for the writes in progress and any state transfer, copies
+// its full state to the backup, asks the view service to promote the backup, from
+// then on refuses reads too, and waits until the new primary has acknowledged the
+// view. A backup or idle server
+// only tells the view service it is leaving. If ctx ends first the server is
+// killed anyway and the reason is returned.
+func (kv *KVServer) Shutdown(ctx context.Context) error {
+	defer kv.Kill()
+
+	kv.mu.Lock()
+	kv.leaving = true
+	kv.updateHealth()
+	kv.mu.Unlock()
+	kv.writes.Wait()
+
+	for {
+		done, err := kv.leave(ctx)
+		if done {
+			return err
+		}
+		kv.logger.Info("Cannot leave yet", "err", err)
+		if !clock.Sleep(ctx, kv.clock, shutdownPoll) {
+			return fmt.Errorf("graceful shutdown gave up: %v", err)
+		}
+	}
+}
+
+// leave makes one attempt to leave the view. It reports done when the server
+// left, or when retrying cannot help.
+func (kv *KVServer) leave(ctx context.Context) (bool, error) {
+	kv.mu.Lock()
+	view := proto.Clone(kv.currentView).(*pb.View)
+	role := kv.role
+	syncing := kv.syncing || kv.flushing
+	client := kv.vsClient
+	var data map[string]string
+	seq := kv.appliedSeq
+	if role == "primary" && !syncing {
+		data = make(map[string]string, len(kv.data))
+		for k, v := range kv.data {
+			data[k] = v
+		}
+	}
+	kv.mu.Unlock()
+
+	if client == nil {
+		return true, nil // never reached the view service, so it holds no role
+	}
+	if role == "primary" {
+		if syncing {
+			return false, fmt.Errorf("state transfer to %s in progress", view.Backup)
+		}
+		if view.Backup == "" {
+			return false, fmt.Errorf("no backup to hand off to")
+		}
+		// Writes are stopped, so this copy brings the backup fully up to date
+		kv.logger.Info("Handing off, copying state to backup", "keys", len(data), "backup", view.Backup)
+		if err := kv.sendState(ctx, view.Backup, peerAddress(view), data, seq, view.ViewNumber); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := client.Leave(ctx, &pb.LeaveRequest{
+		ServerName:  kv.me,
+		Incarnation: kv.incarnation,
+		ViewNumber:  view.ViewNumber,
+	})
+	if err != nil {
+		return false, err
+	}
+	if !resp.Ok {
+		return false, fmt.Errorf("view service refused: %s", resp.Error)
+	}
+
+	// The backup may already serve as primary, so this server must not answer reads
+	kv.mu.Lock()
+	kv.left = true
+	kv.mu.Unlock()
+	if role != "primary" {
+		kv.logger.Info("Left view service", "role", role)
+		return true, nil
+	}
+
+	kv.logger.Info("Handed off, waiting for the new primary to acknowledge", "primary", resp.View.Primary, "view", resp.View.ViewNumber)
+	return true, kv.waitForAck(ctx, client, resp.View.ViewNumber)
+}
+
+// waitForAck waits until the view service reports that a view numbered at least
+// viewNumber was acknowledged by its primary
+func (kv *KVServer) waitForAck(ctx context.Context, client pb.ViewServiceClient, viewNumber uint64) error {
+	for {
+		resp, err := client.GetView(ctx, &pb.GetViewRequest{})
+		if err == nil && resp.View.ViewNumber >= viewNumber && resp.PrimaryAcked {
+			kv.logger.Info("View acknowledged", "view", resp.View.ViewNumber, "primary", resp.View.Primary)
+			return nil
+		}
+		if !clock.Sleep(ctx, kv.clock, shutdownPoll) {
+			return fmt.Errorf("new primary did not acknowledge view %d: %w", viewNumber, ctx.Err())
+		}
+	}
+}
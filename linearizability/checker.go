@@ -0,0 +1,309 @@
+package linearizability
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Result is the outcome of checking a history
+type Result struct {
+	Linearizable bool
+	TimedOut     bool // the check was abandoned because ctx ended; Linearizable is false
+
+	// Set when the history is not linearizable
+	Key            string      // key whose operations cannot be linearized
+	Counterexample []Operation // shortest prefix of that key's history that is not linearizable
+	Linearization  []Operation // longest linearization found for the counterexample, in order
+}
+
+// String describes the result, including the counterexample if there is one
+func (r Result) String() string {
+	if r.Linearizable {
+		return "history is linearizable"
+	}
+	if r.TimedOut {
+		return "linearizability check timed out"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "history is not linearizable on key %q\n", r.Key)
+	fmt.Fprintf(&b, "counterexample (%d operations):\n", len(r.Counterexample))
+	for _, op := range r.Counterexample {
+		fmt.Fprintf(&b, "  %s\n", op)
+	}
+	fmt.Fprintf(&b, "longest linearization (%d operations):\n", len(r.Linearization))
+	for _, op := range r.Linearization {
+		fmt.Fprintf(&b, "  %s\n", op)
+	}
+	b.WriteString("no remaining operation can be linearized after it\n")
+	return b.String()
+}
+
+// Check reports whether ops are linearizable with respect to a key-value map.
+// Keys are checked independently and in sorted order, and the first key that
+// fails is shrunk to its shortest non-linearizable prefix. Checking is
+// exponential in the worst case, so ctx bounds the time spent.
+func Check(ctx context.Context, ops []Operation) Result {
+	parts := partition(ops)
+	keys := make([]string, 0, len(parts))
+	for k := range parts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		ok, _, aborted := checkSingle(ctx, parts[key])
+		if aborted {
+			return Result{TimedOut: true}
+		}
+		if ok {
+			continue
+		}
+
+		counterexample, aborted := shrink(ctx, parts[key])
+		if aborted {
+			return Result{TimedOut: true}
+		}
+		_, best, _ := checkSingle(ctx, counterexample)
+		linearization := make([]Operation, 0, len(best))
+		for _, i := range best {
+			linearization = append(linearization, counterexample[i])
+		}
+		return Result{
+			Key:            key,
+			Counterexample: counterexample,
+			Linearization:  linearization,
+		}
+	}
+	return Result{Linearizable: true}
+}
+
+// shrink returns the shortest prefix of a non-linearizable single-key history that
+// is still not linearizable. A prefix ending at time t keeps every operation that
+// returned by t; writes still running at t become pending and reads still running
+// are dropped. Every prefix of a linearizable history is linearizable, so the
+// shortest failing prefix can be found by binary search.
+func shrink(ctx context.Context, ops []Operation) ([]Operation, bool) {
+	cuts := make([]int64, 0, len(ops))
+	for _, op := range ops {
+		if !op.Pending() {
+			cuts = append(cuts, op.Return)
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+
+	lo, hi := 0, len(cuts) // the prefix ending at cuts[hi-1], or the whole history, fails
+	for lo < hi {
+		mid := (lo + hi) / 2
+		ok, _, aborted := checkSingle(ctx, prefix(ops, cuts[mid]))
+		if aborted {
+			return nil, true
+		}
+		if ok {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if hi == len(cuts) {
+		return ops, false
+	}
+	return prefix(ops, cuts[hi]), false
+}
+
+// prefix returns the part of ops observable at time t
+func prefix(ops []Operation, t int64) []Operation {
+	out := make([]Operation, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case op.Call > t:
+		case op.Return <= t:
+			out = append(out, op)
+		case op.Kind != Get:
+			op.Return = Unknown
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// node is a call or return event in the doubly linked event list
+type node struct {
+	id    int   // index of the operation
+	time  int64 // call or return time
+	match *node // for a call, its return; nil for a return
+	prev  *node
+	next  *node
+}
+
+// checkSingle searches for a linearization of a single-key history using the
+// just-in-time algorithm of Wing & Gong with Lowe's memoisation. It also returns
+// the longest sequence of operation indexes it managed to linearize.
+func checkSingle(ctx context.Context, ops []Operation) (ok bool, best []int, aborted bool) {
+	head := buildList(ops)
+
+	type frame struct {
+		call  *node
+		state state
+	}
+	stack := make([]frame, 0, len(ops))
+	linearized := newBitset(len(ops))
+	cache := make(map[uint64][]cacheEntry)
+	current := state{}
+
+	entry := head.next
+	for steps := 0; head.next != nil; steps++ {
+		if steps%1024 == 0 && ctx.Err() != nil {
+			return false, best, true
+		}
+
+		if entry.match != nil {
+			// Try to linearize this call next
+			allowed, next := step(current, ops[entry.id])
+			if allowed {
+				candidate := linearized.clone().set(entry.id)
+				if addCache(cache, candidate, next) {
+					stack = append(stack, frame{call: entry, state: current})
+					current = next
+					linearized.set(entry.id)
+					lift(entry)
+					if len(stack) > len(best) {
+						best = best[:0]
+						for _, f := range stack {
+							best = append(best, f.call.id)
+						}
+					}
+					entry = head.next
+					continue
+				}
+			}
+			entry = entry.next
+			continue
+		}
+
+		// Reached a return whose call is not linearized: backtrack
+		if len(stack) == 0 {
+			return false, best, false
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		current = top.state
+		linearized.clear(top.call.id)
+		unlift(top.call)
+		entry = top.call.next
+	}
+	return true, best, false
+}
+
+// buildList links the call and return events of ops in time order. At equal
+// times calls come first, which treats touching operations as concurrent.
+func buildList(ops []Operation) *node {
+	nodes := make([]*node, 0, 2*len(ops))
+	for i, op := range ops {
+		ret := &node{id: i, time: op.Return}
+		call := &node{id: i, time: op.Call, match: ret}
+		nodes = append(nodes, call, ret)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].time != nodes[j].time {
+			return nodes[i].time < nodes[j].time
+		}
+		return nodes[i].match != nil && nodes[j].match == nil
+	})
+
+	head := &node{id: -1}
+	prev := head
+	for _, n := range nodes {
+		prev.next = n
+		n.prev = prev
+		prev = n
+	}
+	return head
+}
+
+// lift removes a call and its return from the list
+func lift(call *node) {
+	call.prev.next = call.next
+	if call.next != nil {
+		call.next.prev = call.prev
+	}
+	ret := call.match
+	ret.prev.next = ret.next
+	if ret.next != nil {
+		ret.next.prev = ret.prev
+	}
+}
+
+// unlift puts back a call and its return removed by lift
+func unlift(call *node) {
+	ret := call.match
+	ret.prev.next = ret
+	if ret.next != nil {
+		ret.next.prev = ret
+	}
+	call.prev.next = call
+	if call.next != nil {
+		call.next.prev = call
+	}
+}
+
+// cacheEntry is a (linearized set, state) pair that has already been explored
+type cacheEntry struct {
+	linearized bitset
+	state      state
+}
+
+// addCache records the pair and reports false if it had been seen before
+func addCache(cache map[uint64][]cacheEntry, linearized bitset, s state) bool {
+	h := linearized.hash()
+	for _, e := range cache[h] {
+		if e.state == s && e.linearized.equals(linearized) {
+			return false
+		}
+	}
+	cache[h] = append(cache[h], cacheEntry{linearized: linearized, state: s})
+	return true
+}
+
+// bitset is a fixed-size set of operation indexes
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) clone() bitset {
+	c := make(bitset, len(b))
+	copy(c, b)
+	return c
+}
+
+func (b bitset) set(i int) bitset {
+	b[i/64] |= 1 << (uint(i) % 64)
+	return b
+}
+
+func (b bitset) clear(i int) bitset {
+	b[i/64] &^= 1 << (uint(i) % 64)
+	return b
+}
+
+func (b bitset) equals(o bitset) bool {
+	for i := range b {
+		if b[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) hash() uint64 {
+	h := uint64(len(b))
+	for _, w := range b {
+		h = bits.RotateLeft64(h, 13) ^ (w * 0x9e3779b97f4a7c15)
+	}
+	return h
+}
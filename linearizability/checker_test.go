@@ -0,0 +1,240 @@
+package linearizability
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// put returns a completed or, with ret Unknown, pending put
+func put(key string, value string, call int64, ret int64) Operation {
+	return Operation{Kind: Put, Key: key, Input: value, Call: call, Return: ret}
+}
+
+// appendOp returns an append of value to key
+func appendOp(key string, value string, call int64, ret int64) Operation {
+	return Operation{Kind: Append, Key: key, Input: value, Call: call, Return: ret}
+}
+
+// del returns a delete of key
+func del(key string, call int64, ret int64) Operation {
+	return Operation{Kind: Delete, Key: key, Call: call, Return: ret}
+}
+
+// get returns a get of key that read value
+func get(key string, value string, call int64, ret int64) Operation {
+	return Operation{Kind: Get, Key: key, Output: value, Found: true, Call: call, Return: ret}
+}
+
+// getMissing returns a get of key that found no value
+func getMissing(key string, call int64, ret int64) Operation {
+	return Operation{Kind: Get, Key: key, Call: call, Return: ret}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		ops          []Operation
+		linearizable bool
+	}{
+		{"empty", nil, true},
+		{"sequential", []Operation{
+			getMissing("a", 0, 1),
+			put("a", "1", 2, 3),
+			get("a", "1", 4, 5),
+			put("a", "2", 6, 7),
+			get("a", "2", 8, 9),
+		}, true},
+		{"stale read", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, 3),
+			get("a", "1", 4, 5),
+		}, false},
+		{"read of a value never written", []Operation{
+			put("a", "1", 0, 1),
+			get("a", "2", 2, 3),
+		}, false},
+		{"read missing after write", []Operation{
+			put("a", "1", 0, 1),
+			getMissing("a", 2, 3),
+		}, false},
+		{"read concurrent with write sees old value", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, 10),
+			get("a", "1", 3, 4),
+		}, true},
+		{"read concurrent with write sees new value", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, 10),
+			get("a", "2", 3, 4),
+		}, true},
+		{"new value then old value during write", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, 10),
+			get("a", "2", 3, 4),
+			get("a", "1", 5, 6),
+		}, false},
+		{"concurrent writes in either order", []Operation{
+			put("a", "1", 0, 10),
+			put("a", "2", 0, 10),
+			get("a", "1", 11, 12),
+		}, true},
+		{"concurrent writes read in both orders", []Operation{
+			put("a", "1", 0, 10),
+			put("a", "2", 0, 10),
+			get("a", "1", 11, 12),
+			get("a", "2", 13, 14),
+		}, false},
+		{"reads during concurrent writes fix their order", []Operation{
+			put("a", "1", 0, 10),
+			put("a", "2", 0, 10),
+			get("a", "1", 1, 2),
+			get("a", "2", 3, 4),
+			get("a", "2", 11, 12),
+		}, true},
+		{"pending write took effect", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, Unknown),
+			get("a", "2", 10, 11),
+		}, true},
+		{"pending write never took effect", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, Unknown),
+			get("a", "1", 10, 11),
+		}, true},
+		{"pending write undone", []Operation{
+			put("a", "1", 0, 1),
+			put("a", "2", 2, Unknown),
+			get("a", "2", 10, 11),
+			get("a", "1", 12, 13),
+		}, false},
+		{"pending write read before it was issued", []Operation{
+			put("a", "2", 5, Unknown),
+			get("a", "2", 0, 1),
+		}, false},
+		{"append and delete", []Operation{
+			appendOp("a", "x", 0, 1),
+			appendOp("a", "y", 2, 3),
+			get("a", "xy", 4, 5),
+			del("a", 6, 7),
+			getMissing("a", 8, 9),
+		}, true},
+		{"lost append", []Operation{
+			appendOp("a", "x", 0, 1),
+			appendOp("a", "y", 2, 3),
+			get("a", "y", 4, 5),
+		}, false},
+		{"keys are independent", []Operation{
+			put("a", "1", 0, 1),
+			put("b", "2", 0, 1),
+			get("b", "2", 2, 3),
+			get("a", "1", 2, 3),
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Check(context.Background(), tt.ops)
+			if result.TimedOut {
+				t.Fatal("check timed out")
+			}
+			if result.Linearizable != tt.linearizable {
+				t.Fatalf("Linearizable = %v, want %v\n%s", result.Linearizable, tt.linearizable, result)
+			}
+		})
+	}
+}
+
+func TestCheckCounterexample(t *testing.T) {
+	// The stale read is the fourth operation; everything after it is noise the
+	// counterexample should leave out
+	ops := []Operation{
+		put("a", "1", 0, 1),
+		put("b", "1", 0, 1),
+		put("a", "2", 2, 3),
+		get("a", "1", 4, 5),
+	}
+	for i := int64(0); i < 20; i++ {
+		ops = append(ops, put("a", "3", 10+2*i, 11+2*i), get("a", "3", 11+2*i, 12+2*i))
+	}
+
+	result := Check(context.Background(), ops)
+	if result.Linearizable || result.Key != "a" {
+		t.Fatalf("got %+v, want a failure on key a", result)
+	}
+	if len(result.Counterexample) != 3 {
+		t.Fatalf("counterexample has %d operations, want 3:\n%s", len(result.Counterexample), result)
+	}
+	if len(result.Linearization) != 2 {
+		t.Errorf("linearization has %d operations, want 2:\n%s", len(result.Linearization), result)
+	}
+	if s := result.String(); !strings.Contains(s, `get("a") -> "1"`) {
+		t.Errorf("String() does not show the stale read:\n%s", s)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := Check(ctx, []Operation{put("a", "1", 0, 1), get("a", "1", 2, 3)})
+	if !result.TimedOut || result.Linearizable {
+		t.Fatalf("got %+v, want a timed out check", result)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	events := []Event{
+		{ClientID: 1, OpID: 1, Phase: Invoke, Time: 0, Kind: Put, Key: "a", Value: "1"},
+		{ClientID: 2, OpID: 1, Phase: Invoke, Time: 1, Kind: Put, Key: "a", Value: "2"},
+		{ClientID: 1, OpID: 1, Phase: Complete, Time: 2, Kind: Put, Key: "a"},
+		{ClientID: 2, OpID: 1, Phase: Complete, Time: 3, Kind: Put, Key: "a", Err: "timeout"},
+		{ClientID: 1, OpID: 2, Phase: Invoke, Time: 4, Kind: Get, Key: "a"},
+		{ClientID: 1, OpID: 2, Phase: Complete, Time: 5, Kind: Get, Key: "a", Err: "timeout"},
+		{ClientID: 1, OpID: 3, Phase: Invoke, Time: 6, Kind: Get, Key: "a"},
+		{ClientID: 1, OpID: 3, Phase: Complete, Time: 7, Kind: Get, Key: "a", Value: "2", Found: true},
+		{ClientID: 3, OpID: 1, Phase: Invoke, Time: 8, Kind: Delete, Key: "a"},
+		{ClientID: 4, OpID: 1, Phase: Invoke, Time: 9, Kind: Get, Key: "a"},
+	}
+	ops, err := Operations(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Operation{
+		{ClientID: 1, OpID: 1, Kind: Put, Key: "a", Input: "1", Call: 0, Return: 2},
+		{ClientID: 2, OpID: 1, Kind: Put, Key: "a", Input: "2", Call: 1, Return: Unknown}, // failed write
+		{ClientID: 1, OpID: 3, Kind: Get, Key: "a", Output: "2", Found: true, Call: 6, Return: 7},
+		{ClientID: 3, OpID: 1, Kind: Delete, Key: "a", Call: 8, Return: Unknown}, // never completed
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("got %d operations, want %d: %v", len(ops), len(want), ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("operation %d = %v, want %v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestOperationsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+	}{
+		{"complete before invoke", []Event{
+			{ClientID: 1, OpID: 1, Phase: Complete, Kind: Get, Key: "a"},
+		}},
+		{"invoked twice", []Event{
+			{ClientID: 1, OpID: 1, Phase: Invoke, Kind: Get, Key: "a"},
+			{ClientID: 1, OpID: 1, Phase: Invoke, Kind: Get, Key: "a"},
+		}},
+		{"unknown phase", []Event{
+			{ClientID: 1, OpID: 1, Phase: "start", Kind: Get, Key: "a"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Operations(tt.events); err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
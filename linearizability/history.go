@@ -0,0 +1,185 @@
+// Package linearizability records the operations clients issue against the KV
+// service and checks that the resulting history is linearizable.
+package linearizability
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Kind is the type of a KV operation
+type Kind string
+
+const (
+	Get    Kind = "get"
+	Put    Kind = "put"
+	Append Kind = "append"
+	Delete Kind = "delete"
+)
+
+// Phase tells whether an event starts or ends an operation
+type Phase string
+
+const (
+	Invoke   Phase = "invoke"
+	Complete Phase = "complete"
+)
+
+// Event is one timestamped step of an operation as seen by a client
+type Event struct {
+	ClientID int    `json:"client"`
+	OpID     int    `json:"op"`
+	Phase    Phase  `json:"phase"`
+	Time     int64  `json:"time"` // nanoseconds since the recorder started
+	Kind     Kind   `json:"kind"`
+	Key      string `json:"key"`
+	Value    string `json:"value,omitempty"` // written value, or value read on completion
+	Found    bool   `json:"found,omitempty"` // whether a get found the key
+	Err      string `json:"err,omitempty"`   // set on completion if the operation failed
+}
+
+// Unknown marks the return time of an operation whose outcome was never observed
+const Unknown = math.MaxInt64
+
+// Operation is an invoke event paired with its completion
+type Operation struct {
+	ClientID int
+	OpID     int
+	Kind     Kind
+	Key      string
+	Input    string // value written by put or append
+	Output   string // value returned by get
+	Found    bool   // whether get found the key
+	Call     int64
+	Return   int64 // Unknown if the operation may or may not have taken effect
+}
+
+// Pending reports whether the outcome of the operation is unknown
+func (op Operation) Pending() bool {
+	return op.Return == Unknown
+}
+
+func (op Operation) String() string {
+	var desc string
+	switch op.Kind {
+	case Get:
+		if op.Pending() {
+			desc = fmt.Sprintf("get(%q) -> ?", op.Key)
+		} else if !op.Found {
+			desc = fmt.Sprintf("get(%q) -> <no key>", op.Key)
+		} else {
+			desc = fmt.Sprintf("get(%q) -> %q", op.Key, op.Output)
+		}
+	case Put, Append:
+		desc = fmt.Sprintf("%s(%q, %q)", op.Kind, op.Key, op.Input)
+	case Delete:
+		desc = fmt.Sprintf("delete(%q)", op.Key)
+	default:
+		desc = fmt.Sprintf("%s(%q)", op.Kind, op.Key)
+	}
+	ret := "?"
+	if !op.Pending() {
+		ret = fmt.Sprint(op.Return)
+	}
+	return fmt.Sprintf("client %d: %s [%d, %s]", op.ClientID, desc, op.Call, ret)
+}
+
+// Operations pairs invoke and complete events into operations. Writes that failed
+// or never completed may or may not have taken effect, so they are kept with an
+// Unknown return time; reads without a successful result constrain nothing and are
+// dropped.
+func Operations(events []Event) ([]Operation, error) {
+	type opKey struct{ client, op int }
+	invokes := make(map[opKey]Event)
+	ops := make([]Operation, 0, len(events)/2)
+
+	for _, e := range events {
+		k := opKey{e.ClientID, e.OpID}
+		switch e.Phase {
+		case Invoke:
+			if _, dup := invokes[k]; dup {
+				return nil, fmt.Errorf("linearizability: client %d op %d invoked twice", e.ClientID, e.OpID)
+			}
+			invokes[k] = e
+		case Complete:
+			inv, ok := invokes[k]
+			if !ok {
+				return nil, fmt.Errorf("linearizability: client %d op %d completed before invoke", e.ClientID, e.OpID)
+			}
+			delete(invokes, k)
+			op := Operation{
+				ClientID: inv.ClientID,
+				OpID:     inv.OpID,
+				Kind:     inv.Kind,
+				Key:      inv.Key,
+				Input:    inv.Value,
+				Call:     inv.Time,
+				Return:   e.Time,
+			}
+			if e.Err != "" {
+				if inv.Kind == Get {
+					continue
+				}
+				op.Return = Unknown
+			} else if inv.Kind == Get {
+				op.Output = e.Value
+				op.Found = e.Found
+			}
+			ops = append(ops, op)
+		default:
+			return nil, fmt.Errorf("linearizability: unknown phase %q", e.Phase)
+		}
+	}
+
+	// Operations still outstanding at the end of the history
+	for _, inv := range invokes {
+		if inv.Kind == Get {
+			continue
+		}
+		ops = append(ops, Operation{
+			ClientID: inv.ClientID,
+			OpID:     inv.OpID,
+			Kind:     inv.Kind,
+			Key:      inv.Key,
+			Input:    inv.Value,
+			Call:     inv.Time,
+			Return:   Unknown,
+		})
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Call < ops[j].Call })
+	return ops, nil
+}
+
+// WriteEvents writes events as JSON lines
+func WriteEvents(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadEvents reads events written by WriteEvents
+func ReadEvents(r io.Reader) ([]Event, error) {
+	events := make([]Event, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("linearizability: line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,37 @@
+package linearizability
+
+// state is the sequential specification's view of a single key
+type state struct {
+	value  string
+	exists bool
+}
+
+// step applies op to s and reports whether op's observed result is allowed.
+// Writes whose outcome is unknown carry no result, so they are always allowed.
+func step(s state, op Operation) (bool, state) {
+	switch op.Kind {
+	case Get:
+		if op.Found != s.exists {
+			return false, s
+		}
+		return !s.exists || op.Output == s.value, s
+	case Put:
+		return true, state{value: op.Input, exists: true}
+	case Append:
+		return true, state{value: s.value + op.Input, exists: true}
+	case Delete:
+		return true, state{}
+	default:
+		return false, s
+	}
+}
+
+// partition splits a history by key; operations on different keys never
+// constrain each other, so each key can be checked on its own
+func partition(ops []Operation) map[string][]Operation {
+	parts := make(map[string][]Operation)
+	for _, op := range ops {
+		parts[op.Key] = append(parts[op.Key], op)
+	}
+	return parts
+}
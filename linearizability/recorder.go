@@ -0,0 +1,120 @@
+package linearizability
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"goDistributedSystemDemo/client_main/client"
+)
+
+// Recorder collects the events of every client created from it. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	start   time.Time
+	events  []Event
+	clients int
+}
+
+// NewRecorder creates an empty recorder; event times are relative to this call
+func NewRecorder() *Recorder {
+	return &Recorder{
+		start:  time.Now(),
+		events: make([]Event, 0),
+	}
+}
+
+// Client returns a recording wrapper around ck with a fresh client ID. Each
+// wrapper must only be used by one goroutine at a time, since a client in the
+// history is sequential; several wrappers may share ck.
+func (r *Recorder) Client(ck *client.Client) *RecordingClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients++
+	return &RecordingClient{rec: r, ck: ck, id: r.clients}
+}
+
+// Events returns a copy of the events recorded so far
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// Operations pairs the recorded events into operations
+func (r *Recorder) Operations() ([]Operation, error) {
+	return Operations(r.Events())
+}
+
+// add stamps an event with the current time and stores it
+func (r *Recorder) add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Time = time.Since(r.start).Nanoseconds()
+	r.events = append(r.events, e)
+}
+
+// RecordingClient issues operations through a client.Client and records them
+type RecordingClient struct {
+	rec    *Recorder
+	ck     *client.Client
+	id     int
+	nextOp int
+}
+
+// Get reads key and records the operation
+func (rc *RecordingClient) Get(ctx context.Context, key string) (string, bool, error) {
+	op := rc.invoke(Get, key, "")
+	value, found, err := rc.ck.Get(ctx, key)
+	rc.complete(op, Get, key, value, found, err)
+	return value, found, err
+}
+
+// Put writes key and records the operation
+func (rc *RecordingClient) Put(ctx context.Context, key string, value string) error {
+	op := rc.invoke(Put, key, value)
+	err := rc.ck.Put(ctx, key, value)
+	rc.complete(op, Put, key, "", false, err)
+	return err
+}
+
+// Delete removes key and records the operation
+func (rc *RecordingClient) Delete(ctx context.Context, key string) error {
+	op := rc.invoke(Delete, key, "")
+	err := rc.ck.Delete(ctx, key)
+	rc.complete(op, Delete, key, "", false, err)
+	return err
+}
+
+// invoke records the start of an operation and returns its ID
+func (rc *RecordingClient) invoke(kind Kind, key string, value string) int {
+	rc.nextOp++
+	rc.rec.add(Event{
+		ClientID: rc.id,
+		OpID:     rc.nextOp,
+		Phase:    Invoke,
+		Kind:     kind,
+		Key:      key,
+		Value:    value,
+	})
+	return rc.nextOp
+}
+
+// complete records the end of an operation
+func (rc *RecordingClient) complete(op int, kind Kind, key string, value string, found bool, err error) {
+	e := Event{
+		ClientID: rc.id,
+		OpID:     op,
+		Phase:    Complete,
+		Kind:     kind,
+		Key:      key,
+		Value:    value,
+		Found:    found,
+	}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	rc.rec.add(e)
+}
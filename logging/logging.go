@@ -0,0 +1,117 @@
+// Package logging sets up structured logging and carries request IDs from the
+// client through every server a request touches, so one request can be followed
+// in the logs of the primary and the backup.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"goDistributedSystemDemo/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the gRPC metadata key carrying the request ID
+const RequestIDHeader = "x-request-id"
+
+// New returns a logger writing to w at the given level (debug, info, warn or
+// error) in the given format (text or json)
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, want debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, want text or json", format)
+	}
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a context carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, "" if none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// For returns l with the request ID carried by ctx, if any, attached to every record
+func For(ctx context.Context, l *slog.Logger) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return l.With("request_id", id)
+	}
+	return l
+}
+
+// UnaryClientInterceptor sends the request ID carried by the call's context to the server
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := RequestID(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// DialOption installs the client interceptor on a connection
+func DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor())
+}
+
+// UnaryServerInterceptor puts the request ID of every call into its context and
+// logs the call at debug level. The ID is taken from the call's metadata, else
+// from the request itself, as for updates forwarded to the backup, else a new one
+// is made up.
+func UnaryServerInterceptor(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := ""
+		if ids := metadata.ValueFromIncomingContext(ctx, RequestIDHeader); len(ids) > 0 {
+			id = ids[0]
+		} else if r, ok := req.(interface{ GetRequestId() string }); ok {
+			id = r.GetRequestId()
+		}
+		if id == "" {
+			id = NewRequestID()
+		}
+		ctx = WithRequestID(ctx, id)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		l.LogAttrs(ctx, slog.LevelDebug, "RPC handled",
+			slog.String("method", path.Base(info.FullMethod)),
+			slog.String("request_id", id),
+			slog.String("code", metrics.Code(resp, err)),
+			slog.Duration("duration", time.Since(start)))
+		return resp, err
+	}
+}
+
+// ServerOption installs the server interceptor on a gRPC server
+func ServerOption(l *slog.Logger) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(UnaryServerInterceptor(l))
+}
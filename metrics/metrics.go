@@ -0,0 +1,101 @@
+// Package metrics serves Prometheus metrics over HTTP and records gRPC calls by
+// method and result. Every component keeps its metrics in a registry of its own,
+// so several servers can run in one process, as in tests and the harness.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// LatencyBuckets are histogram buckets, in seconds, for RPCs and pings
+var LatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewRegistry returns a registry holding the Go runtime and process metrics, for a
+// binary to add its component's metrics to
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return reg
+}
+
+// Handler returns an HTTP handler serving the metrics gathered by g
+func Handler(g prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// ListenAndServe serves the metrics gathered by g on address in the background
+func ListenAndServe(address string, g prometheus.Gatherer) {
+	go func() {
+		slog.Info("Metrics endpoint listening", "url", "http://"+address+"/metrics")
+		if err := http.ListenAndServe(address, Handler(g)); err != nil {
+			slog.Error("Metrics endpoint failed", "err", err)
+		}
+	}()
+}
+
+// RPC counts and times the gRPC calls a server handles
+type RPC struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewRPC creates the <namespace>_rpc_requests_total and
+// <namespace>_rpc_duration_seconds metrics and registers them with reg
+func NewRPC(namespace string, reg prometheus.Registerer) *RPC {
+	m := &RPC{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_requests_total",
+			Help:      "RPCs handled, by method and result code.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to handle RPCs, by method.",
+			Buckets:   LatencyBuckets,
+		}, []string{"method"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// UnaryServerInterceptor records every unary call the server handles
+func (m *RPC) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		method := path.Base(info.FullMethod)
+		m.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(method, Code(resp, err)).Inc()
+		return resp, err
+	}
+}
+
+// ServerOption installs the interceptor on a gRPC server
+func (m *RPC) ServerOption() grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(m.UnaryServerInterceptor())
+}
+
+// Code labels the result of a call: the gRPC status code if it failed, else the
+// error carried in the reply, such as ErrNotPrimary, else OK
+func Code(reply any, err error) string {
+	if err != nil {
+		return status.Code(err).String()
+	}
+	if r, ok := reply.(interface{ GetError() string }); ok && r.GetError() != "" {
+		return r.GetError()
+	}
+	return "OK"
+}
@@ -233,18 +233,287 @@ func (x *PutResponse) GetError() string {
 	return ""
 }
 
+// DeleteRequest is sent by clients to remove a key
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_proto_kvserver_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// DeleteResponse confirms the delete operation (deleting a missing key succeeds)
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_proto_kvserver_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ScanRequest is sent by clients to list keys with a common prefix
+type ScanRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Only keys starting with prefix are returned; empty matches all
+	Limit         uint32                 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`  // Maximum number of entries to return; 0 means no limit
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_proto_kvserver_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ScanRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// KeyValue is a single entry returned by Scan
+type KeyValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyValue) Reset() {
+	*x = KeyValue{}
+	mi := &file_proto_kvserver_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyValue) ProtoMessage() {}
+
+func (x *KeyValue) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyValue.ProtoReflect.Descriptor instead.
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *KeyValue) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KeyValue) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// ScanResponse returns the matching entries in key order
+type ScanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*KeyValue            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Ok            bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"` // Error message if any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
+	mi := &file_proto_kvserver_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResponse) ProtoMessage() {}
+
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ScanResponse) GetEntries() []*KeyValue {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ScanResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *ScanResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 // ForwardUpdateRequest is sent by Primary to Backup for replication
 type ForwardUpdateRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Delete        bool                   `protobuf:"varint,3,opt,name=delete,proto3" json:"delete,omitempty"`                           // True if the key is removed rather than written
+	Seq           uint64                 `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`                                 // Replication sequence assigned by the primary
+	RequestId     string                 `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`     // ID of the client request that caused the update, for correlating logs
+	ViewNumber    uint64                 `protobuf:"varint,6,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"` // View in which the sender is primary
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ForwardUpdateRequest) Reset() {
 	*x = ForwardUpdateRequest{}
-	mi := &file_proto_kvserver_proto_msgTypes[4]
+	mi := &file_proto_kvserver_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -256,7 +525,7 @@ func (x *ForwardUpdateRequest) String() string {
 func (*ForwardUpdateRequest) ProtoMessage() {}
 
 func (x *ForwardUpdateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvserver_proto_msgTypes[4]
+	mi := &file_proto_kvserver_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -269,7 +538,7 @@ func (x *ForwardUpdateRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ForwardUpdateRequest.ProtoReflect.Descriptor instead.
 func (*ForwardUpdateRequest) Descriptor() ([]byte, []int) {
-	return file_proto_kvserver_proto_rawDescGZIP(), []int{4}
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ForwardUpdateRequest) GetKey() string {
@@ -286,17 +555,47 @@ func (x *ForwardUpdateRequest) GetValue() string {
 	return ""
 }
 
+func (x *ForwardUpdateRequest) GetDelete() bool {
+	if x != nil {
+		return x.Delete
+	}
+	return false
+}
+
+func (x *ForwardUpdateRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ForwardUpdateRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ForwardUpdateRequest) GetViewNumber() uint64 {
+	if x != nil {
+		return x.ViewNumber
+	}
+	return 0
+}
+
 // ForwardUpdateResponse confirms the update
 type ForwardUpdateResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`         // True if the backup applied the update
+	Seq           uint64                 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`       // Highest replication sequence the backup has applied
+	Resync        bool                   `protobuf:"varint,3,opt,name=resync,proto3" json:"resync,omitempty"` // True if the backup missed updates and needs a state transfer
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ForwardUpdateResponse) Reset() {
 	*x = ForwardUpdateResponse{}
-	mi := &file_proto_kvserver_proto_msgTypes[5]
+	mi := &file_proto_kvserver_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -308,7 +607,7 @@ func (x *ForwardUpdateResponse) String() string {
 func (*ForwardUpdateResponse) ProtoMessage() {}
 
 func (x *ForwardUpdateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvserver_proto_msgTypes[5]
+	mi := &file_proto_kvserver_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -321,7 +620,7 @@ func (x *ForwardUpdateResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ForwardUpdateResponse.ProtoReflect.Descriptor instead.
 func (*ForwardUpdateResponse) Descriptor() ([]byte, []int) {
-	return file_proto_kvserver_proto_rawDescGZIP(), []int{5}
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ForwardUpdateResponse) GetOk() bool {
@@ -331,18 +630,35 @@ func (x *ForwardUpdateResponse) GetOk() bool {
 	return false
 }
 
+func (x *ForwardUpdateResponse) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ForwardUpdateResponse) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
 // SyncStateRequest is sent by Primary to transfer entire state to new Backup
 type SyncStateRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          map[string]string      `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // The entire key-value map
-	ViewNumber    uint64                 `protobuf:"varint,2,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"`                                            // The view number of this state
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Data               map[string]string      `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // The entire key-value map
+	ViewNumber         uint64                 `protobuf:"varint,2,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"`                                            // The view number of this state
+	Seq                uint64                 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`                                                                            // Highest replication sequence included in data
+	Primary            string                 `protobuf:"bytes,4,opt,name=primary,proto3" json:"primary,omitempty"`                                                                     // Name of the sender, primary of the view
+	PrimaryIncarnation uint64                 `protobuf:"varint,5,opt,name=primary_incarnation,json=primaryIncarnation,proto3" json:"primary_incarnation,omitempty"`                    // Incarnation of the sender
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *SyncStateRequest) Reset() {
 	*x = SyncStateRequest{}
-	mi := &file_proto_kvserver_proto_msgTypes[6]
+	mi := &file_proto_kvserver_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -354,7 +670,7 @@ func (x *SyncStateRequest) String() string {
 func (*SyncStateRequest) ProtoMessage() {}
 
 func (x *SyncStateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvserver_proto_msgTypes[6]
+	mi := &file_proto_kvserver_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -367,7 +683,7 @@ func (x *SyncStateRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncStateRequest.ProtoReflect.Descriptor instead.
 func (*SyncStateRequest) Descriptor() ([]byte, []int) {
-	return file_proto_kvserver_proto_rawDescGZIP(), []int{6}
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *SyncStateRequest) GetData() map[string]string {
@@ -384,17 +700,38 @@ func (x *SyncStateRequest) GetViewNumber() uint64 {
 	return 0
 }
 
+func (x *SyncStateRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *SyncStateRequest) GetPrimary() string {
+	if x != nil {
+		return x.Primary
+	}
+	return ""
+}
+
+func (x *SyncStateRequest) GetPrimaryIncarnation() uint64 {
+	if x != nil {
+		return x.PrimaryIncarnation
+	}
+	return 0
+}
+
 // SyncStateResponse confirms the state transfer
 type SyncStateResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"` // False if the receiver is not the backup of the sender's view
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SyncStateResponse) Reset() {
 	*x = SyncStateResponse{}
-	mi := &file_proto_kvserver_proto_msgTypes[7]
+	mi := &file_proto_kvserver_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -406,7 +743,7 @@ func (x *SyncStateResponse) String() string {
 func (*SyncStateResponse) ProtoMessage() {}
 
 func (x *SyncStateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvserver_proto_msgTypes[7]
+	mi := &file_proto_kvserver_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -419,7 +756,7 @@ func (x *SyncStateResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncStateResponse.ProtoReflect.Descriptor instead.
 func (*SyncStateResponse) Descriptor() ([]byte, []int) {
-	return file_proto_kvserver_proto_rawDescGZIP(), []int{7}
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *SyncStateResponse) GetOk() bool {
@@ -429,6 +766,96 @@ func (x *SyncStateResponse) GetOk() bool {
 	return false
 }
 
+// ConfirmViewRequest asks the backup whether a view is still current
+type ConfirmViewRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ViewNumber    uint64                 `protobuf:"varint,1,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"` // View in which the sender is primary
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfirmViewRequest) Reset() {
+	*x = ConfirmViewRequest{}
+	mi := &file_proto_kvserver_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfirmViewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmViewRequest) ProtoMessage() {}
+
+func (x *ConfirmViewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmViewRequest.ProtoReflect.Descriptor instead.
+func (*ConfirmViewRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ConfirmViewRequest) GetViewNumber() uint64 {
+	if x != nil {
+		return x.ViewNumber
+	}
+	return 0
+}
+
+// ConfirmViewResponse answers ConfirmViewRequest
+type ConfirmViewResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"` // True if the receiver is backup in that view
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfirmViewResponse) Reset() {
+	*x = ConfirmViewResponse{}
+	mi := &file_proto_kvserver_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfirmViewResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmViewResponse) ProtoMessage() {}
+
+func (x *ConfirmViewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvserver_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmViewResponse.ProtoReflect.Descriptor instead.
+func (*ConfirmViewResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvserver_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ConfirmViewResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
 var File_proto_kvserver_proto protoreflect.FileDescriptor
 
 const file_proto_kvserver_proto_rawDesc = "" +
@@ -447,26 +874,61 @@ const file_proto_kvserver_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value\"3\n" +
 	"\vPutResponse\x12\x0e\n" +
 	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\">\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"!\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"6\n" +
+	"\x0eDeleteResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\";\n" +
+	"\vScanRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\"2\n" +
+	"\bKeyValue\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"_\n" +
+	"\fScanResponse\x12)\n" +
+	"\aentries\x18\x01 \x03(\v2\x0f.proto.KeyValueR\aentries\x12\x0e\n" +
+	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xa8\x01\n" +
 	"\x14ForwardUpdateRequest\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"'\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x16\n" +
+	"\x06delete\x18\x03 \x01(\bR\x06delete\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\x04R\x03seq\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12\x1f\n" +
+	"\vview_number\x18\x06 \x01(\x04R\n" +
+	"viewNumber\"Q\n" +
 	"\x15ForwardUpdateResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\"\xa3\x01\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x04R\x03seq\x12\x16\n" +
+	"\x06resync\x18\x03 \x01(\bR\x06resync\"\x80\x02\n" +
 	"\x10SyncStateRequest\x125\n" +
 	"\x04data\x18\x01 \x03(\v2!.proto.SyncStateRequest.DataEntryR\x04data\x12\x1f\n" +
 	"\vview_number\x18\x02 \x01(\x04R\n" +
-	"viewNumber\x1a7\n" +
+	"viewNumber\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x04R\x03seq\x12\x18\n" +
+	"\aprimary\x18\x04 \x01(\tR\aprimary\x12/\n" +
+	"\x13primary_incarnation\x18\x05 \x01(\x04R\x12primaryIncarnation\x1a7\n" +
 	"\tDataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"#\n" +
 	"\x11SyncStateResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok2\xf2\x01\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"5\n" +
+	"\x12ConfirmViewRequest\x12\x1f\n" +
+	"\vview_number\x18\x01 \x01(\x04R\n" +
+	"viewNumber\"%\n" +
+	"\x13ConfirmViewResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok2\xce\x01\n" +
 	"\bKVServer\x12,\n" +
 	"\x03Get\x12\x11.proto.GetRequest\x1a\x12.proto.GetResponse\x12,\n" +
-	"\x03Put\x12\x11.proto.PutRequest\x1a\x12.proto.PutResponse\x12J\n" +
+	"\x03Put\x12\x11.proto.PutRequest\x1a\x12.proto.PutResponse\x125\n" +
+	"\x06Delete\x12\x14.proto.DeleteRequest\x1a\x15.proto.DeleteResponse\x12/\n" +
+	"\x04Scan\x12\x12.proto.ScanRequest\x1a\x13.proto.ScanResponse2\xdf\x01\n" +
+	"\vReplication\x12J\n" +
 	"\rForwardUpdate\x12\x1b.proto.ForwardUpdateRequest\x1a\x1c.proto.ForwardUpdateResponse\x12>\n" +
-	"\tSyncState\x12\x17.proto.SyncStateRequest\x1a\x18.proto.SyncStateResponseB\x1fZ\x1dgoDistributedSystemDemo/protob\x06proto3"
+	"\tSyncState\x12\x17.proto.SyncStateRequest\x1a\x18.proto.SyncStateResponse\x12D\n" +
+	"\vConfirmView\x12\x19.proto.ConfirmViewRequest\x1a\x1a.proto.ConfirmViewResponseB\x1fZ\x1dgoDistributedSystemDemo/protob\x06proto3"
 
 var (
 	file_proto_kvserver_proto_rawDescOnce sync.Once
@@ -480,33 +942,47 @@ func file_proto_kvserver_proto_rawDescGZIP() []byte {
 	return file_proto_kvserver_proto_rawDescData
 }
 
-var file_proto_kvserver_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_proto_kvserver_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_proto_kvserver_proto_goTypes = []any{
 	(*GetRequest)(nil),            // 0: proto.GetRequest
 	(*GetResponse)(nil),           // 1: proto.GetResponse
 	(*PutRequest)(nil),            // 2: proto.PutRequest
 	(*PutResponse)(nil),           // 3: proto.PutResponse
-	(*ForwardUpdateRequest)(nil),  // 4: proto.ForwardUpdateRequest
-	(*ForwardUpdateResponse)(nil), // 5: proto.ForwardUpdateResponse
-	(*SyncStateRequest)(nil),      // 6: proto.SyncStateRequest
-	(*SyncStateResponse)(nil),     // 7: proto.SyncStateResponse
-	nil,                           // 8: proto.SyncStateRequest.DataEntry
+	(*DeleteRequest)(nil),         // 4: proto.DeleteRequest
+	(*DeleteResponse)(nil),        // 5: proto.DeleteResponse
+	(*ScanRequest)(nil),           // 6: proto.ScanRequest
+	(*KeyValue)(nil),              // 7: proto.KeyValue
+	(*ScanResponse)(nil),          // 8: proto.ScanResponse
+	(*ForwardUpdateRequest)(nil),  // 9: proto.ForwardUpdateRequest
+	(*ForwardUpdateResponse)(nil), // 10: proto.ForwardUpdateResponse
+	(*SyncStateRequest)(nil),      // 11: proto.SyncStateRequest
+	(*SyncStateResponse)(nil),     // 12: proto.SyncStateResponse
+	(*ConfirmViewRequest)(nil),    // 13: proto.ConfirmViewRequest
+	(*ConfirmViewResponse)(nil),   // 14: proto.ConfirmViewResponse
+	nil,                           // 15: proto.SyncStateRequest.DataEntry
 }
 var file_proto_kvserver_proto_depIdxs = []int32{
-	8, // 0: proto.SyncStateRequest.data:type_name -> proto.SyncStateRequest.DataEntry
-	0, // 1: proto.KVServer.Get:input_type -> proto.GetRequest
-	2, // 2: proto.KVServer.Put:input_type -> proto.PutRequest
-	4, // 3: proto.KVServer.ForwardUpdate:input_type -> proto.ForwardUpdateRequest
-	6, // 4: proto.KVServer.SyncState:input_type -> proto.SyncStateRequest
-	1, // 5: proto.KVServer.Get:output_type -> proto.GetResponse
-	3, // 6: proto.KVServer.Put:output_type -> proto.PutResponse
-	5, // 7: proto.KVServer.ForwardUpdate:output_type -> proto.ForwardUpdateResponse
-	7, // 8: proto.KVServer.SyncState:output_type -> proto.SyncStateResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	7,  // 0: proto.ScanResponse.entries:type_name -> proto.KeyValue
+	15, // 1: proto.SyncStateRequest.data:type_name -> proto.SyncStateRequest.DataEntry
+	0,  // 2: proto.KVServer.Get:input_type -> proto.GetRequest
+	2,  // 3: proto.KVServer.Put:input_type -> proto.PutRequest
+	4,  // 4: proto.KVServer.Delete:input_type -> proto.DeleteRequest
+	6,  // 5: proto.KVServer.Scan:input_type -> proto.ScanRequest
+	9,  // 6: proto.Replication.ForwardUpdate:input_type -> proto.ForwardUpdateRequest
+	11, // 7: proto.Replication.SyncState:input_type -> proto.SyncStateRequest
+	13, // 8: proto.Replication.ConfirmView:input_type -> proto.ConfirmViewRequest
+	1,  // 9: proto.KVServer.Get:output_type -> proto.GetResponse
+	3,  // 10: proto.KVServer.Put:output_type -> proto.PutResponse
+	5,  // 11: proto.KVServer.Delete:output_type -> proto.DeleteResponse
+	8,  // 12: proto.KVServer.Scan:output_type -> proto.ScanResponse
+	10, // 13: proto.Replication.ForwardUpdate:output_type -> proto.ForwardUpdateResponse
+	12, // 14: proto.Replication.SyncState:output_type -> proto.SyncStateResponse
+	14, // 15: proto.Replication.ConfirmView:output_type -> proto.ConfirmViewResponse
+	9,  // [9:16] is the sub-list for method output_type
+	2,  // [2:9] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_kvserver_proto_init() }
@@ -520,9 +996,9 @@ func file_proto_kvserver_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_kvserver_proto_rawDesc), len(file_proto_kvserver_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   16,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_kvserver_proto_goTypes,
 		DependencyIndexes: file_proto_kvserver_proto_depIdxs,
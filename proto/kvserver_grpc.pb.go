@@ -19,10 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	KVServer_Get_FullMethodName           = "/proto.KVServer/Get"
-	KVServer_Put_FullMethodName           = "/proto.KVServer/Put"
-	KVServer_ForwardUpdate_FullMethodName = "/proto.KVServer/ForwardUpdate"
-	KVServer_SyncState_FullMethodName     = "/proto.KVServer/SyncState"
+	KVServer_Get_FullMethodName    = "/proto.KVServer/Get"
+	KVServer_Put_FullMethodName    = "/proto.KVServer/Put"
+	KVServer_Delete_FullMethodName = "/proto.KVServer/Delete"
+	KVServer_Scan_FullMethodName   = "/proto.KVServer/Scan"
 )
 
 // KVServerClient is the client API for KVServer service.
@@ -35,10 +35,10 @@ type KVServerClient interface {
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	// Put stores a key-value pair (only handled by Primary)
 	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
-	// ForwardUpdate is called by Primary to replicate updates to Backup
-	ForwardUpdate(ctx context.Context, in *ForwardUpdateRequest, opts ...grpc.CallOption) (*ForwardUpdateResponse, error)
-	// SyncState is called by Primary to transfer entire state to new Backup
-	SyncState(ctx context.Context, in *SyncStateRequest, opts ...grpc.CallOption) (*SyncStateResponse, error)
+	// Delete removes a key (only handled by Primary)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Scan lists keys with a given prefix (only handled by Primary)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
 }
 
 type kVServerClient struct {
@@ -69,20 +69,20 @@ func (c *kVServerClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.C
 	return out, nil
 }
 
-func (c *kVServerClient) ForwardUpdate(ctx context.Context, in *ForwardUpdateRequest, opts ...grpc.CallOption) (*ForwardUpdateResponse, error) {
+func (c *kVServerClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(ForwardUpdateResponse)
-	err := c.cc.Invoke(ctx, KVServer_ForwardUpdate_FullMethodName, in, out, cOpts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, KVServer_Delete_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *kVServerClient) SyncState(ctx context.Context, in *SyncStateRequest, opts ...grpc.CallOption) (*SyncStateResponse, error) {
+func (c *kVServerClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(SyncStateResponse)
-	err := c.cc.Invoke(ctx, KVServer_SyncState_FullMethodName, in, out, cOpts...)
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, KVServer_Scan_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -99,10 +99,10 @@ type KVServerServer interface {
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	// Put stores a key-value pair (only handled by Primary)
 	Put(context.Context, *PutRequest) (*PutResponse, error)
-	// ForwardUpdate is called by Primary to replicate updates to Backup
-	ForwardUpdate(context.Context, *ForwardUpdateRequest) (*ForwardUpdateResponse, error)
-	// SyncState is called by Primary to transfer entire state to new Backup
-	SyncState(context.Context, *SyncStateRequest) (*SyncStateResponse, error)
+	// Delete removes a key (only handled by Primary)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Scan lists keys with a given prefix (only handled by Primary)
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
 	mustEmbedUnimplementedKVServerServer()
 }
 
@@ -119,11 +119,11 @@ func (UnimplementedKVServerServer) Get(context.Context, *GetRequest) (*GetRespon
 func (UnimplementedKVServerServer) Put(context.Context, *PutRequest) (*PutResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
 }
-func (UnimplementedKVServerServer) ForwardUpdate(context.Context, *ForwardUpdateRequest) (*ForwardUpdateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ForwardUpdate not implemented")
+func (UnimplementedKVServerServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
-func (UnimplementedKVServerServer) SyncState(context.Context, *SyncStateRequest) (*SyncStateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SyncState not implemented")
+func (UnimplementedKVServerServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
 }
 func (UnimplementedKVServerServer) mustEmbedUnimplementedKVServerServer() {}
 func (UnimplementedKVServerServer) testEmbeddedByValue()                  {}
@@ -182,38 +182,38 @@ func _KVServer_Put_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVServer_ForwardUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ForwardUpdateRequest)
+func _KVServer_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServerServer).ForwardUpdate(ctx, in)
+		return srv.(KVServerServer).Delete(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVServer_ForwardUpdate_FullMethodName,
+		FullMethod: KVServer_Delete_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServerServer).ForwardUpdate(ctx, req.(*ForwardUpdateRequest))
+		return srv.(KVServerServer).Delete(ctx, req.(*DeleteRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVServer_SyncState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SyncStateRequest)
+func _KVServer_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServerServer).SyncState(ctx, in)
+		return srv.(KVServerServer).Scan(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVServer_SyncState_FullMethodName,
+		FullMethod: KVServer_Scan_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServerServer).SyncState(ctx, req.(*SyncStateRequest))
+		return srv.(KVServerServer).Scan(ctx, req.(*ScanRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -233,13 +233,205 @@ var KVServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Put",
 			Handler:    _KVServer_Put_Handler,
 		},
+		{
+			MethodName: "Delete",
+			Handler:    _KVServer_Delete_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _KVServer_Scan_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/kvserver.proto",
+}
+
+const (
+	Replication_ForwardUpdate_FullMethodName = "/proto.Replication/ForwardUpdate"
+	Replication_SyncState_FullMethodName     = "/proto.Replication/SyncState"
+	Replication_ConfirmView_FullMethodName   = "/proto.Replication/ConfirmView"
+)
+
+// ReplicationClient is the client API for Replication service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Replication service for copying data from Primary to Backup. It is internal to
+// the KV servers and may be served on a port of its own, apart from clients.
+type ReplicationClient interface {
+	// ForwardUpdate is called by Primary to replicate updates to Backup
+	ForwardUpdate(ctx context.Context, in *ForwardUpdateRequest, opts ...grpc.CallOption) (*ForwardUpdateResponse, error)
+	// SyncState is called by Primary to transfer entire state to new Backup
+	SyncState(ctx context.Context, in *SyncStateRequest, opts ...grpc.CallOption) (*SyncStateResponse, error)
+	// ConfirmView is called by Primary before answering a read, so a primary that
+	// was replaced without knowing it cannot serve stale data
+	ConfirmView(ctx context.Context, in *ConfirmViewRequest, opts ...grpc.CallOption) (*ConfirmViewResponse, error)
+}
+
+type replicationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReplicationClient(cc grpc.ClientConnInterface) ReplicationClient {
+	return &replicationClient{cc}
+}
+
+func (c *replicationClient) ForwardUpdate(ctx context.Context, in *ForwardUpdateRequest, opts ...grpc.CallOption) (*ForwardUpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForwardUpdateResponse)
+	err := c.cc.Invoke(ctx, Replication_ForwardUpdate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicationClient) SyncState(ctx context.Context, in *SyncStateRequest, opts ...grpc.CallOption) (*SyncStateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncStateResponse)
+	err := c.cc.Invoke(ctx, Replication_SyncState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicationClient) ConfirmView(ctx context.Context, in *ConfirmViewRequest, opts ...grpc.CallOption) (*ConfirmViewResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfirmViewResponse)
+	err := c.cc.Invoke(ctx, Replication_ConfirmView_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplicationServer is the server API for Replication service.
+// All implementations must embed UnimplementedReplicationServer
+// for forward compatibility.
+//
+// Replication service for copying data from Primary to Backup. It is internal to
+// the KV servers and may be served on a port of its own, apart from clients.
+type ReplicationServer interface {
+	// ForwardUpdate is called by Primary to replicate updates to Backup
+	ForwardUpdate(context.Context, *ForwardUpdateRequest) (*ForwardUpdateResponse, error)
+	// SyncState is called by Primary to transfer entire state to new Backup
+	SyncState(context.Context, *SyncStateRequest) (*SyncStateResponse, error)
+	// ConfirmView is called by Primary before answering a read, so a primary that
+	// was replaced without knowing it cannot serve stale data
+	ConfirmView(context.Context, *ConfirmViewRequest) (*ConfirmViewResponse, error)
+	mustEmbedUnimplementedReplicationServer()
+}
+
+// UnimplementedReplicationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReplicationServer struct{}
+
+func (UnimplementedReplicationServer) ForwardUpdate(context.Context, *ForwardUpdateRequest) (*ForwardUpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForwardUpdate not implemented")
+}
+func (UnimplementedReplicationServer) SyncState(context.Context, *SyncStateRequest) (*SyncStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncState not implemented")
+}
+func (UnimplementedReplicationServer) ConfirmView(context.Context, *ConfirmViewRequest) (*ConfirmViewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmView not implemented")
+}
+func (UnimplementedReplicationServer) mustEmbedUnimplementedReplicationServer() {}
+func (UnimplementedReplicationServer) testEmbeddedByValue()                     {}
+
+// UnsafeReplicationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReplicationServer will
+// result in compilation errors.
+type UnsafeReplicationServer interface {
+	mustEmbedUnimplementedReplicationServer()
+}
+
+func RegisterReplicationServer(s grpc.ServiceRegistrar, srv ReplicationServer) {
+	// If the following call pancis, it indicates UnimplementedReplicationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Replication_ServiceDesc, srv)
+}
+
+func _Replication_ForwardUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationServer).ForwardUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replication_ForwardUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).ForwardUpdate(ctx, req.(*ForwardUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replication_SyncState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationServer).SyncState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replication_SyncState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).SyncState(ctx, req.(*SyncStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replication_ConfirmView_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmViewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationServer).ConfirmView(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replication_ConfirmView_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).ConfirmView(ctx, req.(*ConfirmViewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Replication_ServiceDesc is the grpc.ServiceDesc for Replication service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Replication_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.Replication",
+	HandlerType: (*ReplicationServer)(nil),
+	Methods: []grpc.MethodDesc{
 		{
 			MethodName: "ForwardUpdate",
-			Handler:    _KVServer_ForwardUpdate_Handler,
+			Handler:    _Replication_ForwardUpdate_Handler,
 		},
 		{
 			MethodName: "SyncState",
-			Handler:    _KVServer_SyncState_Handler,
+			Handler:    _Replication_SyncState_Handler,
+		},
+		{
+			MethodName: "ConfirmView",
+			Handler:    _Replication_ConfirmView_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
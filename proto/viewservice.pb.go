@@ -23,12 +23,15 @@ const (
 
 // View represents the current system configuration
 type View struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ViewNumber    uint64                 `protobuf:"varint,1,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"` // Increments every time the view changes
-	Primary       string                 `protobuf:"bytes,2,opt,name=primary,proto3" json:"primary,omitempty"`                          // Address of the primary server
-	Backup        string                 `protobuf:"bytes,3,opt,name=backup,proto3" json:"backup,omitempty"`                            // Address of the backup server (can be empty)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	ViewNumber         uint64                 `protobuf:"varint,1,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"`                         // Increments every time the view changes
+	Primary            string                 `protobuf:"bytes,2,opt,name=primary,proto3" json:"primary,omitempty"`                                                  // Address of the primary server
+	Backup             string                 `protobuf:"bytes,3,opt,name=backup,proto3" json:"backup,omitempty"`                                                    // Address of the backup server (can be empty)
+	PrimaryIncarnation uint64                 `protobuf:"varint,4,opt,name=primary_incarnation,json=primaryIncarnation,proto3" json:"primary_incarnation,omitempty"` // Incarnation of the primary process named in this view
+	BackupIncarnation  uint64                 `protobuf:"varint,5,opt,name=backup_incarnation,json=backupIncarnation,proto3" json:"backup_incarnation,omitempty"`    // Incarnation of the backup process named in this view
+	BackupPeerAddress  string                 `protobuf:"bytes,6,opt,name=backup_peer_address,json=backupPeerAddress,proto3" json:"backup_peer_address,omitempty"`   // Where the backup accepts replication, the backup's address if empty
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *View) Reset() {
@@ -82,11 +85,36 @@ func (x *View) GetBackup() string {
 	return ""
 }
 
+func (x *View) GetPrimaryIncarnation() uint64 {
+	if x != nil {
+		return x.PrimaryIncarnation
+	}
+	return 0
+}
+
+func (x *View) GetBackupIncarnation() uint64 {
+	if x != nil {
+		return x.BackupIncarnation
+	}
+	return 0
+}
+
+func (x *View) GetBackupPeerAddress() string {
+	if x != nil {
+		return x.BackupPeerAddress
+	}
+	return ""
+}
+
 // PingRequest is sent by KV servers to announce they are alive
 type PingRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerName    string                 `protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`  // Name/address of the server sending ping
-	ViewNumber    uint64                 `protobuf:"varint,2,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"` // The view number the server currently knows
+	ServerName    string                 `protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`        // Name/address of the server sending ping
+	ViewNumber    uint64                 `protobuf:"varint,2,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"`       // The view number the server currently knows
+	Incarnation   uint64                 `protobuf:"varint,3,opt,name=incarnation,proto3" json:"incarnation,omitempty"`                       // Random ID chosen at process start; changes when the server restarts
+	Zone          string                 `protobuf:"bytes,4,opt,name=zone,proto3" json:"zone,omitempty"`                                      // Failure domain of the server, empty if unknown
+	BackupSynced  bool                   `protobuf:"varint,5,opt,name=backup_synced,json=backupSynced,proto3" json:"backup_synced,omitempty"` // Sent by the primary: the backup of view_number holds the full state
+	Metadata      *ServerMetadata        `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`                              // What the server runs and holds; absent from servers that predate it
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -135,6 +163,127 @@ func (x *PingRequest) GetViewNumber() uint64 {
 	return 0
 }
 
+func (x *PingRequest) GetIncarnation() uint64 {
+	if x != nil {
+		return x.Incarnation
+	}
+	return 0
+}
+
+func (x *PingRequest) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *PingRequest) GetBackupSynced() bool {
+	if x != nil {
+		return x.BackupSynced
+	}
+	return false
+}
+
+func (x *PingRequest) GetMetadata() *ServerMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// ServerMetadata describes a KV server for placement and status tooling
+type ServerMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`                                                                         // Build version of the server
+	Labels        map[string]string      `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Operator-defined labels
+	AppliedSeq    uint64                 `protobuf:"varint,3,opt,name=applied_seq,json=appliedSeq,proto3" json:"applied_seq,omitempty"`                                                // Highest replication sequence applied to the server's data
+	Keys          uint64                 `protobuf:"varint,4,opt,name=keys,proto3" json:"keys,omitempty"`                                                                              // Number of keys held
+	DataBytes     uint64                 `protobuf:"varint,5,opt,name=data_bytes,json=dataBytes,proto3" json:"data_bytes,omitempty"`                                                   // Total size of the keys and values held
+	Willing       bool                   `protobuf:"varint,6,opt,name=willing,proto3" json:"willing,omitempty"`                                                                        // False while the server does not want a role, e.g. when shutting down
+	PeerAddress   string                 `protobuf:"bytes,7,opt,name=peer_address,json=peerAddress,proto3" json:"peer_address,omitempty"`                                              // Where the server accepts replication, empty if on its own address
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerMetadata) Reset() {
+	*x = ServerMetadata{}
+	mi := &file_proto_viewservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerMetadata) ProtoMessage() {}
+
+func (x *ServerMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerMetadata.ProtoReflect.Descriptor instead.
+func (*ServerMetadata) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ServerMetadata) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ServerMetadata) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ServerMetadata) GetAppliedSeq() uint64 {
+	if x != nil {
+		return x.AppliedSeq
+	}
+	return 0
+}
+
+func (x *ServerMetadata) GetKeys() uint64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+func (x *ServerMetadata) GetDataBytes() uint64 {
+	if x != nil {
+		return x.DataBytes
+	}
+	return 0
+}
+
+func (x *ServerMetadata) GetWilling() bool {
+	if x != nil {
+		return x.Willing
+	}
+	return false
+}
+
+func (x *ServerMetadata) GetPeerAddress() string {
+	if x != nil {
+		return x.PeerAddress
+	}
+	return ""
+}
+
 // PingResponse returns the current view
 type PingResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -145,7 +294,7 @@ type PingResponse struct {
 
 func (x *PingResponse) Reset() {
 	*x = PingResponse{}
-	mi := &file_proto_viewservice_proto_msgTypes[2]
+	mi := &file_proto_viewservice_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -157,7 +306,7 @@ func (x *PingResponse) String() string {
 func (*PingResponse) ProtoMessage() {}
 
 func (x *PingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_viewservice_proto_msgTypes[2]
+	mi := &file_proto_viewservice_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -170,7 +319,7 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
 func (*PingResponse) Descriptor() ([]byte, []int) {
-	return file_proto_viewservice_proto_rawDescGZIP(), []int{2}
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *PingResponse) GetView() *View {
@@ -189,7 +338,7 @@ type GetViewRequest struct {
 
 func (x *GetViewRequest) Reset() {
 	*x = GetViewRequest{}
-	mi := &file_proto_viewservice_proto_msgTypes[3]
+	mi := &file_proto_viewservice_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -201,7 +350,7 @@ func (x *GetViewRequest) String() string {
 func (*GetViewRequest) ProtoMessage() {}
 
 func (x *GetViewRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_viewservice_proto_msgTypes[3]
+	mi := &file_proto_viewservice_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -214,20 +363,23 @@ func (x *GetViewRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetViewRequest.ProtoReflect.Descriptor instead.
 func (*GetViewRequest) Descriptor() ([]byte, []int) {
-	return file_proto_viewservice_proto_rawDescGZIP(), []int{3}
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{4}
 }
 
 // GetViewResponse returns the current view
 type GetViewResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	View          *View                  `protobuf:"bytes,1,opt,name=view,proto3" json:"view,omitempty"`
+	PrimaryAcked  bool                   `protobuf:"varint,2,opt,name=primary_acked,json=primaryAcked,proto3" json:"primary_acked,omitempty"`   // Whether the primary has acknowledged this view
+	Blocked       string                 `protobuf:"bytes,3,opt,name=blocked,proto3" json:"blocked,omitempty"`                                  // Why the view service cannot make progress, empty if it can
+	DataLossRisk  bool                   `protobuf:"varint,4,opt,name=data_loss_risk,json=dataLossRisk,proto3" json:"data_loss_risk,omitempty"` // Blocked because no live server is known to hold every acknowledged write
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetViewResponse) Reset() {
 	*x = GetViewResponse{}
-	mi := &file_proto_viewservice_proto_msgTypes[4]
+	mi := &file_proto_viewservice_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -239,7 +391,7 @@ func (x *GetViewResponse) String() string {
 func (*GetViewResponse) ProtoMessage() {}
 
 func (x *GetViewResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_viewservice_proto_msgTypes[4]
+	mi := &file_proto_viewservice_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -252,7 +404,7 @@ func (x *GetViewResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetViewResponse.ProtoReflect.Descriptor instead.
 func (*GetViewResponse) Descriptor() ([]byte, []int) {
-	return file_proto_viewservice_proto_rawDescGZIP(), []int{4}
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetViewResponse) GetView() *View {
@@ -262,29 +414,705 @@ func (x *GetViewResponse) GetView() *View {
 	return nil
 }
 
-var File_proto_viewservice_proto protoreflect.FileDescriptor
+func (x *GetViewResponse) GetPrimaryAcked() bool {
+	if x != nil {
+		return x.PrimaryAcked
+	}
+	return false
+}
 
-const file_proto_viewservice_proto_rawDesc = "" +
-	"\n" +
-	"\x17proto/viewservice.proto\x12\x05proto\"Y\n" +
-	"\x04View\x12\x1f\n" +
-	"\vview_number\x18\x01 \x01(\x04R\n" +
-	"viewNumber\x12\x18\n" +
-	"\aprimary\x18\x02 \x01(\tR\aprimary\x12\x16\n" +
-	"\x06backup\x18\x03 \x01(\tR\x06backup\"O\n" +
-	"\vPingRequest\x12\x1f\n" +
-	"\vserver_name\x18\x01 \x01(\tR\n" +
-	"serverName\x12\x1f\n" +
-	"\vview_number\x18\x02 \x01(\x04R\n" +
-	"viewNumber\"/\n" +
-	"\fPingResponse\x12\x1f\n" +
-	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\"\x10\n" +
-	"\x0eGetViewRequest\"2\n" +
-	"\x0fGetViewResponse\x12\x1f\n" +
-	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view2x\n" +
+func (x *GetViewResponse) GetBlocked() string {
+	if x != nil {
+		return x.Blocked
+	}
+	return ""
+}
+
+func (x *GetViewResponse) GetDataLossRisk() bool {
+	if x != nil {
+		return x.DataLossRisk
+	}
+	return false
+}
+
+// LeaveRequest is sent by a KV server that is shutting down on purpose
+type LeaveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerName    string                 `protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`  // Name/address of the leaving server
+	Incarnation   uint64                 `protobuf:"varint,2,opt,name=incarnation,proto3" json:"incarnation,omitempty"`                 // Incarnation of the leaving process
+	ViewNumber    uint64                 `protobuf:"varint,3,opt,name=view_number,json=viewNumber,proto3" json:"view_number,omitempty"` // View the server's state matches; a primary may only leave from the current view
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveRequest) Reset() {
+	*x = LeaveRequest{}
+	mi := &file_proto_viewservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveRequest) ProtoMessage() {}
+
+func (x *LeaveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveRequest.ProtoReflect.Descriptor instead.
+func (*LeaveRequest) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LeaveRequest) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetIncarnation() uint64 {
+	if x != nil {
+		return x.Incarnation
+	}
+	return 0
+}
+
+func (x *LeaveRequest) GetViewNumber() uint64 {
+	if x != nil {
+		return x.ViewNumber
+	}
+	return 0
+}
+
+// LeaveResponse returns the view after the server left
+type LeaveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	View          *View                  `protobuf:"bytes,1,opt,name=view,proto3" json:"view,omitempty"`
+	Ok            bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"` // ErrViewNotAcked or ErrNoBackup if a primary cannot leave yet
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveResponse) Reset() {
+	*x = LeaveResponse{}
+	mi := &file_proto_viewservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveResponse) ProtoMessage() {}
+
+func (x *LeaveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveResponse.ProtoReflect.Descriptor instead.
+func (*LeaveResponse) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LeaveResponse) GetView() *View {
+	if x != nil {
+		return x.View
+	}
+	return nil
+}
+
+func (x *LeaveResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *LeaveResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ForcePrimaryRequest is an operator override that makes a live server primary
+type ForcePrimaryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerName    string                 `protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"` // Name/address of the server to make primary
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForcePrimaryRequest) Reset() {
+	*x = ForcePrimaryRequest{}
+	mi := &file_proto_viewservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForcePrimaryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForcePrimaryRequest) ProtoMessage() {}
+
+func (x *ForcePrimaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForcePrimaryRequest.ProtoReflect.Descriptor instead.
+func (*ForcePrimaryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ForcePrimaryRequest) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+// ForcePrimaryResponse returns the view after the override
+type ForcePrimaryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	View          *View                  `protobuf:"bytes,1,opt,name=view,proto3" json:"view,omitempty"`
+	Ok            bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"` // ErrUnknownServer if the server is not alive
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForcePrimaryResponse) Reset() {
+	*x = ForcePrimaryResponse{}
+	mi := &file_proto_viewservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForcePrimaryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForcePrimaryResponse) ProtoMessage() {}
+
+func (x *ForcePrimaryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForcePrimaryResponse.ProtoReflect.Descriptor instead.
+func (*ForcePrimaryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ForcePrimaryResponse) GetView() *View {
+	if x != nil {
+		return x.View
+	}
+	return nil
+}
+
+func (x *ForcePrimaryResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *ForcePrimaryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ListServersRequest asks for every server the view service knows
+type ListServersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServersRequest) Reset() {
+	*x = ListServersRequest{}
+	mi := &file_proto_viewservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServersRequest) ProtoMessage() {}
+
+func (x *ListServersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServersRequest.ProtoReflect.Descriptor instead.
+func (*ListServersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{10}
+}
+
+// ServerStatus is what the view service knows about one server
+type ServerStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Incarnation      uint64                 `protobuf:"varint,2,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	Alive            bool                   `protobuf:"varint,3,opt,name=alive,proto3" json:"alive,omitempty"`
+	Leaving          bool                   `protobuf:"varint,4,opt,name=leaving,proto3" json:"leaving,omitempty"` // Left on purpose and gets no role until it restarts
+	Zone             string                 `protobuf:"bytes,5,opt,name=zone,proto3" json:"zone,omitempty"`
+	Role             string                 `protobuf:"bytes,6,opt,name=role,proto3" json:"role,omitempty"`                                                      // "primary", "backup" or "idle" in the current view
+	LastPingUnixNano int64                  `protobuf:"varint,7,opt,name=last_ping_unix_nano,json=lastPingUnixNano,proto3" json:"last_ping_unix_nano,omitempty"` // When the last ping arrived
+	Metadata         *ServerMetadata        `protobuf:"bytes,8,opt,name=metadata,proto3" json:"metadata,omitempty"`                                              // From the last ping, absent if the server sent none
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ServerStatus) Reset() {
+	*x = ServerStatus{}
+	mi := &file_proto_viewservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerStatus) ProtoMessage() {}
+
+func (x *ServerStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerStatus.ProtoReflect.Descriptor instead.
+func (*ServerStatus) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ServerStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetIncarnation() uint64 {
+	if x != nil {
+		return x.Incarnation
+	}
+	return 0
+}
+
+func (x *ServerStatus) GetAlive() bool {
+	if x != nil {
+		return x.Alive
+	}
+	return false
+}
+
+func (x *ServerStatus) GetLeaving() bool {
+	if x != nil {
+		return x.Leaving
+	}
+	return false
+}
+
+func (x *ServerStatus) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetLastPingUnixNano() int64 {
+	if x != nil {
+		return x.LastPingUnixNano
+	}
+	return 0
+}
+
+func (x *ServerStatus) GetMetadata() *ServerMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// ListServersResponse lists servers in join order
+type ListServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*ServerStatus        `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServersResponse) Reset() {
+	*x = ListServersResponse{}
+	mi := &file_proto_viewservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServersResponse) ProtoMessage() {}
+
+func (x *ListServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServersResponse.ProtoReflect.Descriptor instead.
+func (*ListServersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListServersResponse) GetServers() []*ServerStatus {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+// ViewChange records one transition to a new view
+type ViewChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	View          *View                  `protobuf:"bytes,1,opt,name=view,proto3" json:"view,omitempty"`                                        // The view the service moved to
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`                                    // primary_died, backup_died, backup_promoted, server_assigned, admin, ...
+	Detail        string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`                                    // What happened, in words
+	TimeUnixNano  int64                  `protobuf:"varint,4,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"` // When the view service made the change
+	Servers       []*ServerStatus        `protobuf:"bytes,5,rep,name=servers,proto3" json:"servers,omitempty"`                                  // Liveness of every known server at that moment
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ViewChange) Reset() {
+	*x = ViewChange{}
+	mi := &file_proto_viewservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ViewChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ViewChange) ProtoMessage() {}
+
+func (x *ViewChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ViewChange.ProtoReflect.Descriptor instead.
+func (*ViewChange) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ViewChange) GetView() *View {
+	if x != nil {
+		return x.View
+	}
+	return nil
+}
+
+func (x *ViewChange) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ViewChange) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *ViewChange) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *ViewChange) GetServers() []*ServerStatus {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+// ListViewsRequest asks for the recorded view changes
+type ListViewsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AfterView     uint64                 `protobuf:"varint,1,opt,name=after_view,json=afterView,proto3" json:"after_view,omitempty"` // Only changes to views numbered above this
+	Limit         uint32                 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                          // At most this many, the most recent; 0 means all that are kept
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListViewsRequest) Reset() {
+	*x = ListViewsRequest{}
+	mi := &file_proto_viewservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListViewsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListViewsRequest) ProtoMessage() {}
+
+func (x *ListViewsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListViewsRequest.ProtoReflect.Descriptor instead.
+func (*ListViewsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListViewsRequest) GetAfterView() uint64 {
+	if x != nil {
+		return x.AfterView
+	}
+	return 0
+}
+
+func (x *ListViewsRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// ListViewsResponse lists view changes, oldest first
+type ListViewsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Changes       []*ViewChange          `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListViewsResponse) Reset() {
+	*x = ListViewsResponse{}
+	mi := &file_proto_viewservice_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListViewsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListViewsResponse) ProtoMessage() {}
+
+func (x *ListViewsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_viewservice_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListViewsResponse.ProtoReflect.Descriptor instead.
+func (*ListViewsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_viewservice_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListViewsResponse) GetChanges() []*ViewChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+var File_proto_viewservice_proto protoreflect.FileDescriptor
+
+const file_proto_viewservice_proto_rawDesc = "" +
+	"\n" +
+	"\x17proto/viewservice.proto\x12\x05proto\"\xe9\x01\n" +
+	"\x04View\x12\x1f\n" +
+	"\vview_number\x18\x01 \x01(\x04R\n" +
+	"viewNumber\x12\x18\n" +
+	"\aprimary\x18\x02 \x01(\tR\aprimary\x12\x16\n" +
+	"\x06backup\x18\x03 \x01(\tR\x06backup\x12/\n" +
+	"\x13primary_incarnation\x18\x04 \x01(\x04R\x12primaryIncarnation\x12-\n" +
+	"\x12backup_incarnation\x18\x05 \x01(\x04R\x11backupIncarnation\x12.\n" +
+	"\x13backup_peer_address\x18\x06 \x01(\tR\x11backupPeerAddress\"\xdd\x01\n" +
+	"\vPingRequest\x12\x1f\n" +
+	"\vserver_name\x18\x01 \x01(\tR\n" +
+	"serverName\x12\x1f\n" +
+	"\vview_number\x18\x02 \x01(\x04R\n" +
+	"viewNumber\x12 \n" +
+	"\vincarnation\x18\x03 \x01(\x04R\vincarnation\x12\x12\n" +
+	"\x04zone\x18\x04 \x01(\tR\x04zone\x12#\n" +
+	"\rbackup_synced\x18\x05 \x01(\bR\fbackupSynced\x121\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x15.proto.ServerMetadataR\bmetadata\"\xb1\x02\n" +
+	"\x0eServerMetadata\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x129\n" +
+	"\x06labels\x18\x02 \x03(\v2!.proto.ServerMetadata.LabelsEntryR\x06labels\x12\x1f\n" +
+	"\vapplied_seq\x18\x03 \x01(\x04R\n" +
+	"appliedSeq\x12\x12\n" +
+	"\x04keys\x18\x04 \x01(\x04R\x04keys\x12\x1d\n" +
+	"\n" +
+	"data_bytes\x18\x05 \x01(\x04R\tdataBytes\x12\x18\n" +
+	"\awilling\x18\x06 \x01(\bR\awilling\x12!\n" +
+	"\fpeer_address\x18\a \x01(\tR\vpeerAddress\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"/\n" +
+	"\fPingResponse\x12\x1f\n" +
+	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\"\x10\n" +
+	"\x0eGetViewRequest\"\x97\x01\n" +
+	"\x0fGetViewResponse\x12\x1f\n" +
+	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\x12#\n" +
+	"\rprimary_acked\x18\x02 \x01(\bR\fprimaryAcked\x12\x18\n" +
+	"\ablocked\x18\x03 \x01(\tR\ablocked\x12$\n" +
+	"\x0edata_loss_risk\x18\x04 \x01(\bR\fdataLossRisk\"r\n" +
+	"\fLeaveRequest\x12\x1f\n" +
+	"\vserver_name\x18\x01 \x01(\tR\n" +
+	"serverName\x12 \n" +
+	"\vincarnation\x18\x02 \x01(\x04R\vincarnation\x12\x1f\n" +
+	"\vview_number\x18\x03 \x01(\x04R\n" +
+	"viewNumber\"V\n" +
+	"\rLeaveResponse\x12\x1f\n" +
+	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\x12\x0e\n" +
+	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"6\n" +
+	"\x13ForcePrimaryRequest\x12\x1f\n" +
+	"\vserver_name\x18\x01 \x01(\tR\n" +
+	"serverName\"]\n" +
+	"\x14ForcePrimaryResponse\x12\x1f\n" +
+	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\x12\x0e\n" +
+	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x14\n" +
+	"\x12ListServersRequest\"\xfe\x01\n" +
+	"\fServerStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vincarnation\x18\x02 \x01(\x04R\vincarnation\x12\x14\n" +
+	"\x05alive\x18\x03 \x01(\bR\x05alive\x12\x18\n" +
+	"\aleaving\x18\x04 \x01(\bR\aleaving\x12\x12\n" +
+	"\x04zone\x18\x05 \x01(\tR\x04zone\x12\x12\n" +
+	"\x04role\x18\x06 \x01(\tR\x04role\x12-\n" +
+	"\x13last_ping_unix_nano\x18\a \x01(\x03R\x10lastPingUnixNano\x121\n" +
+	"\bmetadata\x18\b \x01(\v2\x15.proto.ServerMetadataR\bmetadata\"D\n" +
+	"\x13ListServersResponse\x12-\n" +
+	"\aservers\x18\x01 \x03(\v2\x13.proto.ServerStatusR\aservers\"\xb2\x01\n" +
+	"\n" +
+	"ViewChange\x12\x1f\n" +
+	"\x04view\x18\x01 \x01(\v2\v.proto.ViewR\x04view\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\x12$\n" +
+	"\x0etime_unix_nano\x18\x04 \x01(\x03R\ftimeUnixNano\x12-\n" +
+	"\aservers\x18\x05 \x03(\v2\x13.proto.ServerStatusR\aservers\"G\n" +
+	"\x10ListViewsRequest\x12\x1d\n" +
+	"\n" +
+	"after_view\x18\x01 \x01(\x04R\tafterView\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\"@\n" +
+	"\x11ListViewsResponse\x12+\n" +
+	"\achanges\x18\x01 \x03(\v2\x11.proto.ViewChangeR\achanges2\xfb\x02\n" +
 	"\vViewService\x12/\n" +
 	"\x04Ping\x12\x12.proto.PingRequest\x1a\x13.proto.PingResponse\x128\n" +
-	"\aGetView\x12\x15.proto.GetViewRequest\x1a\x16.proto.GetViewResponseB$Z\"goDistribclearutedSystemDemo/protob\x06proto3"
+	"\aGetView\x12\x15.proto.GetViewRequest\x1a\x16.proto.GetViewResponse\x122\n" +
+	"\x05Leave\x12\x13.proto.LeaveRequest\x1a\x14.proto.LeaveResponse\x12G\n" +
+	"\fForcePrimary\x12\x1a.proto.ForcePrimaryRequest\x1a\x1b.proto.ForcePrimaryResponse\x12D\n" +
+	"\vListServers\x12\x19.proto.ListServersRequest\x1a\x1a.proto.ListServersResponse\x12>\n" +
+	"\tListViews\x12\x17.proto.ListViewsRequest\x1a\x18.proto.ListViewsResponseB$Z\"goDistribclearutedSystemDemo/protob\x06proto3"
 
 var (
 	file_proto_viewservice_proto_rawDescOnce sync.Once
@@ -298,26 +1126,55 @@ func file_proto_viewservice_proto_rawDescGZIP() []byte {
 	return file_proto_viewservice_proto_rawDescData
 }
 
-var file_proto_viewservice_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_viewservice_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_proto_viewservice_proto_goTypes = []any{
-	(*View)(nil),            // 0: proto.View
-	(*PingRequest)(nil),     // 1: proto.PingRequest
-	(*PingResponse)(nil),    // 2: proto.PingResponse
-	(*GetViewRequest)(nil),  // 3: proto.GetViewRequest
-	(*GetViewResponse)(nil), // 4: proto.GetViewResponse
+	(*View)(nil),                 // 0: proto.View
+	(*PingRequest)(nil),          // 1: proto.PingRequest
+	(*ServerMetadata)(nil),       // 2: proto.ServerMetadata
+	(*PingResponse)(nil),         // 3: proto.PingResponse
+	(*GetViewRequest)(nil),       // 4: proto.GetViewRequest
+	(*GetViewResponse)(nil),      // 5: proto.GetViewResponse
+	(*LeaveRequest)(nil),         // 6: proto.LeaveRequest
+	(*LeaveResponse)(nil),        // 7: proto.LeaveResponse
+	(*ForcePrimaryRequest)(nil),  // 8: proto.ForcePrimaryRequest
+	(*ForcePrimaryResponse)(nil), // 9: proto.ForcePrimaryResponse
+	(*ListServersRequest)(nil),   // 10: proto.ListServersRequest
+	(*ServerStatus)(nil),         // 11: proto.ServerStatus
+	(*ListServersResponse)(nil),  // 12: proto.ListServersResponse
+	(*ViewChange)(nil),           // 13: proto.ViewChange
+	(*ListViewsRequest)(nil),     // 14: proto.ListViewsRequest
+	(*ListViewsResponse)(nil),    // 15: proto.ListViewsResponse
+	nil,                          // 16: proto.ServerMetadata.LabelsEntry
 }
 var file_proto_viewservice_proto_depIdxs = []int32{
-	0, // 0: proto.PingResponse.view:type_name -> proto.View
-	0, // 1: proto.GetViewResponse.view:type_name -> proto.View
-	1, // 2: proto.ViewService.Ping:input_type -> proto.PingRequest
-	3, // 3: proto.ViewService.GetView:input_type -> proto.GetViewRequest
-	2, // 4: proto.ViewService.Ping:output_type -> proto.PingResponse
-	4, // 5: proto.ViewService.GetView:output_type -> proto.GetViewResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	2,  // 0: proto.PingRequest.metadata:type_name -> proto.ServerMetadata
+	16, // 1: proto.ServerMetadata.labels:type_name -> proto.ServerMetadata.LabelsEntry
+	0,  // 2: proto.PingResponse.view:type_name -> proto.View
+	0,  // 3: proto.GetViewResponse.view:type_name -> proto.View
+	0,  // 4: proto.LeaveResponse.view:type_name -> proto.View
+	0,  // 5: proto.ForcePrimaryResponse.view:type_name -> proto.View
+	2,  // 6: proto.ServerStatus.metadata:type_name -> proto.ServerMetadata
+	11, // 7: proto.ListServersResponse.servers:type_name -> proto.ServerStatus
+	0,  // 8: proto.ViewChange.view:type_name -> proto.View
+	11, // 9: proto.ViewChange.servers:type_name -> proto.ServerStatus
+	13, // 10: proto.ListViewsResponse.changes:type_name -> proto.ViewChange
+	1,  // 11: proto.ViewService.Ping:input_type -> proto.PingRequest
+	4,  // 12: proto.ViewService.GetView:input_type -> proto.GetViewRequest
+	6,  // 13: proto.ViewService.Leave:input_type -> proto.LeaveRequest
+	8,  // 14: proto.ViewService.ForcePrimary:input_type -> proto.ForcePrimaryRequest
+	10, // 15: proto.ViewService.ListServers:input_type -> proto.ListServersRequest
+	14, // 16: proto.ViewService.ListViews:input_type -> proto.ListViewsRequest
+	3,  // 17: proto.ViewService.Ping:output_type -> proto.PingResponse
+	5,  // 18: proto.ViewService.GetView:output_type -> proto.GetViewResponse
+	7,  // 19: proto.ViewService.Leave:output_type -> proto.LeaveResponse
+	9,  // 20: proto.ViewService.ForcePrimary:output_type -> proto.ForcePrimaryResponse
+	12, // 21: proto.ViewService.ListServers:output_type -> proto.ListServersResponse
+	15, // 22: proto.ViewService.ListViews:output_type -> proto.ListViewsResponse
+	17, // [17:23] is the sub-list for method output_type
+	11, // [11:17] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_proto_viewservice_proto_init() }
@@ -331,7 +1188,7 @@ func file_proto_viewservice_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_viewservice_proto_rawDesc), len(file_proto_viewservice_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,8 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ViewService_Ping_FullMethodName    = "/proto.ViewService/Ping"
-	ViewService_GetView_FullMethodName = "/proto.ViewService/GetView"
+	ViewService_Ping_FullMethodName         = "/proto.ViewService/Ping"
+	ViewService_GetView_FullMethodName      = "/proto.ViewService/GetView"
+	ViewService_Leave_FullMethodName        = "/proto.ViewService/Leave"
+	ViewService_ForcePrimary_FullMethodName = "/proto.ViewService/ForcePrimary"
+	ViewService_ListServers_FullMethodName  = "/proto.ViewService/ListServers"
+	ViewService_ListViews_FullMethodName    = "/proto.ViewService/ListViews"
 )
 
 // ViewServiceClient is the client API for ViewService service.
@@ -33,6 +37,15 @@ type ViewServiceClient interface {
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	// GetView is called by clients to find the current primary
 	GetView(ctx context.Context, in *GetViewRequest, opts ...grpc.CallOption) (*GetViewResponse, error)
+	// Leave removes a server from the view at once; a primary hands off to its backup
+	Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error)
+	// ForcePrimary lets an operator unblock the view service, accepting that writes
+	// held only by the lost primary are gone
+	ForcePrimary(ctx context.Context, in *ForcePrimaryRequest, opts ...grpc.CallOption) (*ForcePrimaryResponse, error)
+	// ListServers reports every known server with the metadata from its last ping
+	ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error)
+	// ListViews returns the recent view changes with the reason for each
+	ListViews(ctx context.Context, in *ListViewsRequest, opts ...grpc.CallOption) (*ListViewsResponse, error)
 }
 
 type viewServiceClient struct {
@@ -63,6 +76,46 @@ func (c *viewServiceClient) GetView(ctx context.Context, in *GetViewRequest, opt
 	return out, nil
 }
 
+func (c *viewServiceClient) Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeaveResponse)
+	err := c.cc.Invoke(ctx, ViewService_Leave_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *viewServiceClient) ForcePrimary(ctx context.Context, in *ForcePrimaryRequest, opts ...grpc.CallOption) (*ForcePrimaryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForcePrimaryResponse)
+	err := c.cc.Invoke(ctx, ViewService_ForcePrimary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *viewServiceClient) ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServersResponse)
+	err := c.cc.Invoke(ctx, ViewService_ListServers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *viewServiceClient) ListViews(ctx context.Context, in *ListViewsRequest, opts ...grpc.CallOption) (*ListViewsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListViewsResponse)
+	err := c.cc.Invoke(ctx, ViewService_ListViews_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ViewServiceServer is the server API for ViewService service.
 // All implementations must embed UnimplementedViewServiceServer
 // for forward compatibility.
@@ -73,6 +126,15 @@ type ViewServiceServer interface {
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	// GetView is called by clients to find the current primary
 	GetView(context.Context, *GetViewRequest) (*GetViewResponse, error)
+	// Leave removes a server from the view at once; a primary hands off to its backup
+	Leave(context.Context, *LeaveRequest) (*LeaveResponse, error)
+	// ForcePrimary lets an operator unblock the view service, accepting that writes
+	// held only by the lost primary are gone
+	ForcePrimary(context.Context, *ForcePrimaryRequest) (*ForcePrimaryResponse, error)
+	// ListServers reports every known server with the metadata from its last ping
+	ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error)
+	// ListViews returns the recent view changes with the reason for each
+	ListViews(context.Context, *ListViewsRequest) (*ListViewsResponse, error)
 	mustEmbedUnimplementedViewServiceServer()
 }
 
@@ -89,6 +151,18 @@ func (UnimplementedViewServiceServer) Ping(context.Context, *PingRequest) (*Ping
 func (UnimplementedViewServiceServer) GetView(context.Context, *GetViewRequest) (*GetViewResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetView not implemented")
 }
+func (UnimplementedViewServiceServer) Leave(context.Context, *LeaveRequest) (*LeaveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leave not implemented")
+}
+func (UnimplementedViewServiceServer) ForcePrimary(context.Context, *ForcePrimaryRequest) (*ForcePrimaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForcePrimary not implemented")
+}
+func (UnimplementedViewServiceServer) ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServers not implemented")
+}
+func (UnimplementedViewServiceServer) ListViews(context.Context, *ListViewsRequest) (*ListViewsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListViews not implemented")
+}
 func (UnimplementedViewServiceServer) mustEmbedUnimplementedViewServiceServer() {}
 func (UnimplementedViewServiceServer) testEmbeddedByValue()                     {}
 
@@ -146,6 +220,78 @@ func _ViewService_GetView_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ViewService_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ViewServiceServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ViewService_Leave_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ViewServiceServer).Leave(ctx, req.(*LeaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ViewService_ForcePrimary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForcePrimaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ViewServiceServer).ForcePrimary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ViewService_ForcePrimary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ViewServiceServer).ForcePrimary(ctx, req.(*ForcePrimaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ViewService_ListServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ViewServiceServer).ListServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ViewService_ListServers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ViewServiceServer).ListServers(ctx, req.(*ListServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ViewService_ListViews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListViewsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ViewServiceServer).ListViews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ViewService_ListViews_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ViewServiceServer).ListViews(ctx, req.(*ListViewsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ViewService_ServiceDesc is the grpc.ServiceDesc for ViewService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +307,22 @@ var ViewService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetView",
 			Handler:    _ViewService_GetView_Handler,
 		},
+		{
+			MethodName: "Leave",
+			Handler:    _ViewService_Leave_Handler,
+		},
+		{
+			MethodName: "ForcePrimary",
+			Handler:    _ViewService_ForcePrimary_Handler,
+		},
+		{
+			MethodName: "ListServers",
+			Handler:    _ViewService_ListServers_Handler,
+		},
+		{
+			MethodName: "ListViews",
+			Handler:    _ViewService_ListViews_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/viewservice.proto",
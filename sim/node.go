@@ -0,0 +1,279 @@
+package sim
+
+import (
+	"context"
+
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	pb "goDistributedSystemDemo/proto"
+)
+
+// node models a KV server the way kvserver.KVServer behaves: it pings the view
+// service, takes its role from the view, serves clients only in a view it
+// acknowledged, transfers its state to each new backup, queues writes during the
+// transfer and acknowledges a write only once its backup applied it. It is a copy
+// of those rules, not kvserver's code: a change to kvserver's replication must be
+// made here too, or the simulation checks a protocol nothing runs.
+type node struct {
+	w    *world
+	name string
+
+	up          bool
+	incarnation uint64 // bumped on every restart; sent in pings like kvserver's incarnation ID
+	partitioned int    // > 0 while pings to the view service are lost
+	slow        int    // > 0 while pings to the view service are slow
+
+	view          *pb.View
+	viewAcked     bool // the view service got a ping naming view
+	role          string
+	data          map[string]string
+	appliedSeq    uint64 // highest replication sequence applied to data
+	lastBackup    string // backup that accepted the last state transfer
+	lastBackupInc uint64
+	syncedBackup  string
+	syncedInc     uint64
+	syncing       bool
+	backupSince   uint64 // view in which the node last became backup
+	stateView     uint64 // view of the last state transfer received
+	pending       []pendingWrite
+}
+
+// pendingWrite is a write queued during a state transfer, answered through done
+// once it is replicated or the transfer failed
+type pendingWrite struct {
+	req  *pb.ForwardUpdateRequest
+	done func(errCode string)
+}
+
+// start boots the node with empty state, as a restarted process would
+func (n *node) start() {
+	n.up = true
+	n.incarnation++
+	n.view = &pb.View{}
+	n.viewAcked = false
+	n.role = "default"
+	n.data = make(map[string]string)
+	n.appliedSeq = 0
+	n.lastBackup = ""
+	n.lastBackupInc = 0
+	n.syncedBackup = ""
+	n.syncedInc = 0
+	n.syncing = false
+	n.backupSince = 0
+	n.stateView = 0
+	n.pending = nil
+}
+
+// crash stops the node and loses its state
+func (n *node) crash() {
+	n.up = false
+	n.data = nil
+}
+
+// alive reports whether the node is still running the incarnation inc
+func (n *node) alive(inc uint64) bool {
+	return n.up && n.incarnation == inc
+}
+
+// pingLoop pings the view service every kvserver.PingInterval while the node is up
+func (n *node) pingLoop() {
+	defer n.w.after(kvserver.PingInterval, n.pingLoop)
+	n.ping(true)
+}
+
+// ping sends one ping to the view service and adopts the view in the reply. Like
+// kvserver, the node pings again at once after a view change if again is set, to
+// acknowledge the view.
+func (n *node) ping(again bool) {
+	if !n.up || n.partitioned > 0 {
+		return
+	}
+
+	delay := n.w.latency()
+	if n.slow > 0 {
+		delay = kvserver.PingInterval + n.w.jitter(slowLatency)
+	}
+	inc := n.incarnation
+	req := &pb.PingRequest{ServerName: n.name, ViewNumber: n.view.ViewNumber, Incarnation: n.incarnation,
+		BackupSynced: n.role == "primary" && !n.syncing && n.view.Backup != "" &&
+			n.view.Backup == n.syncedBackup && n.view.BackupIncarnation == n.syncedInc,
+		Metadata: &pb.ServerMetadata{AppliedSeq: n.appliedSeq, Keys: uint64(len(n.data)), Willing: true}}
+
+	n.w.after(delay, func() {
+		resp, _ := n.w.vs.Ping(context.Background(), req)
+		n.w.after(n.w.latency(), func() {
+			if !n.alive(inc) {
+				return
+			}
+			n.viewAcked = req.ViewNumber == resp.View.ViewNumber
+			changed := resp.View.ViewNumber != n.view.ViewNumber
+			n.handleView(resp.View)
+			n.syncBackup()
+			if changed && again {
+				n.ping(false)
+			}
+		})
+	})
+}
+
+// handleView adopts a view from a ping reply
+func (n *node) handleView(v *pb.View) {
+	if v.ViewNumber == n.view.ViewNumber {
+		return
+	}
+	n.view = v
+
+	oldRole := n.role
+	switch {
+	case v.Primary == n.name && v.PrimaryIncarnation == n.incarnation:
+		n.role = "primary"
+	case v.Backup == n.name && v.BackupIncarnation == n.incarnation:
+		n.role = "backup"
+	default:
+		n.role = "default"
+	}
+	if n.role == "backup" && oldRole != "backup" {
+		n.backupSince = v.ViewNumber
+	}
+}
+
+// syncBackup starts a state transfer if the node is primary and its backup has
+// not accepted one, which also retries a transfer that failed
+func (n *node) syncBackup() {
+	if n.role != "primary" || n.syncing {
+		return
+	}
+	v := n.view
+	if v.Backup == "" {
+		n.lastBackup = ""
+		n.lastBackupInc = 0
+		return
+	}
+	if v.Backup != n.lastBackup || v.BackupIncarnation != n.lastBackupInc {
+		n.transferState(v)
+	}
+}
+
+// transferState copies the node's data to the backup of view v, then replays the
+// writes that arrived meanwhile. The backup accepts the state only while it is
+// backup of that view.
+func (n *node) transferState(v *pb.View) {
+	n.syncing = true
+	snapshot := make(map[string]string, len(n.data))
+	for k, v := range n.data {
+		snapshot[k] = v
+	}
+	seq := n.appliedSeq
+	backup, backupInc := v.Backup, v.BackupIncarnation
+	n.w.tracef("%s transfers %d keys to %s", n.name, len(snapshot), backup)
+
+	inc := n.incarnation
+	n.w.after(transferTime+n.w.jitter(transferTime), func() {
+		if !n.alive(inc) {
+			return
+		}
+		n.syncing = false
+		pending := n.pending
+		n.pending = nil
+
+		b := n.w.node(backup)
+		if b == nil || !b.alive(backupInc) || b.role != "backup" || b.view.ViewNumber != v.ViewNumber ||
+			b.view.Primary != n.name || b.view.PrimaryIncarnation != n.incarnation {
+			n.w.tracef("%s state transfer to %s failed", n.name, backup)
+			for _, p := range pending {
+				p.done("ErrNotPrimary")
+			}
+			return
+		}
+		b.data = snapshot
+		b.appliedSeq = seq
+		b.stateView = max(b.stateView, v.ViewNumber)
+		n.lastBackup = backup
+		n.lastBackupInc = backupInc
+
+		for _, p := range pending {
+			n.update(p.req, p.done)
+		}
+		n.syncedBackup = backup
+		n.syncedInc = backupInc
+	})
+}
+
+// confirmView reports whether the node's backup, if it has one, is still backup
+// of the node's view, as kvserver confirms before answering a read
+func (n *node) confirmView() bool {
+	if n.view.Backup == "" {
+		return true
+	}
+	b := n.w.node(n.view.Backup)
+	return b != nil && b.alive(n.view.BackupIncarnation) && b.role == "backup" && b.view.ViewNumber == n.view.ViewNumber
+}
+
+// get serves a client read
+func (n *node) get(key string) (string, bool, string) {
+	if n.role != "primary" || !n.viewAcked || !n.confirmView() {
+		return "", false, "ErrNotPrimary"
+	}
+	value, ok := n.data[key]
+	if !ok {
+		return "", false, "ErrNoKey"
+	}
+	return value, true, ""
+}
+
+// put serves a client write; done receives the reply, at once or when a state
+// transfer the write is queued behind completes
+func (n *node) put(key string, value string, done func(errCode string)) {
+	n.update(&pb.ForwardUpdateRequest{Key: key, Value: value}, done)
+}
+
+// update applies a write on the primary once its backup applied it
+func (n *node) update(req *pb.ForwardUpdateRequest, done func(errCode string)) {
+	if n.role != "primary" || !n.viewAcked {
+		done("ErrNotPrimary")
+		return
+	}
+	if n.syncing {
+		n.pending = append(n.pending, pendingWrite{req: req, done: done})
+		return
+	}
+	v := n.view
+	if v.Backup != "" && (v.Backup != n.lastBackup || v.BackupIncarnation != n.lastBackupInc) {
+		done("ErrNotPrimary")
+		return
+	}
+	req.Seq = n.appliedSeq + 1
+	req.ViewNumber = v.ViewNumber
+
+	if v.Backup != "" {
+		b := n.w.node(v.Backup)
+		switch {
+		case b == nil || !b.alive(v.BackupIncarnation):
+			n.resyncBackup()
+			done("ErrNotPrimary")
+			return
+		case b.role != "backup" || b.view.ViewNumber != req.ViewNumber:
+			done("ErrNotPrimary")
+			return
+		case b.stateView < b.backupSince || req.Seq != b.appliedSeq+1:
+			n.resyncBackup()
+			done("ErrNotPrimary")
+			return
+		}
+		b.apply(req)
+	}
+	n.apply(req)
+	done("")
+}
+
+// resyncBackup forgets that the backup holds the state and transfers it again
+func (n *node) resyncBackup() {
+	n.lastBackup = ""
+	n.lastBackupInc = 0
+	n.syncBackup()
+}
+
+// apply writes an update into the node's data
+func (n *node) apply(req *pb.ForwardUpdateRequest) {
+	n.data[req.Key] = req.Value
+	n.appliedSeq = req.Seq
+}
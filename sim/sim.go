@@ -0,0 +1,306 @@
+// Package sim runs the real view service against modelled KV servers and a client on
+// a simulated clock. Every random choice comes from one seed, so a schedule of
+// crashes, restarts, partitions and slow pings that breaks an invariant can be
+// replayed exactly, and thousands of schedules run in seconds.
+//
+// Only the view service is real. The KV servers are node, a model of the
+// replication rules of kvserver.KVServer kept in step with it by hand, and the
+// client is clientLoop, which calls the modelled primary directly rather than going
+// through the client package. Servers reach each other instantly and reliably, and
+// client operations complete at once. A passing run therefore shows that the view
+// service and the protocol as modelled keep every acknowledged write; it says
+// nothing about bugs in the kvserver or client code, which the chaos runs exercise
+// instead.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/kv_server_main/kvserver"
+	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/view/viewservice"
+)
+
+// epoch is the simulated time every run starts at
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Config describes one simulated run
+type Config struct {
+	Seed       uint64        // seed for every random choice in the run
+	Servers    int           // number of KV servers
+	Duration   time.Duration // simulated time to run for
+	FaultEvery time.Duration // mean simulated time between injected faults, 0 for none
+	Keys       int           // size of the client's key space
+	Trace      io.Writer     // receives a line per event if not nil
+}
+
+// Validate checks that the configuration can be run
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.Servers < 1:
+		return fmt.Errorf("sim: at least 1 server is required")
+	case cfg.Duration <= 0:
+		return fmt.Errorf("sim: duration must be positive")
+	case cfg.FaultEvery < 0:
+		return fmt.Errorf("sim: fault interval must not be negative")
+	case cfg.Keys < 1:
+		return fmt.Errorf("sim: key space must hold at least 1 key")
+	}
+	return nil
+}
+
+// Result summarises one run
+type Result struct {
+	Seed        uint64
+	Views       uint64   // number of the last view
+	Acked       int      // writes acknowledged to the client
+	Reads       int      // reads answered by a primary
+	Faults      int      // faults injected
+	Violations  []string // invariant violations, in the order found
+	SimDuration time.Duration
+}
+
+// Failed reports whether any invariant was violated
+func (r Result) Failed() bool {
+	return len(r.Violations) > 0
+}
+
+// Timing of the modelled network and client
+const (
+	clientInterval = 100 * time.Millisecond // mean pause between client operations
+	maxLatency     = 20 * time.Millisecond  // normal one-way message delay
+	slowLatency    = 3 * time.Second        // one-way delay while a node's pings are slow
+	transferTime   = 200 * time.Millisecond // time a state transfer takes
+	maxOutage      = 5 * time.Second        // longest crash, partition or slow period
+)
+
+// world is the state of one run. Everything happens in timer callbacks fired by
+// clk.Advance on the calling goroutine, so no locking is needed.
+type world struct {
+	cfg   Config
+	clk   *clock.Sim
+	rng   *rand.Rand
+	vs    *viewservice.ViewServer
+	nodes []*node
+
+	lastView  *pb.View          // view seen by the previous Tick
+	ackedView *pb.View          // last view with a primary seen acknowledged by it
+	blocked   string            // last reason the view service gave for being blocked
+	acked     map[string]string // last acknowledged value per key
+	seq       int               // counter for unique written values
+	result    Result
+}
+
+// Run simulates cfg.Duration of the system under a seeded fault schedule
+func Run(cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	w := &world{
+		cfg:       cfg,
+		clk:       clock.NewSim(epoch),
+		rng:       rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		lastView:  &pb.View{},
+		ackedView: &pb.View{},
+		acked:     make(map[string]string),
+		result:    Result{Seed: cfg.Seed},
+	}
+	w.vs = viewservice.New(viewservice.WithClock(w.clk))
+
+	for i := 0; i < cfg.Servers; i++ {
+		n := &node{w: w, name: fmt.Sprintf("s%d", i+1)}
+		w.nodes = append(w.nodes, n)
+		n.start()
+		// Spread the first pings so servers do not move in lockstep
+		w.after(w.jitter(kvserver.PingInterval), n.pingLoop)
+	}
+	w.every(viewservice.TickerInterval, w.tick)
+	w.after(w.jitter(2*clientInterval), w.clientLoop)
+	if cfg.FaultEvery > 0 {
+		w.after(w.jitter(2*cfg.FaultEvery), w.faultLoop)
+	}
+
+	w.clk.Advance(cfg.Duration)
+	w.result.Views = w.lastView.ViewNumber
+	w.result.SimDuration = cfg.Duration
+	return w.result, nil
+}
+
+// after runs f once d of simulated time has passed
+func (w *world) after(d time.Duration, f func()) {
+	w.clk.AfterFunc(d, f)
+}
+
+// every runs f each time d of simulated time passes
+func (w *world) every(d time.Duration, f func()) {
+	w.after(d, func() {
+		f()
+		w.every(d, f)
+	})
+}
+
+// jitter returns a random duration in [0, d)
+func (w *world) jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(w.rng.Int64N(int64(d)))
+}
+
+// latency returns a normal one-way message delay
+func (w *world) latency() time.Duration {
+	return w.jitter(maxLatency)
+}
+
+// tracef writes one line of the trace, prefixed with the simulated time
+func (w *world) tracef(format string, args ...any) {
+	if w.cfg.Trace == nil {
+		return
+	}
+	fmt.Fprintf(w.cfg.Trace, "%10s  %s\n", clock.Since(w.clk, epoch).Round(time.Millisecond), fmt.Sprintf(format, args...))
+}
+
+// violate records a broken invariant
+func (w *world) violate(format string, args ...any) {
+	msg := fmt.Sprintf("at %v: ", clock.Since(w.clk, epoch).Round(time.Millisecond)) + fmt.Sprintf(format, args...)
+	w.result.Violations = append(w.result.Violations, msg)
+	w.tracef("VIOLATION %s", fmt.Sprintf(format, args...))
+}
+
+// node returns the node called name, or nil
+func (w *world) node(name string) *node {
+	for _, n := range w.nodes {
+		if n.name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// view asks the view service for the current view
+func (w *world) view() *pb.View {
+	return w.status().View
+}
+
+// status asks the view service for the view and its acknowledgement state
+func (w *world) status() *pb.GetViewResponse {
+	resp, _ := w.vs.GetView(context.Background(), &pb.GetViewRequest{})
+	if resp.PrimaryAcked && resp.View.Primary != "" {
+		w.ackedView = resp.View
+	}
+	return resp
+}
+
+// tick runs the view service's failure detector and checks that the view evolved
+// safely: a new primary must come from the previous view, or be the primary of the
+// last acknowledged view falling back from a stuck one, or data is lost
+func (w *world) tick() {
+	w.status() // notice acks that arrived since the last tick
+	w.vs.Tick()
+	resp := w.status()
+	if resp.Blocked != w.blocked {
+		w.blocked = resp.Blocked
+		w.tracef("blocked: %q", resp.Blocked)
+	}
+
+	v := resp.View
+	old := w.lastView
+	if v.ViewNumber == old.ViewNumber {
+		return
+	}
+	changes, _ := w.vs.ListViews(context.Background(), &pb.ListViewsRequest{AfterView: old.ViewNumber})
+	for _, c := range changes.Changes {
+		cv := c.View
+		w.tracef("view %d: primary=%s/%d backup=%s/%d (%s: %s)", cv.ViewNumber, cv.Primary, cv.PrimaryIncarnation,
+			cv.Backup, cv.BackupIncarnation, c.Reason, c.Detail)
+	}
+	samePrimary := v.Primary == old.Primary && v.PrimaryIncarnation == old.PrimaryIncarnation
+	fromBackup := v.Primary == old.Backup && v.PrimaryIncarnation == old.BackupIncarnation
+	fromAcked := v.Primary == w.ackedView.Primary && v.PrimaryIncarnation == w.ackedView.PrimaryIncarnation
+	if v.Primary != "" && !samePrimary && !fromBackup && !fromAcked && len(w.acked) > 0 {
+		w.violate("view %d made %s (incarnation %d) primary, but it was neither primary nor backup in view %d",
+			v.ViewNumber, v.Primary, v.PrimaryIncarnation, old.ViewNumber)
+	}
+	w.lastView = v
+}
+
+// clientLoop issues one operation to the primary named by the view service, then
+// schedules the next. Operations complete instantly, or a write when the state
+// transfer it waits for completes, so every read must return the last
+// acknowledged write.
+func (w *world) clientLoop() {
+	defer w.after(clientInterval/2+w.jitter(clientInterval), w.clientLoop)
+
+	v := w.view()
+	n := w.node(v.Primary)
+	if n == nil || !n.up {
+		return
+	}
+	key := fmt.Sprintf("k%d", w.rng.IntN(w.cfg.Keys))
+
+	if w.rng.IntN(2) == 0 {
+		w.seq++
+		value := fmt.Sprintf("v%d", w.seq)
+		n.put(key, value, func(errCode string) {
+			if errCode != "" {
+				return
+			}
+			w.acked[key] = value
+			w.result.Acked++
+			w.tracef("client put %s=%s acked by %s", key, value, n.name)
+		})
+		return
+	}
+
+	value, ok, errCode := n.get(key)
+	if errCode != "" && errCode != "ErrNoKey" {
+		return
+	}
+	w.result.Reads++
+	want, wantOK := w.acked[key]
+	if ok != wantOK || value != want {
+		w.violate("get %s from primary %s returned %q, last acknowledged write was %q", key, n.name, value, want)
+	}
+}
+
+// faultLoop injects one random fault, then schedules the next
+func (w *world) faultLoop() {
+	defer w.after(w.cfg.FaultEvery/2+w.jitter(w.cfg.FaultEvery), w.faultLoop)
+
+	n := w.nodes[w.rng.IntN(len(w.nodes))]
+	if !n.up {
+		return
+	}
+	outage := 100*time.Millisecond + w.jitter(maxOutage)
+	w.result.Faults++
+
+	switch w.rng.IntN(3) {
+	case 0:
+		w.tracef("fault: crash %s for %v", n.name, outage)
+		n.crash()
+		w.after(outage, func() {
+			w.tracef("fault: restart %s", n.name)
+			n.start()
+		})
+	case 1:
+		w.tracef("fault: partition %s from the view service for %v", n.name, outage)
+		n.partitioned++
+		w.after(outage, func() {
+			n.partitioned--
+			w.tracef("fault: heal %s", n.name)
+		})
+	case 2:
+		w.tracef("fault: slow pings from %s for %v", n.name, outage)
+		n.slow++
+		w.after(outage, func() {
+			n.slow--
+			w.tracef("fault: %s pings are fast again", n.name)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package sim
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// seeds is the number of schedules each configuration runs
+const seeds = 200
+
+func TestSeeds(t *testing.T) {
+	log.SetOutput(io.Discard) // the view service logs every view change
+
+	configs := []struct {
+		name string
+		cfg  Config
+	}{
+		{"default", Config{Servers: 3, Duration: time.Minute, FaultEvery: 3 * time.Second, Keys: 5}},
+		{"two servers", Config{Servers: 2, Duration: time.Minute, FaultEvery: 3 * time.Second, Keys: 2}},
+		{"frequent faults", Config{Servers: 5, Duration: time.Minute, FaultEvery: time.Second, Keys: 5}},
+		{"no faults", Config{Servers: 3, Duration: 10 * time.Second, Keys: 5}},
+	}
+	for _, c := range configs {
+		t.Run(c.name, func(t *testing.T) {
+			acked := 0
+			for seed := uint64(1); seed <= seeds; seed++ {
+				cfg := c.cfg
+				cfg.Seed = seed
+				r, err := Run(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, v := range r.Violations {
+					t.Errorf("seed %d: %s", seed, v)
+				}
+				acked += r.Acked
+			}
+			if acked == 0 {
+				t.Errorf("no write was acknowledged in %d seeds", seeds)
+			}
+		})
+	}
+}
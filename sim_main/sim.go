@@ -0,0 +1,97 @@
+// Package main runs seeded failure schedules against the view service in simulated time.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"goDistributedSystemDemo/sim"
+)
+
+func main() {
+	seeds := flag.Int("seeds", 1000, "Number of seeds to run, starting at -seed")
+	first := flag.Uint64("seed", 1, "First seed; with -seeds=1 this replays a single seed")
+	parallel := flag.Int("parallel", runtime.GOMAXPROCS(0), "Seeds run at the same time")
+	verbose := flag.Bool("v", false, "Print the event trace and view service logs (only with -seeds=1)")
+	show := flag.Int("show", 10, "Failing seeds to list in detail")
+
+	cfg := sim.Config{}
+	flag.IntVar(&cfg.Servers, "servers", 3, "Number of KV servers")
+	flag.DurationVar(&cfg.Duration, "d", time.Minute, "Simulated time per seed")
+	flag.DurationVar(&cfg.FaultEvery, "faults", 3*time.Second, "Mean simulated time between faults (0 disables faults)")
+	flag.IntVar(&cfg.Keys, "keys", 5, "Size of the client's key space")
+	flag.Parse()
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *seeds < 1 || *parallel < 1 {
+		fmt.Println("sim: -seeds and -parallel must be at least 1")
+		os.Exit(2)
+	}
+	if *verbose && *seeds == 1 {
+		cfg.Trace = os.Stdout
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	start := time.Now()
+	results := make([]sim.Result, *seeds)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < *parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				c := cfg
+				c.Seed = *first + uint64(idx)
+				results[idx], _ = sim.Run(c)
+			}
+		}()
+	}
+	for idx := 0; idx < *seeds; idx++ {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+
+	var failed []sim.Result
+	acked, faults := 0, 0
+	for _, r := range results {
+		acked += r.Acked
+		faults += r.Faults
+		if r.Failed() {
+			failed = append(failed, r)
+		}
+	}
+
+	fmt.Printf("Ran %d seeds (%v simulated each) in %v: %d acked writes, %d faults\n",
+		*seeds, cfg.Duration, time.Since(start).Round(time.Millisecond), acked, faults)
+	if len(failed) == 0 {
+		fmt.Printf("All seeds passed\n")
+		return
+	}
+
+	fmt.Printf("%d of %d seeds violated an invariant\n", len(failed), *seeds)
+	for i, r := range failed {
+		if i == *show {
+			fmt.Printf("... and %d more\n", len(failed)-*show)
+			break
+		}
+		fmt.Printf("seed %d: %s", r.Seed, r.Violations[0])
+		if len(r.Violations) > 1 {
+			fmt.Printf(" (+%d more)", len(r.Violations)-1)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("Replay one with: sim -seeds=1 -seed=<seed> -v\n")
+	os.Exit(1)
+}
@@ -0,0 +1,247 @@
+// Package tlsconfig sets up TLS for the gRPC connections between clients, KV
+// servers and the view service. Certificates are read from PEM files and read
+// again when the files change, so they can be rotated without a restart.
+//
+// Servers ask every caller for a certificate but accept calls without one;
+// methods that only servers may call are restricted with RequireServerCert, so
+// servers talk to each other over mutual TLS while clients need only trust the CA.
+// A server's certificate allows server authentication and a client's must not,
+// which is how a server tells the two apart when no auth policy names them.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// reloadInterval is how often the files are checked for changes, at most
+const reloadInterval = time.Second
+
+// Config names the PEM files a binary uses for TLS
+type Config struct {
+	CertFile string // certificate presented to peers, with its chain
+	KeyFile  string // private key of CertFile
+	CAFile   string // CAs trusted to sign peer certificates; the system roots if empty
+}
+
+// Enabled reports whether TLS is configured at all
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// Validate checks the config for values that cannot work
+func (c Config) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("a TLS certificate and its key must be given together")
+	}
+	return nil
+}
+
+// Certs holds the certificate and CA pool loaded from a Config, reloading them
+// when the files change. A failed reload is logged and the old ones kept.
+type Certs struct {
+	cfg Config
+
+	mu      sync.Mutex
+	cert    *tls.Certificate // nil without CertFile
+	pool    *x509.CertPool   // nil without CAFile, meaning the system roots
+	modTime time.Time        // latest modification time of the files when loaded
+	checked time.Time        // when the files were last checked for changes
+}
+
+// Load reads the files named by cfg
+func Load(cfg Config) (*Certs, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Certs{cfg: cfg}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.checked = time.Now()
+	return c, nil
+}
+
+// HasCert reports whether a certificate was configured to present to peers
+func (c *Certs) HasCert() bool {
+	return c.cfg.CertFile != ""
+}
+
+// load reads the files; c.mu must be held or c not yet shared
+func (c *Certs) load() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	var cert *tls.Certificate
+	if c.cfg.CertFile != "" {
+		kp, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("cannot load TLS certificate: %w", err)
+		}
+		cert = &kp
+	}
+	var pool *x509.CertPool
+	if c.cfg.CAFile != "" {
+		pem, err := os.ReadFile(c.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read TLS CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in TLS CA file %s", c.cfg.CAFile)
+		}
+	}
+	c.cert, c.pool, c.modTime = cert, pool, modTime
+	return nil
+}
+
+// latestModTime returns the latest modification time of the configured files
+func (c *Certs) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.cfg.CertFile, c.cfg.KeyFile, c.cfg.CAFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// current returns the certificate and CA pool, first reloading them if the files
+// changed since they were last checked
+func (c *Certs) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) >= reloadInterval {
+		c.checked = time.Now()
+		if modTime, err := c.latestModTime(); err != nil || !modTime.Equal(c.modTime) {
+			if err == nil {
+				err = c.load()
+			}
+			if err != nil {
+				slog.Warn("TLS certificate reload failed, keeping the old ones", "err", err)
+			} else {
+				slog.Info("TLS certificates reloaded", "cert", c.cfg.CertFile, "ca", c.cfg.CAFile)
+			}
+		}
+	}
+	return c.cert, c.pool
+}
+
+// ServerCredentials returns credentials for a gRPC server. Callers are asked for
+// a certificate signed by the CA but may connect without one.
+func (c *Certs) ServerCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := c.current()
+			if cert == nil {
+				return nil, errors.New("no TLS certificate configured")
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	})
+}
+
+// ClientCredentials returns credentials for dialing a gRPC server. The server's
+// certificate must be signed by the CA and name the host dialed; the client's own
+// certificate, if configured, is presented when the server asks for one.
+func (c *Certs) ClientCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert, _ := c.current(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		// The CA pool may be reloaded, so the server is verified in VerifyConnection
+		// against the current one rather than against a pool fixed here
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool := c.current()
+			return verifyServer(cs, pool)
+		},
+	})
+}
+
+// verifyServer does the verification InsecureSkipVerify turned off: the chain
+// must lead to a root in pool and the leaf must name the server dialed
+func verifyServer(cs tls.ConnectionState, pool *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		DNSName:       cs.ServerName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
+
+// PeerCert returns the verified certificate the caller presented, nil if it
+// presented none or the connection is not TLS
+func PeerCert(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// RequireServerCert refuses calls to the given full method names, such as
+// pb.Replication_SyncState_FullMethodName, unless the caller presented a verified
+// certificate issued to a server: one whose extended key usage names server
+// authentication. Certificates issued to clients are refused.
+func RequireServerCert(methods ...string) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		cert := PeerCert(ctx)
+		if cert == nil {
+			return nil, status.Errorf(codes.Unauthenticated, "%s requires a server certificate", info.FullMethod)
+		}
+		if !slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s may only be called by servers, %q has no server certificate",
+				info.FullMethod, cert.Subject.CommonName)
+		}
+		return handler(ctx, req)
+	})
+}
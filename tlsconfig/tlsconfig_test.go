@@ -0,0 +1,249 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// testCA is a self-signed CA that issues certificates for the tests
+type testCA struct {
+	t    *testing.T
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string // the CA certificate as PEM
+}
+
+// newCA creates a CA named name with its files in a temporary directory
+func newCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{t: t, dir: t.TempDir(), cert: cert, key: key}
+	ca.file = ca.write(name+".pem", "CERTIFICATE", der)
+	return ca
+}
+
+// write stores der as a PEM block of type kind and returns the file name
+func (ca *testCA) write(name string, kind string, der []byte) string {
+	ca.t.Helper()
+	file := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		ca.t.Fatal(err)
+	}
+	return file
+}
+
+// issue creates a certificate for 127.0.0.1 named cn with the given extended key
+// usages and returns a Config presenting it and trusting ca
+func (ca *testCA) issue(cn string, usages ...x509.ExtKeyUsage) Config {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return Config{
+		CertFile: ca.write(cn+".pem", "CERTIFICATE", der),
+		KeyFile:  ca.write(cn+"-key.pem", "PRIVATE KEY", keyDER),
+		CAFile:   ca.file,
+	}
+}
+
+// server is a certificate for a KV server, allowed to serve and to call peers
+func (ca *testCA) server(cn string) Config {
+	return ca.issue(cn, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)
+}
+
+func TestLoad(t *testing.T) {
+	ca := newCA(t, "ca")
+	server := ca.server("kv1")
+	other := ca.server("kv2")
+	garbage := filepath.Join(t.TempDir(), "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		hasCert bool
+		wantErr bool
+	}{
+		{"certificate and CA", server, true, false},
+		{"CA only", Config{CAFile: ca.file}, false, false},
+		{"certificate without key", Config{CertFile: server.CertFile, CAFile: ca.file}, false, true},
+		{"key without certificate", Config{KeyFile: server.KeyFile}, false, true},
+		{"missing certificate", Config{CertFile: "missing.pem", KeyFile: server.KeyFile}, false, true},
+		{"key of another certificate", Config{CertFile: server.CertFile, KeyFile: other.KeyFile}, false, true},
+		{"CA file without certificates", Config{CAFile: garbage}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, err := Load(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && certs.HasCert() != tt.hasCert {
+				t.Errorf("HasCert() = %v, want %v", certs.HasCert(), tt.hasCert)
+			}
+		})
+	}
+}
+
+// TestReload replaces the certificate files of loaded Certs: a valid replacement
+// is used from then on, and an invalid one is ignored
+func TestReload(t *testing.T) {
+	ca := newCA(t, "ca")
+	cfg := ca.server("kv1")
+	certs, err := Load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replace copies src over dst, makes dst newer than when last loaded and has
+	// the next use of certs check the files
+	modTime := time.Now()
+	replace := func(dst string, src string) {
+		t.Helper()
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Minute)
+		if err := os.Chtimes(dst, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		certs.mu.Lock()
+		certs.checked = time.Time{} // check now rather than after reloadInterval
+		certs.mu.Unlock()
+	}
+	commonName := func() string {
+		cert, _ := certs.current()
+		return cert.Leaf.Subject.CommonName
+	}
+
+	rotated := ca.server("kv1-rotated")
+	replace(cfg.KeyFile, rotated.KeyFile)
+	replace(cfg.CertFile, rotated.CertFile)
+	if cn := commonName(); cn != "kv1-rotated" {
+		t.Fatalf("certificate %q after rotation, want kv1-rotated", cn)
+	}
+
+	garbage := filepath.Join(t.TempDir(), "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	replace(cfg.CertFile, garbage)
+	if cn := commonName(); cn != "kv1-rotated" {
+		t.Fatalf("certificate %q after a failed reload, want the previous one kept", cn)
+	}
+}
+
+// TestRequireServerCert calls a method restricted to servers over mutual TLS with
+// each kind of caller certificate
+func TestRequireServerCert(t *testing.T) {
+	ca := newCA(t, "ca")
+	serverCerts, err := Load(ca.server("kv1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(serverCerts.ServerCredentials()), RequireServerCert(healthpb.Health_Check_FullMethodName))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	otherCA := newCA(t, "other-ca")
+	foreign := otherCA.server("kv3")
+	foreign.CAFile = ca.file
+	tests := []struct {
+		name string
+		cfg  Config
+		want codes.Code
+	}{
+		{"server certificate", ca.server("kv2"), codes.OK},
+		{"client certificate", ca.issue("app", x509.ExtKeyUsageClientAuth), codes.PermissionDenied},
+		{"no certificate", Config{CAFile: ca.file}, codes.Unauthenticated},
+		{"server certificate of another CA", foreign, codes.Unavailable},
+		{"server not trusted by the caller", Config{CAFile: otherCA.file}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, err := Load(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(certs.ClientCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if status.Code(err) != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package tracing records OpenTelemetry traces of the gRPC calls between the
+// client, the KV servers and the view service, and exports them to a file or an
+// OTLP collector. Components take a trace.TracerProvider as an option and fall
+// back to the global one, which records nothing unless a binary installs one.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// propagator carries the trace context in gRPC metadata as W3C traceparent headers
+var propagator = propagation.TraceContext{}
+
+// Config selects where a binary's traces go
+type Config struct {
+	Service      string  // service.name of the spans, such as "kvserver"
+	Instance     string  // service.instance.id, such as the server address
+	File         string  // append spans to this file as JSON lines if set
+	OTLPEndpoint string  // send spans to this OTLP/gRPC collector (host:port, plaintext) if set
+	SampleRatio  float64 // fraction of new traces recorded; calls that join a trace follow the caller
+}
+
+// Enabled reports whether traces are exported anywhere
+func (c Config) Enabled() bool {
+	return c.File != "" || c.OTLPEndpoint != ""
+}
+
+// Validate checks the config for values that cannot work
+func (c Config) Validate() error {
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	return nil
+}
+
+// New returns a tracer provider exporting as cfg says, and a function that
+// flushes the remaining spans and closes the exporters
+func New(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, func(context.Context) error, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(cfg.Service),
+			semconv.ServiceInstanceID(cfg.Instance))),
+	}
+
+	var file *os.File
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		file = f
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	if cfg.OTLPEndpoint != "" {
+		exp, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint), otlptracegrpc.WithInsecure())
+		if err != nil {
+			if file != nil {
+				file.Close()
+			}
+			return nil, nil, fmt.Errorf("cannot create OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	shutdown := func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}
+	return tp, shutdown, nil
+}
+
+// flushTimeout bounds how long Flush waits for the exporters
+const flushTimeout = 5 * time.Second
+
+// Flush calls the shutdown function returned by New, so the spans not exported
+// yet are sent before a binary exits. It gives up after a few seconds or when ctx
+// ends, logging why.
+func Flush(ctx context.Context, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Warn("Flushing traces failed", "err", err)
+	}
+}
+
+// options instruments gRPC with tp. Pings and health checks are left out: every
+// server sends a ping twice a second and load balancers check health as often,
+// which would bury the calls worth looking at.
+func options(tp trace.TracerProvider) []otelgrpc.Option {
+	return []otelgrpc.Option{
+		otelgrpc.WithTracerProvider(tp),
+		otelgrpc.WithPropagators(propagator),
+		otelgrpc.WithFilter(filters.Not(filters.Any(
+			filters.MethodName("Ping"), filters.ServiceName(healthgrpc.Health_ServiceDesc.ServiceName)))),
+	}
+}
+
+// ServerOption records a span for every call a gRPC server handles, continuing
+// the caller's trace
+func ServerOption(tp trace.TracerProvider) grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler(options(tp)...))
+}
+
+// DialOption records a span for every call made on a connection and passes the
+// trace on to the server
+func DialOption(tp trace.TracerProvider) grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(options(tp)...))
+}
+
+// Extract returns ctx joined to the trace carried by the W3C traceparent header
+// of an HTTP request, for front ends that forward HTTP requests over gRPC
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// End records err, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RequestID is the span attribute holding the request ID that also appears in the logs
+func RequestID(id string) attribute.KeyValue {
+	return attribute.String("request_id", id)
+}
@@ -1,29 +1,156 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/faults"
+	"goDistributedSystemDemo/logging"
+	"goDistributedSystemDemo/metrics"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
 	"goDistributedSystemDemo/view/viewservice"
 )
 
 func main() {
 	address := flag.String("addr", "localhost:8000", "View service address (host:port)")
+	faultsAdmin := flag.String("faults-admin", "", "Address of the fault injection admin endpoint (disabled if empty)")
+	faultsSeed := flag.Uint64("faults-seed", 1, "Seed for probabilistic fault rules")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics (disabled if empty)")
+	preferred := flag.String("preferred", "", "Comma-separated servers to make primary first, in order; the first fails back when it returns")
+	exclude := flag.String("exclude", "", "Comma-separated servers never to give a role; an excluded primary hands over to its backup")
+	zoneAware := flag.Bool("zone-aware", false, "Never place the backup in the same zone as the primary")
+	historyFile := flag.String("history-file", "", "Append every view change to this file as a line of JSON (disabled if empty)")
+	logLevel := flag.String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	traceFile := flag.String("trace-file", "", "Append trace spans to this file as JSON lines (disabled if empty)")
+	traceOTLP := flag.String("trace-otlp", "", "Send trace spans to this OTLP/gRPC collector, host:port without TLS (disabled if empty)")
+	traceSample := flag.Float64("trace-sample", 1, "Fraction of new traces recorded (0-1)")
+	tlsCert := flag.String("tls-cert", "", "Certificate to serve TLS with and present to other servers (PEM, TLS disabled if empty)")
+	tlsKey := flag.String("tls-key", "", "Private key of -tls-cert (PEM)")
+	tlsCA := flag.String("tls-ca", "", "CA certificates that sign the certificates of servers and clients (PEM, system roots if empty)")
+	authPolicy := flag.String("auth-policy", "", "JSON file of the principals allowed to call each RPC (every call allowed if empty)")
+	auditLog := flag.String("audit-log", "", "Append denied calls and admin actions to this file as JSON lines (to the log if empty)")
 	flag.Parse()
 
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
 	fmt.Printf("Starting View Service on %s\n", *address)
 	pid := os.Getpid()
 	fmt.Printf("PID: %d\n", pid)
-	vs := viewservice.StartServer(*address)
+
+	opts := make([]viewservice.Option, 0)
+	if *faultsAdmin != "" {
+		inj := faults.New(*faultsSeed)
+		opts = append(opts, viewservice.WithServerOptions(inj.ServerOption(*address)))
+		inj.ListenAndServe(*faultsAdmin)
+	}
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		opts = append(opts, viewservice.WithMetrics(reg))
+		metrics.ListenAndServe(*metricsAddr, reg)
+	}
+
+	tlsCfg := tlsconfig.Config{CertFile: *tlsCert, KeyFile: *tlsKey, CAFile: *tlsCA}
+	if tlsCfg.Enabled() {
+		if tlsCfg.CertFile == "" {
+			fmt.Fprintln(os.Stderr, "TLS needs -tls-cert and -tls-key to serve with")
+			os.Exit(2)
+		}
+		certs, err := tlsconfig.Load(tlsCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, viewservice.WithTLS(certs))
+	}
+
+	if *authPolicy != "" {
+		policy, err := auth.LoadPolicy(*authPolicy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		audit, err := auth.AuditLogger(*auditLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		opts = append(opts, viewservice.WithAuthorizer(auth.New(policy, audit)))
+	}
+
+	traceCfg := tracing.Config{Service: "viewservice", Instance: *address, File: *traceFile, OTLPEndpoint: *traceOTLP, SampleRatio: *traceSample}
+	if traceCfg.Enabled() {
+		tp, shutdown, err := tracing.New(context.Background(), traceCfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer tracing.Flush(context.Background(), shutdown)
+		opts = append(opts, viewservice.WithTracerProvider(tp))
+	}
+
+	var policy viewservice.Policy = viewservice.FIFO{}
+	if *exclude != "" {
+		policy = viewservice.Exclude(splitList(*exclude), policy)
+	}
+	if *preferred != "" {
+		policy = viewservice.Preferred(splitList(*preferred), policy)
+	}
+	if *zoneAware {
+		policy = viewservice.ZoneAware(policy)
+	}
+	opts = append(opts, viewservice.WithPolicy(policy))
+
+	if *historyFile != "" {
+		f, err := os.OpenFile(*historyFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open history file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		opts = append(opts, viewservice.WithHistoryExport(f))
+	}
+
+	vs, err := viewservice.StartServer(*address, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	select {
+	case <-sigChan:
+	case err := <-vs.Failed():
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	fmt.Println("\nShutting down View Service...")
 	vs.Kill()
 }
+
+// splitList splits a comma-separated list, dropping blanks
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(part); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
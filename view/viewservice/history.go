@@ -0,0 +1,99 @@
+package viewservice
+
+import (
+	"context"
+	"io"
+
+	pb "goDistributedSystemDemo/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reasons recorded with each view change
+const (
+	ReasonPrimaryDied     = "primary_died"     // the primary died or restarted and there was no backup
+	ReasonBackupDied      = "backup_died"      // the backup died and was dropped
+	ReasonBackupRestarted = "backup_restarted" // the backup restarted and needs the state again
+	ReasonBackupPromoted  = "backup_promoted"  // the primary died or restarted and the backup took over
+	ReasonServerAssigned  = "server_assigned"  // an idle server became primary or backup
+	ReasonServerLeft      = "server_left"      // the primary or backup left gracefully
+	ReasonFailback        = "failback"         // the placement policy swapped primary and backup
+	ReasonRecovered       = "recovered"        // a stuck view fell back to the last acknowledged primary
+	ReasonAdmin           = "admin"            // an operator forced a primary
+)
+
+// historyLimit is the number of view changes kept in memory for ListViews
+const historyLimit = 1000
+
+// nextView moves to the next view number and records the change to the view just
+// built in vs.currentView, filling in where its backup accepts replication. The
+// new view is unacknowledged until its primary pings with it, so the view cannot
+// change again before the primary knows of it and its backup; vs.mu must be held
+func (vs *ViewServer) nextView(reason string, detail string) {
+	vs.currentView.ViewNumber++
+	vs.primaryAcked = vs.currentView.Primary == ""
+	vs.currentView.BackupPeerAddress = vs.peerAddress(vs.currentView.Backup)
+	change := &pb.ViewChange{
+		View:         proto.Clone(vs.currentView).(*pb.View),
+		Reason:       reason,
+		Detail:       detail,
+		TimeUnixNano: vs.clock.Now().UnixNano(),
+		Servers:      vs.serverStatuses(),
+	}
+	vs.metrics.viewChanges.WithLabelValues(reason).Inc()
+	_, span := vs.tracer.Start(context.Background(), "viewservice.ViewChange", trace.WithAttributes(
+		attribute.Int64("view", int64(change.View.ViewNumber)),
+		attribute.String("primary", change.View.Primary),
+		attribute.String("backup", change.View.Backup),
+		attribute.String("reason", reason),
+		attribute.String("detail", detail)))
+	span.End()
+	vs.history = append(vs.history, change)
+	if len(vs.history) > historyLimit {
+		vs.history = vs.history[len(vs.history)-historyLimit:]
+	}
+	if vs.historyOut != nil {
+		if err := writeChange(vs.historyOut, change); err != nil {
+			vs.logger.Warn("Failed to export view change", "view", change.View.ViewNumber, "err", err)
+		}
+	}
+}
+
+// peerAddress returns the replication address the server name reported in its
+// last ping, "" if it reported none and takes replication on its own address
+func (vs *ViewServer) peerAddress(name string) string {
+	if server, ok := vs.servers[name]; ok && server.Metadata != nil {
+		return server.Metadata.PeerAddress
+	}
+	return ""
+}
+
+// writeChange appends change to w as one line of JSON
+func writeChange(w io.Writer, change *pb.ViewChange) error {
+	line, err := protojson.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// ListViews RPC handler - returns the recorded view changes, oldest first
+func (vs *ViewServer) ListViews(ctx context.Context, req *pb.ListViewsRequest) (*pb.ListViewsResponse, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	changes := make([]*pb.ViewChange, 0)
+	for _, change := range vs.history {
+		if change.View.ViewNumber > req.AfterView {
+			changes = append(changes, proto.Clone(change).(*pb.ViewChange))
+		}
+	}
+	if req.Limit > 0 && len(changes) > int(req.Limit) {
+		changes = changes[len(changes)-int(req.Limit):]
+	}
+	return &pb.ListViewsResponse{Changes: changes}, nil
+}
@@ -0,0 +1,115 @@
+package viewservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TestListViews records a primary, a backup and the backup's death, and reads
+// the changes back whole, after a view and limited to the latest
+func TestListViews(t *testing.T) {
+	var export bytes.Buffer
+	f := newFixture(t, WithHistoryExport(&export))
+	pair(f)
+	f.stop("b")
+	f.expire()
+	f.wantView(3, "p", "")
+
+	want := []struct {
+		view   uint64
+		reason string
+	}{
+		{1, ReasonServerAssigned},
+		{2, ReasonServerAssigned},
+		{3, ReasonBackupDied},
+	}
+	tests := []struct {
+		name      string
+		afterView uint64
+		limit     uint32
+		first     int // index in want of the first change expected
+	}{
+		{"all", 0, 0, 0},
+		{"after view 1", 1, 0, 1},
+		{"latest only", 0, 1, 2},
+		{"limit above the number kept", 0, 10, 0},
+		{"after the current view", 3, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := f.vs.ListViews(context.Background(), &pb.ListViewsRequest{AfterView: tt.afterView, Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Changes) != len(want)-tt.first {
+				t.Fatalf("got %d changes, want %d", len(resp.Changes), len(want)-tt.first)
+			}
+			for i, change := range resp.Changes {
+				w := want[tt.first+i]
+				if change.View.ViewNumber != w.view || change.Reason != w.reason {
+					t.Errorf("change %d is view %d for %q, want view %d for %q",
+						i, change.View.ViewNumber, change.Reason, w.view, w.reason)
+				}
+			}
+		})
+	}
+
+	// The last change records b as dead at the moment it was dropped
+	resp, err := f.vs.ListViews(context.Background(), &pb.ListViewsRequest{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := resp.Changes[0]
+	if last.Detail != "backup b is dead" || last.TimeUnixNano != f.clk.Now().UnixNano() {
+		t.Errorf("last change %q at %d, want %q at %d", last.Detail, last.TimeUnixNano, "backup b is dead", f.clk.Now().UnixNano())
+	}
+	for _, s := range last.Servers {
+		if s.Name == "b" && s.Alive {
+			t.Errorf("last change records b alive")
+		}
+	}
+
+	// Every change was exported as a line of JSON
+	scanner := bufio.NewScanner(&export)
+	n := 0
+	for ; scanner.Scan(); n++ {
+		var change pb.ViewChange
+		if err := protojson.Unmarshal(scanner.Bytes(), &change); err != nil {
+			t.Fatalf("exported line %d: %v", n+1, err)
+		}
+		if n < len(want) && change.View.ViewNumber != want[n].view {
+			t.Errorf("exported line %d is view %d, want %d", n+1, change.View.ViewNumber, want[n].view)
+		}
+	}
+	if n != len(want) {
+		t.Errorf("exported %d changes, want %d", n, len(want))
+	}
+}
+
+// TestHistoryLimit keeps only the most recent historyLimit changes
+func TestHistoryLimit(t *testing.T) {
+	f := newFixture(t)
+	f.join("p", "")
+	f.tick()
+	for range historyLimit + 10 {
+		f.vs.mu.Lock()
+		f.vs.nextView(ReasonAdmin, "test")
+		f.vs.mu.Unlock()
+	}
+	resp, err := f.vs.ListViews(context.Background(), &pb.ListViewsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Changes) != historyLimit {
+		t.Fatalf("kept %d changes, want %d", len(resp.Changes), historyLimit)
+	}
+	if first := resp.Changes[0].View.ViewNumber; first != 12 {
+		t.Errorf("oldest change kept is view %d, want 12", first)
+	}
+}
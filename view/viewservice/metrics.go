@@ -0,0 +1,83 @@
+package viewservice
+
+import (
+	"goDistributedSystemDemo/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serverMetrics are the events the view service counts; its state is read at
+// scrape time by stateCollector
+type serverMetrics struct {
+	rpc         *metrics.RPC
+	viewChanges *prometheus.CounterVec
+}
+
+// newServerMetrics creates the view service's metrics and registers them with reg
+func newServerMetrics(vs *ViewServer, reg prometheus.Registerer) *serverMetrics {
+	m := &serverMetrics{
+		rpc: metrics.NewRPC("viewservice", reg),
+		viewChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "viewservice",
+			Name:      "view_changes_total",
+			Help:      "View changes, by reason.",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(m.viewChanges, stateCollector{vs})
+	return m
+}
+
+var (
+	viewNumberDesc   = prometheus.NewDesc("viewservice_view_number", "Number of the current view.", nil, nil)
+	primaryAckedDesc = prometheus.NewDesc("viewservice_primary_acked", "1 if the primary acknowledged the current view.", nil, nil)
+	blockedDesc      = prometheus.NewDesc("viewservice_blocked", "1 if the view service cannot make progress.", nil, nil)
+	dataLossDesc     = prometheus.NewDesc("viewservice_data_loss_risk", "1 if no live server is known to hold every acknowledged write.", nil, nil)
+	primarySeqDesc   = prometheus.NewDesc("viewservice_primary_seq", "Highest replication sequence a primary reported applying.", nil, nil)
+	serversDesc      = prometheus.NewDesc("viewservice_servers", "Known servers, by state.", []string{"state"}, nil)
+)
+
+// stateCollector reports the view service's state when metrics are scraped
+type stateCollector struct {
+	vs *ViewServer
+}
+
+func (c stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{viewNumberDesc, primaryAckedDesc, blockedDesc, dataLossDesc, primarySeqDesc, serversDesc} {
+		ch <- d
+	}
+}
+
+func (c stateCollector) Collect(ch chan<- prometheus.Metric) {
+	vs := c.vs
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(viewNumberDesc, prometheus.GaugeValue, float64(vs.currentView.ViewNumber))
+	ch <- prometheus.MustNewConstMetric(primaryAckedDesc, prometheus.GaugeValue, boolValue(vs.primaryAcked))
+	ch <- prometheus.MustNewConstMetric(blockedDesc, prometheus.GaugeValue, boolValue(vs.blocked != ""))
+	ch <- prometheus.MustNewConstMetric(dataLossDesc, prometheus.GaugeValue, boolValue(vs.dataLossRisk))
+	ch <- prometheus.MustNewConstMetric(primarySeqDesc, prometheus.GaugeValue, float64(vs.primarySeq))
+
+	counts := map[string]int{"alive": 0, "dead": 0, "leaving": 0}
+	for _, server := range vs.servers {
+		switch {
+		case !server.Alive:
+			counts["dead"]++
+		case server.Leaving:
+			counts["leaving"]++
+		default:
+			counts["alive"]++
+		}
+	}
+	for state, n := range counts {
+		ch <- prometheus.MustNewConstMetric(serversDesc, prometheus.GaugeValue, float64(n), state)
+	}
+}
+
+// boolValue converts a flag to a gauge value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
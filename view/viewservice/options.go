@@ -0,0 +1,94 @@
+package viewservice
+
+import (
+	"io"
+	"log/slog"
+	"net"
+
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/tlsconfig"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Option configures a ViewServer
+type Option func(*ViewServer)
+
+// WithServerOptions adds gRPC server options, such as interceptors, to the server
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(vs *ViewServer) {
+		vs.serverOptions = append(vs.serverOptions, opts...)
+	}
+}
+
+// WithListener serves on lis instead of listening on the address passed to
+// StartServer, so a caller that picked the port keeps it until the service owns
+// it. The service closes lis when it is killed.
+func WithListener(lis net.Listener) Option {
+	return func(vs *ViewServer) {
+		vs.listener = lis
+	}
+}
+
+// WithClock makes the server measure liveness on c instead of the wall clock
+func WithClock(c clock.Clock) Option {
+	return func(vs *ViewServer) {
+		vs.clock = c
+	}
+}
+
+// WithPolicy sets the policy that chooses primaries and backups; the default is FIFO
+func WithPolicy(p Policy) Option {
+	return func(vs *ViewServer) {
+		vs.policy = p
+	}
+}
+
+// WithHistoryExport writes every view change to w as a line of JSON, for review
+// after an incident
+func WithHistoryExport(w io.Writer) Option {
+	return func(vs *ViewServer) {
+		vs.historyOut = w
+	}
+}
+
+// WithMetrics registers the service's metrics with reg, to be served on /metrics
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(vs *ViewServer) {
+		vs.registerer = reg
+	}
+}
+
+// WithLogger makes the service log to l instead of the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *ViewServer) {
+		vs.logger = l
+	}
+}
+
+// WithTracerProvider makes the service record spans with tp instead of the global
+// tracer provider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(vs *ViewServer) {
+		vs.traces = tp
+	}
+}
+
+// WithTLS makes the service serve over TLS with the certificates in certs. Only
+// callers presenting a certificate signed by the CA may ping or leave.
+func WithTLS(certs *tlsconfig.Certs) Option {
+	return func(vs *ViewServer) {
+		vs.tls = certs
+	}
+}
+
+// WithAuthorizer makes the service refuse calls a that does not allow. It replaces
+// the client certificate check of WithTLS.
+func WithAuthorizer(a *auth.Authorizer) Option {
+	return func(vs *ViewServer) {
+		vs.authz = a
+	}
+}
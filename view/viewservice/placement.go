@@ -0,0 +1,172 @@
+package viewservice
+
+import (
+	"slices"
+)
+
+// Candidate is a live server that may be given a role
+type Candidate struct {
+	Name       string
+	Zone       string            // failure domain reported in pings, empty if unknown
+	Version    string            // build version from the server's metadata
+	Labels     map[string]string // operator-defined labels from the server's metadata
+	AppliedSeq uint64            // last replication sequence the server reported applying
+}
+
+// Policy decides which servers become primary and backup. Candidates are always
+// passed in join order, earliest first, and never include dead, leaving,
+// unwilling or already assigned servers.
+type Policy interface {
+	// Primary picks the primary of a view that has none, "" for no choice
+	Primary(candidates []Candidate) string
+	// Backup picks the backup for primary, "" for no choice
+	Backup(primary Candidate, candidates []Candidate) string
+	// Failback reports whether a synced backup should take over from a healthy
+	// primary, which then becomes its backup
+	Failback(primary Candidate, backup Candidate) bool
+}
+
+// FIFO gives roles in join order and never fails back. It is the default policy.
+type FIFO struct{}
+
+// Primary picks the earliest joined candidate
+func (FIFO) Primary(candidates []Candidate) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0].Name
+}
+
+// Backup picks the earliest joined candidate
+func (FIFO) Backup(primary Candidate, candidates []Candidate) string {
+	return FIFO{}.Primary(candidates)
+}
+
+// Failback is always false: a primary keeps its role until it fails or leaves
+func (FIFO) Failback(primary Candidate, backup Candidate) bool {
+	return false
+}
+
+// Preferred ranks the named servers first, in the given order, and leaves the
+// choice among the rest to next. When a server ranked above the primary is its
+// synced backup, the roles are swapped so the preferred primary takes over again.
+func Preferred(names []string, next Policy) Policy {
+	return preferred{names: names, next: next}
+}
+
+type preferred struct {
+	names []string
+	next  Policy
+}
+
+// rank returns the position of name in the preference list, len(names) if absent
+func (p preferred) rank(name string) int {
+	if i := slices.Index(p.names, name); i >= 0 {
+		return i
+	}
+	return len(p.names)
+}
+
+// order sorts candidates by preference, keeping join order among equals
+func (p preferred) order(candidates []Candidate) []Candidate {
+	ordered := slices.Clone(candidates)
+	slices.SortStableFunc(ordered, func(a, b Candidate) int {
+		return p.rank(a.Name) - p.rank(b.Name)
+	})
+	return ordered
+}
+
+// Primary lets next pick among the candidates in order of preference
+func (p preferred) Primary(candidates []Candidate) string {
+	return p.next.Primary(p.order(candidates))
+}
+
+// Backup lets next pick among the candidates in order of preference
+func (p preferred) Backup(primary Candidate, candidates []Candidate) string {
+	return p.next.Backup(primary, p.order(candidates))
+}
+
+// Failback swaps the roles when the backup is ranked above the primary, or when
+// next would
+func (p preferred) Failback(primary Candidate, backup Candidate) bool {
+	return p.rank(backup.Name) < p.rank(primary.Name) || p.next.Failback(primary, backup)
+}
+
+// Exclude never gives a role to the named servers. An excluded primary hands
+// over to its synced backup, so servers can be drained by excluding them.
+func Exclude(names []string, next Policy) Policy {
+	return exclude{names: names, next: next}
+}
+
+type exclude struct {
+	names []string
+	next  Policy
+}
+
+// allowed returns the candidates that are not excluded
+func (e exclude) allowed(candidates []Candidate) []Candidate {
+	return slices.DeleteFunc(slices.Clone(candidates), func(c Candidate) bool {
+		return slices.Contains(e.names, c.Name)
+	})
+}
+
+// Primary lets next pick among the candidates that are not excluded
+func (e exclude) Primary(candidates []Candidate) string {
+	return e.next.Primary(e.allowed(candidates))
+}
+
+// Backup lets next pick among the candidates that are not excluded
+func (e exclude) Backup(primary Candidate, candidates []Candidate) string {
+	return e.next.Backup(primary, e.allowed(candidates))
+}
+
+// Failback swaps the roles when the primary is excluded and the backup is not,
+// or when next would
+func (e exclude) Failback(primary Candidate, backup Candidate) bool {
+	if slices.Contains(e.names, primary.Name) && !slices.Contains(e.names, backup.Name) {
+		return true
+	}
+	return e.next.Failback(primary, backup)
+}
+
+// ZoneAware never puts the backup in the same zone as the primary; without such
+// a candidate the view has no backup. A server that reports no zone may be in
+// any zone, so it is neither known to share the primary's zone nor known to be
+// apart from it: it becomes backup only when no candidate is known to be in
+// another zone. This also covers a primary without a zone, and clusters that
+// report no zones at all place backups as next would.
+func ZoneAware(next Policy) Policy {
+	return zoneAware{next: next}
+}
+
+type zoneAware struct {
+	next Policy
+}
+
+// Primary leaves the choice to next; zones only constrain the backup
+func (z zoneAware) Primary(candidates []Candidate) string {
+	return z.next.Primary(candidates)
+}
+
+// Backup lets next pick among the candidates known to be in another zone than
+// the primary, and failing that among those whose zone or the primary's is unknown
+func (z zoneAware) Backup(primary Candidate, candidates []Candidate) string {
+	var apart, unknown []Candidate
+	for _, c := range candidates {
+		switch {
+		case primary.Zone == "" || c.Zone == "":
+			unknown = append(unknown, c)
+		case c.Zone != primary.Zone:
+			apart = append(apart, c)
+		}
+	}
+	if name := z.next.Backup(primary, apart); name != "" {
+		return name
+	}
+	return z.next.Backup(primary, unknown)
+}
+
+// Failback leaves the choice to next
+func (z zoneAware) Failback(primary Candidate, backup Candidate) bool {
+	return z.next.Failback(primary, backup)
+}
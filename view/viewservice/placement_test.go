@@ -0,0 +1,132 @@
+package viewservice
+
+import (
+	"testing"
+)
+
+// servers returns candidates named by names, in join order, in zone ""
+func servers(names ...string) []Candidate {
+	candidates := make([]Candidate, len(names))
+	for i, name := range names {
+		candidates[i] = Candidate{Name: name}
+	}
+	return candidates
+}
+
+// zoned returns a candidate named name in zone
+func zoned(name string, zone string) Candidate {
+	return Candidate{Name: name, Zone: zone}
+}
+
+func TestPolicyPrimary(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		candidates []Candidate
+		want       string
+	}{
+		{"FIFO without candidates", FIFO{}, nil, ""},
+		{"FIFO takes the earliest joined", FIFO{}, servers("a", "b", "c"), "a"},
+		{"Preferred takes the highest ranked", Preferred([]string{"c", "b"}, FIFO{}), servers("a", "b", "c"), "c"},
+		{"Preferred skips ranked servers that are not candidates", Preferred([]string{"x", "b"}, FIFO{}), servers("a", "b"), "b"},
+		{"Preferred falls back to join order", Preferred([]string{"x"}, FIFO{}), servers("a", "b"), "a"},
+		{"Exclude skips excluded servers", Exclude([]string{"a"}, FIFO{}), servers("a", "b"), "b"},
+		{"Exclude of every candidate", Exclude([]string{"a", "b"}, FIFO{}), servers("a", "b"), ""},
+		{"Exclude before Preferred", Exclude([]string{"c"}, Preferred([]string{"c", "b"}, FIFO{})), servers("a", "b", "c"), "b"},
+		{"ZoneAware does not constrain the primary", ZoneAware(FIFO{}), []Candidate{zoned("a", "z1"), zoned("b", "z2")}, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Primary(tt.candidates); got != tt.want {
+				t.Fatalf("Primary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyBackup(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		primary    Candidate
+		candidates []Candidate
+		want       string
+	}{
+		{"FIFO takes the earliest joined", FIFO{}, zoned("p", ""), servers("a", "b"), "a"},
+		{"FIFO without candidates", FIFO{}, zoned("p", ""), nil, ""},
+		{"Preferred takes the highest ranked", Preferred([]string{"b"}, FIFO{}), zoned("p", ""), servers("a", "b"), "b"},
+		{"Exclude skips excluded servers", Exclude([]string{"a"}, FIFO{}), zoned("p", ""), servers("a", "b"), "b"},
+		{"ZoneAware takes another zone", ZoneAware(FIFO{}), zoned("p", "z1"),
+			[]Candidate{zoned("a", "z1"), zoned("b", "z2")}, "b"},
+		{"ZoneAware never takes the primary's zone", ZoneAware(FIFO{}), zoned("p", "z1"),
+			[]Candidate{zoned("a", "z1")}, ""},
+		{"ZoneAware prefers a known zone to an unknown one", ZoneAware(FIFO{}), zoned("p", "z1"),
+			[]Candidate{zoned("a", ""), zoned("b", "z2")}, "b"},
+		{"ZoneAware takes an unknown zone without another known one", ZoneAware(FIFO{}), zoned("p", "z1"),
+			[]Candidate{zoned("a", "z1"), zoned("b", "")}, "b"},
+		{"ZoneAware with a primary of unknown zone", ZoneAware(FIFO{}), zoned("p", ""),
+			[]Candidate{zoned("a", "z1"), zoned("b", "z2")}, "a"},
+		{"ZoneAware without any zones", ZoneAware(FIFO{}), zoned("p", ""), servers("a", "b"), "a"},
+		{"ZoneAware keeps the order of next", ZoneAware(Preferred([]string{"c"}, FIFO{})), zoned("p", "z1"),
+			[]Candidate{zoned("b", "z2"), zoned("c", "z3")}, "c"},
+		{"ZoneAware falls back when next refuses another zone", ZoneAware(Exclude([]string{"b"}, FIFO{})), zoned("p", "z1"),
+			[]Candidate{zoned("b", "z2"), zoned("c", "")}, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backup(tt.primary, tt.candidates); got != tt.want {
+				t.Fatalf("Backup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFailback(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		primary string
+		backup  string
+		want    bool
+	}{
+		{"FIFO never fails back", FIFO{}, "a", "b", false},
+		{"Preferred backup ranked above primary", Preferred([]string{"b", "a"}, FIFO{}), "a", "b", true},
+		{"Preferred primary ranked above backup", Preferred([]string{"b", "a"}, FIFO{}), "b", "a", false},
+		{"Preferred backup ranked above unranked primary", Preferred([]string{"b"}, FIFO{}), "a", "b", true},
+		{"Preferred neither ranked", Preferred([]string{"c"}, FIFO{}), "a", "b", false},
+		{"Exclude drains the primary", Exclude([]string{"a"}, FIFO{}), "a", "b", true},
+		{"Exclude keeps a primary over an excluded backup", Exclude([]string{"b"}, FIFO{}), "a", "b", false},
+		{"Exclude both", Exclude([]string{"a", "b"}, FIFO{}), "a", "b", false},
+		{"Exclude defers to next", Exclude([]string{"c"}, Preferred([]string{"b"}, FIFO{})), "a", "b", true},
+		{"ZoneAware defers to next", ZoneAware(Preferred([]string{"b"}, FIFO{})), "a", "b", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Failback(Candidate{Name: tt.primary}, Candidate{Name: tt.backup}); got != tt.want {
+				t.Fatalf("Failback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestZoneAwareView places servers through the view service: the backup is the
+// server in another zone, though it joined after one in the primary's zone
+func TestZoneAwareView(t *testing.T) {
+	f := newFixture(t, WithPolicy(ZoneAware(FIFO{})))
+	f.join("p", "z1")
+	f.tick()
+	f.ack()
+	f.join("a", "z1")
+	f.join("b", "")
+	f.join("c", "z2")
+	f.tick()
+	f.wantView(2, "p", "c")
+
+	// Without a server known to be in another zone, the one of unknown zone is next
+	f.stop("c")
+	f.ack()
+	f.expire()
+	f.ack()
+	f.tick()
+	f.wantView(4, "p", "b")
+}
@@ -0,0 +1,152 @@
+package viewservice
+
+import (
+	"context"
+	"fmt"
+
+	"goDistributedSystemDemo/logging"
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// recoverStuckView handles a primary that died or restarted before acknowledging
+// its view. The view cannot change the normal way, because the primary may have
+// served writes no other server holds. It is still safe to fall back to the
+// primary of the last acknowledged view if safePrimary trusts it. If no view was
+// ever acknowledged the failed primary was the first and only one to serve writes,
+// so nobody else is safe. It reports whether the view changed, and otherwise why it
+// is blocked. vs.mu must be held.
+func (vs *ViewServer) recoverStuckView() (bool, string) {
+	view := vs.currentView
+	acked := vs.ackedView
+
+	reason := fmt.Sprintf("data loss risk: primary %s of view %d failed before acknowledging it", view.Primary, view.ViewNumber)
+	if acked.Primary == "" {
+		return false, reason + " and may have served writes no other server holds; " +
+			"waiting for it to return or for an operator to force a primary"
+	}
+	candidate, why := vs.safePrimary()
+	if candidate == "" {
+		return false, reason + "; " + why
+	}
+
+	vs.logger.Info("Recovering stuck view", "view", view.ViewNumber, "primary", candidate,
+		"failed", view.Primary, "acked_view", acked.ViewNumber)
+	vs.makePrimary(candidate, ReasonRecovered, fmt.Sprintf("primary %s of view %d failed before acknowledging it, %s of acknowledged view %d took over",
+		view.Primary, view.ViewNumber, candidate, acked.ViewNumber))
+	return true, ""
+}
+
+// safePrimary picks a server to be primary of a view that lost its primary without
+// a backup to promote. Only the primary of the last acknowledged view provably
+// holds every acknowledged write, and only while it is alive, has not restarted
+// and is not behind the writes it reported. If no primary ever acknowledged a view
+// there is no data to keep and the policy may pick any candidate. Otherwise it
+// returns "" and why no server qualifies. vs.mu must be held.
+func (vs *ViewServer) safePrimary() (string, string) {
+	acked := vs.ackedView
+	if acked.Primary == "" {
+		return vs.policy.Primary(vs.candidates()), ""
+	}
+
+	server, ok := vs.servers[acked.Primary]
+	var state string
+	switch {
+	case !ok || !server.Alive:
+		state = "is dead"
+	case server.Incarnation != acked.PrimaryIncarnation:
+		state = "restarted and lost its data"
+	case server.Leaving:
+		state = "is leaving"
+	case vs.behind(server):
+		state = fmt.Sprintf("is behind at sequence %d of %d", server.Metadata.AppliedSeq, vs.primarySeq)
+	default:
+		return acked.Primary, ""
+	}
+
+	reason := fmt.Sprintf("no live server is known to hold every acknowledged write, "+
+		"%s (primary of acknowledged view %d) %s", acked.Primary, acked.ViewNumber, state)
+	if best := vs.mostUpToDate(); best != "" {
+		reason += fmt.Sprintf("; the most up-to-date live server is %s at sequence %d of %d",
+			best, vs.servers[best].Metadata.AppliedSeq, vs.primarySeq)
+	}
+	return "", reason + fmt.Sprintf("; waiting for %s to return or for an operator to force a primary", acked.Primary)
+}
+
+// behind reports whether the server provably lacks writes a primary applied. A
+// server that sends no metadata cannot be judged and is not considered behind.
+func (vs *ViewServer) behind(server *ServerInfo) bool {
+	return server.Metadata != nil && server.Metadata.AppliedSeq < vs.primarySeq
+}
+
+// mostUpToDate returns the live server that reported the highest applied
+// sequence, earliest joined first among equals, or "" if none reported any
+func (vs *ViewServer) mostUpToDate() string {
+	best := ""
+	for _, name := range vs.serverNames() {
+		server := vs.servers[name]
+		if !server.Alive || server.Leaving || server.Metadata == nil {
+			continue
+		}
+		if best == "" || server.Metadata.AppliedSeq > vs.servers[best].Metadata.AppliedSeq {
+			best = name
+		}
+	}
+	return best
+}
+
+// makePrimary starts a new view with name as primary and no backup, recording why;
+// vs.mu must be held
+func (vs *ViewServer) makePrimary(name string, reason string, detail string) {
+	vs.currentView.Primary = name
+	vs.currentView.PrimaryIncarnation = vs.servers[name].Incarnation
+	vs.currentView.Backup = ""
+	vs.currentView.BackupIncarnation = 0
+	vs.nextView(reason, detail)
+}
+
+// setBlocked records why the view cannot change, logging when that changes; vs.mu must be held
+func (vs *ViewServer) setBlocked(reason string) {
+	if reason == vs.blocked {
+		return
+	}
+	if reason != "" {
+		vs.logger.Warn("View service blocked", "reason", reason)
+	} else {
+		vs.logger.Info("View service no longer blocked")
+	}
+	vs.blocked = reason
+}
+
+// ForcePrimary RPC handler - an operator override that makes a live server primary
+// of a new view whatever the state of the current one. Writes held only by the
+// replaced primary are lost.
+func (vs *ViewServer) ForcePrimary(ctx context.Context, req *pb.ForcePrimaryRequest) (*pb.ForcePrimaryResponse, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	server, ok := vs.servers[req.ServerName]
+	if !ok || !server.Alive || server.Leaving {
+		return &pb.ForcePrimaryResponse{
+			View:  proto.Clone(vs.currentView).(*pb.View),
+			Ok:    false,
+			Error: "ErrUnknownServer",
+		}, nil
+	}
+
+	logging.For(ctx, vs.logger).Warn("Operator forced a new primary", "primary", req.ServerName,
+		"replaced", vs.currentView.Primary, "view", vs.currentView.ViewNumber)
+	vs.makePrimary(req.ServerName, ReasonAdmin, fmt.Sprintf("operator forced %s to be primary in place of %q",
+		req.ServerName, vs.currentView.Primary))
+	vs.setBlocked("")
+	vs.dataLossRisk = false
+	// Writes beyond what the new primary holds are given up, so it is up to date by definition
+	vs.primarySeq = 0
+	if server.Metadata != nil {
+		vs.primarySeq = server.Metadata.AppliedSeq
+	}
+	vs.logger.Info("View changed", "view", vs.currentView.ViewNumber,
+		"primary", vs.currentView.Primary, "backup", vs.currentView.Backup)
+	return &pb.ForcePrimaryResponse{View: proto.Clone(vs.currentView).(*pb.View), Ok: true}, nil
+}
@@ -0,0 +1,161 @@
+package viewservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "goDistributedSystemDemo/proto"
+)
+
+// status returns the view service's answer to GetView
+func (f *fixture) status() *pb.GetViewResponse {
+	f.t.Helper()
+	resp, err := f.vs.GetView(context.Background(), &pb.GetViewRequest{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return resp
+}
+
+// applied makes name report having applied writes up to seq from now on
+func (f *fixture) applied(name string, seq uint64) {
+	f.t.Helper()
+	req := f.live[name]
+	req.Metadata = &pb.ServerMetadata{AppliedSeq: seq, Willing: true}
+	f.ping(req)
+}
+
+// wantBlocked fails the test unless the service is blocked for a reason
+// containing want, with data at risk
+func (f *fixture) wantBlocked(want string) {
+	f.t.Helper()
+	s := f.status()
+	if !strings.Contains(s.Blocked, want) || !s.DataLossRisk {
+		f.t.Fatalf("blocked %q, data loss risk %v; want %q with risk", s.Blocked, s.DataLossRisk, want)
+	}
+}
+
+// pair brings up p as primary and b as its synced backup in acknowledged view 2
+func pair(f *fixture) {
+	f.t.Helper()
+	f.join("p", "")
+	f.tick()
+	f.ack()
+	f.join("b", "")
+	f.tick()
+	f.ack()
+	f.wantView(2, "p", "b")
+}
+
+// TestRecoverStuckView has a backup take over after failback and die before
+// acknowledging its view. The primary of the last acknowledged view holds every
+// acknowledged write, so it takes over again.
+func TestRecoverStuckView(t *testing.T) {
+	f := newFixture(t, WithPolicy(Preferred([]string{"b"}, FIFO{})))
+	pair(f)
+	f.tick()
+	f.wantView(3, "b", "p")
+
+	f.stop("b")
+	f.expire()
+	f.wantView(4, "p", "")
+	if reason := f.vs.history[len(f.vs.history)-1].Reason; reason != ReasonRecovered {
+		t.Fatalf("last change %q, want %q", reason, ReasonRecovered)
+	}
+	if s := f.status(); s.Blocked != "" {
+		t.Fatalf("still blocked: %s", s.Blocked)
+	}
+}
+
+// TestStuckViewBlocked leaves no server known to hold every acknowledged write:
+// the view service must wait rather than pick one, until an operator forces a
+// primary
+func TestStuckViewBlocked(t *testing.T) {
+	f := newFixture(t, WithPolicy(Preferred([]string{"b"}, FIFO{})))
+	pair(f)
+	f.join("c", "")
+	f.tick()
+	f.wantView(3, "b", "p")
+
+	f.stop("b")
+	f.stop("p")
+	f.expire()
+	f.wantView(3, "b", "p")
+	f.wantBlocked("failed before acknowledging it")
+
+	resp, err := f.vs.ForcePrimary(context.Background(), &pb.ForcePrimaryRequest{ServerName: "b"})
+	if err != nil || resp.Ok || resp.Error != "ErrUnknownServer" {
+		t.Fatalf("ForcePrimary(dead b) = %v, %v; want ErrUnknownServer", resp, err)
+	}
+	resp, err = f.vs.ForcePrimary(context.Background(), &pb.ForcePrimaryRequest{ServerName: "c"})
+	if err != nil || !resp.Ok {
+		t.Fatalf("ForcePrimary(c) = %v, %v", resp, err)
+	}
+	f.wantView(4, "c", "")
+	if s := f.status(); s.Blocked != "" || s.DataLossRisk {
+		t.Fatalf("blocked %q, data loss risk %v after forcing a primary", s.Blocked, s.DataLossRisk)
+	}
+}
+
+// TestFirstPrimaryDiesBeforeAck blocks when the first primary fails before
+// acknowledging its view: it may have served writes nobody else holds
+func TestFirstPrimaryDiesBeforeAck(t *testing.T) {
+	f := newFixture(t)
+	f.join("p", "")
+	f.tick()
+	f.wantView(1, "p", "")
+	f.join("b", "")
+
+	f.stop("p")
+	f.expire()
+	f.wantView(1, "p", "")
+	f.wantBlocked("may have served writes no other server holds")
+}
+
+// TestBackupBehindIsNotPromoted kills a primary whose backup reported fewer
+// applied writes than the primary did. The backup is promoted only once it
+// reports having caught up.
+func TestBackupBehindIsNotPromoted(t *testing.T) {
+	f := newFixture(t)
+	pair(f)
+	f.applied("b", 5)
+	f.applied("p", 10)
+	f.ack()
+
+	f.stop("p")
+	f.expire()
+	f.wantView(2, "p", "b")
+	f.wantBlocked("backup b has applied writes up to sequence 5 but primary p reached 10")
+
+	f.applied("b", 10)
+	f.tick()
+	f.wantView(3, "b", "")
+	if s := f.status(); s.Blocked != "" || s.DataLossRisk {
+		t.Fatalf("blocked %q, data loss risk %v after the backup caught up", s.Blocked, s.DataLossRisk)
+	}
+}
+
+// TestPrimaryWithoutBackupDies waits for the only copy of the data to return
+// rather than make an empty server primary
+func TestPrimaryWithoutBackupDies(t *testing.T) {
+	f := newFixture(t)
+	f.join("p", "")
+	f.tick()
+	f.ack()
+
+	f.stop("p")
+	f.expire()
+	f.wantView(2, "", "")
+	f.join("c", "")
+	f.tick()
+	f.wantView(2, "", "")
+	f.wantBlocked("p (primary of acknowledged view 1) is dead")
+
+	f.join("p", "")
+	f.tick()
+	f.wantView(3, "p", "")
+	f.ack()
+	f.tick()
+	f.wantView(4, "p", "c")
+}
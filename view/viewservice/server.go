@@ -2,14 +2,30 @@ package viewservice
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"goDistributedSystemDemo/auth"
+	"goDistributedSystemDemo/clock"
+	"goDistributedSystemDemo/logging"
 	pb "goDistributedSystemDemo/proto"
+	"goDistributedSystemDemo/tlsconfig"
+	"goDistributedSystemDemo/tracing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -21,8 +37,13 @@ const (
 // ServerInfo tracks information about each server
 type ServerInfo struct {
 	Name         string
+	Incarnation  uint64 // incarnation of the process that pinged last
 	LastPingTime time.Time
 	Alive        bool
+	Leaving      bool               // left on purpose; not given a role until it restarts
+	Zone         string             // failure domain reported in pings
+	Joined       uint64             // join order; a restart counts as joining again
+	Metadata     *pb.ServerMetadata // from the last ping, nil if the server sent none
 }
 
 // ViewServer is the View Service implementation
@@ -31,16 +52,38 @@ type ViewServer struct {
 	mu         sync.Mutex
 	listener   net.Listener
 	grpcServer *grpc.Server
-	dead       bool
+	health     *health.Server // answers health checks, NOT_SERVING once killed
+	dead       atomic.Bool
+	failed     chan error // receives the error that stopped the gRPC server
 
 	currentView  *pb.View
 	servers      map[string]*ServerInfo // tracks all servers that have pinged
-	idleServers  []string               // servers that are not primary or backup
+	joins        uint64                 // number of joins so far, for ServerInfo.Joined
 	primaryAcked bool                   // primary has acknowledged the current view
+	backupSynced uint64                 // latest view whose primary reported its backup in sync
+	ackedView    *pb.View               // copy of the last view whose primary acknowledged it
+	primarySeq   uint64                 // highest replication sequence a primary reported applying
+	blocked      string                 // why the view cannot change, "" if it can
+	dataLossRisk bool                   // blocked because no live server is known to hold every acknowledged write
+	history      []*pb.ViewChange       // most recent view changes, oldest first
+	historyOut   io.Writer              // receives every view change as a line of JSON if not nil
+
+	serverOptions []grpc.ServerOption   // extra options for the gRPC server
+	clock         clock.Clock           // source of time for liveness
+	policy        Policy                // chooses primaries and backups
+	registerer    prometheus.Registerer // receives the service's metrics
+	metrics       *serverMetrics
+	logger        *slog.Logger
+	traces        trace.TracerProvider // receives spans for RPCs and view changes
+	tracer        trace.Tracer
+	tls           *tlsconfig.Certs // serves over TLS if not nil
+	authz         *auth.Authorizer // checks every call if not nil
 }
 
-// StartServer creates and starts a new ViewServer
-func StartServer(address string) *ViewServer {
+// New creates a ViewServer that is not connected to the network. Its RPC handlers
+// can be called directly and Tick must be called to detect failures; StartServer
+// does both over gRPC.
+func New(opts ...Option) *ViewServer {
 	vs := &ViewServer{
 		currentView: &pb.View{
 			ViewNumber: 0,
@@ -48,34 +91,87 @@ func StartServer(address string) *ViewServer {
 			Backup:     "",
 		},
 		servers:      make(map[string]*ServerInfo),
-		idleServers:  make([]string, 0),
+		failed:       make(chan error, 1),
 		primaryAcked: true, // no primary initially, so considered acked
+		ackedView:    &pb.View{},
+		clock:        clock.Real,
+		policy:       FIFO{},
+		logger:       slog.Default(),
+		traces:       otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(vs)
 	}
+	vs.tracer = vs.traces.Tracer("goDistributedSystemDemo/viewservice")
+	if vs.registerer == nil {
+		vs.registerer = prometheus.NewRegistry()
+	}
+	vs.metrics = newServerMetrics(vs, vs.registerer)
+	return vs
+}
+
+// StartServer creates and starts a new ViewServer listening on address, or on the
+// listener given with WithListener. It fails if address cannot be listened on.
+func StartServer(address string, opts ...Option) (*ViewServer, error) {
+	vs := New(opts...)
 
 	// Start listening
-	lis, err := net.Listen("tcp", address)
-	if err != nil {
-		log.Fatalf("ViewServer failed to listen: %v", err)
+	lis := vs.listener
+	if lis == nil {
+		var err error
+		lis, err = net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("ViewServer failed to listen on %s: %w", address, err)
+		}
+		vs.listener = lis
 	}
-	vs.listener = lis
 
 	// Create gRPC server
-	vs.grpcServer = grpc.NewServer()
+	serverOptions := append(vs.serverOptions, tracing.ServerOption(vs.traces),
+		vs.metrics.rpc.ServerOption(), logging.ServerOption(vs.logger))
+	if vs.tls != nil {
+		serverOptions = append(serverOptions, grpc.Creds(vs.tls.ServerCredentials()))
+	}
+	switch {
+	case vs.authz != nil:
+		serverOptions = append(serverOptions, vs.authz.ServerOptions()...)
+	case vs.tls != nil:
+		// Only servers may join or leave the view
+		serverOptions = append(serverOptions,
+			tlsconfig.RequireServerCert(pb.ViewService_Ping_FullMethodName, pb.ViewService_Leave_FullMethodName))
+	}
+	vs.grpcServer = grpc.NewServer(serverOptions...)
 	pb.RegisterViewServiceServer(vs.grpcServer, vs)
+	vs.health = health.NewServer() // the overall status starts as SERVING
+	vs.health.SetServingStatus(pb.ViewService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(vs.grpcServer, vs.health)
+	reflection.Register(vs.grpcServer)
 
-	// Start gRPC server in background
+	// Start gRPC server in background; if it stops on its own the service is
+	// killed and the error reported on Failed
 	go func() {
-		if err := vs.grpcServer.Serve(lis); err != nil && !vs.dead {
-			log.Fatalf("ViewServer failed to serve: %v", err)
+		if err := vs.grpcServer.Serve(lis); err != nil && !vs.dead.Load() {
+			vs.logger.Error("ViewServer failed to serve", "err", err)
+			vs.failed <- fmt.Errorf("ViewServer failed to serve on %s: %w", lis.Addr(), err)
+			vs.Kill()
 		}
 	}()
 
 	// Start ticker for failure detection and promotions
 	go vs.ticker()
 
-	log.Printf("ViewServer started on %s\n", address)
-	log.Printf("Server Configuration: DeadInterval=%v, TickerInterval=%v\n", DeadInterval, TickerInterval)
-	return vs
+	vs.logger.Info("ViewServer started", "addr", vs.Addr(), "dead_interval", DeadInterval, "ticker_interval", TickerInterval)
+	return vs, nil
+}
+
+// Failed receives the error if the service stopped serving without being killed
+func (vs *ViewServer) Failed() <-chan error {
+	return vs.failed
+}
+
+// Addr returns the address the view service listens on
+func (vs *ViewServer) Addr() string {
+	return vs.listener.Addr().String()
 }
 
 // Ping RPC handler - called by KV servers every 0.5 seconds
@@ -85,28 +181,50 @@ func (vs *ViewServer) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingRe
 
 	// Update server's last ping time
 	if server, exists := vs.servers[req.ServerName]; exists {
-		server.LastPingTime = time.Now()
+		if server.Incarnation != req.Incarnation {
+			// The process restarted and lost its data; checkFailuresAndPromote
+			// replaces it wherever the view still names the old incarnation
+			vs.logger.Info("Server restarted", "server", req.ServerName, "incarnation", req.Incarnation, "was", server.Incarnation)
+			server.Incarnation = req.Incarnation
+			server.Leaving = false
+			vs.joins++
+			server.Joined = vs.joins
+		}
+		server.LastPingTime = vs.clock.Now()
 		server.Alive = true
+		server.Zone = req.Zone
+		server.Metadata = req.Metadata
 	} else {
 		// New server
+		vs.joins++
 		vs.servers[req.ServerName] = &ServerInfo{
 			Name:         req.ServerName,
-			LastPingTime: time.Now(),
+			Incarnation:  req.Incarnation,
+			LastPingTime: vs.clock.Now(),
 			Alive:        true,
-		}
-		// Add to default servers if not already primary or backup
-		if req.ServerName != vs.currentView.Primary && req.ServerName != vs.currentView.Backup {
-			vs.idleServers = append(vs.idleServers, req.ServerName)
+			Zone:         req.Zone,
+			Joined:       vs.joins,
+			Metadata:     req.Metadata,
 		}
 	}
 
-	// Check if primary has acked the current view
-	if req.ServerName == vs.currentView.Primary && req.ViewNumber == vs.currentView.ViewNumber {
+	// Check if primary has acked the current view; a restarted primary cannot ack
+	if req.ServerName == vs.currentView.Primary && req.Incarnation == vs.currentView.PrimaryIncarnation &&
+		req.ViewNumber == vs.currentView.ViewNumber {
 		vs.primaryAcked = true
+		if vs.ackedView.ViewNumber != vs.currentView.ViewNumber {
+			vs.ackedView = proto.Clone(vs.currentView).(*pb.View)
+		}
+		if req.BackupSynced && vs.currentView.Backup != "" {
+			vs.backupSynced = vs.currentView.ViewNumber
+		}
+		if req.Metadata != nil {
+			vs.primarySeq = max(vs.primarySeq, req.Metadata.AppliedSeq)
+		}
 	}
 
-	// Return current view
-	return &pb.PingResponse{View: vs.currentView}, nil
+	// Return a copy of the current view; it is marshalled after the lock is released
+	return &pb.PingResponse{View: proto.Clone(vs.currentView).(*pb.View)}, nil
 }
 
 // GetView RPC handler - called by clients to find the current primary
@@ -114,123 +232,275 @@ func (vs *ViewServer) GetView(ctx context.Context, req *pb.GetViewRequest) (*pb.
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
-	return &pb.GetViewResponse{View: vs.currentView}, nil
+	return &pb.GetViewResponse{
+		View:         proto.Clone(vs.currentView).(*pb.View),
+		PrimaryAcked: vs.primaryAcked,
+		Blocked:      vs.blocked,
+		DataLossRisk: vs.dataLossRisk,
+	}, nil
+}
+
+// Leave RPC handler - called by KV servers that shut down gracefully. A leaving
+// backup is dropped from the view; a leaving primary is replaced by its backup at
+// once, which requires that it acked the current view and brought the backup up to
+// date in it. The server gets no new role until it restarts.
+func (vs *ViewServer) Leave(ctx context.Context, req *pb.LeaveRequest) (*pb.LeaveResponse, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	server, exists := vs.servers[req.ServerName]
+	if !exists || server.Incarnation != req.Incarnation {
+		// Not a process the view service knows, so it holds no role
+		return &pb.LeaveResponse{View: proto.Clone(vs.currentView).(*pb.View), Ok: true}, nil
+	}
+
+	view := vs.currentView
+	viewChanged := true
+	switch {
+	case req.ServerName == view.Primary && req.Incarnation == view.PrimaryIncarnation:
+		if !vs.primaryAcked || req.ViewNumber != view.ViewNumber {
+			return vs.leaveError("ErrViewNotAcked"), nil
+		}
+		backup, ok := vs.servers[view.Backup]
+		if view.Backup == "" || !ok || !backup.Alive || backup.Incarnation != view.BackupIncarnation {
+			return vs.leaveError("ErrNoBackup"), nil
+		}
+		vs.logger.Info("Primary is leaving, promoting backup to primary", "primary", view.Primary, "backup", view.Backup)
+		detail := fmt.Sprintf("primary %s left, backup %s promoted", view.Primary, view.Backup)
+		view.Primary = view.Backup
+		view.PrimaryIncarnation = view.BackupIncarnation
+		view.Backup = ""
+		view.BackupIncarnation = 0
+		vs.nextView(ReasonServerLeft, detail)
+	case req.ServerName == view.Backup && req.Incarnation == view.BackupIncarnation:
+		vs.logger.Info("Backup is leaving", "backup", view.Backup)
+		detail := fmt.Sprintf("backup %s left", view.Backup)
+		view.Backup = ""
+		view.BackupIncarnation = 0
+		vs.nextView(ReasonServerLeft, detail)
+	default:
+		vs.logger.Info("Server is leaving", "server", req.ServerName)
+		viewChanged = false
+	}
+	server.Leaving = true
+
+	if viewChanged {
+		vs.logger.Info("View changed", "view", view.ViewNumber, "primary", view.Primary, "backup", view.Backup)
+	}
+	return &pb.LeaveResponse{View: proto.Clone(view).(*pb.View), Ok: true}, nil
+}
+
+// leaveError builds a refusal to let a primary leave; vs.mu must be held
+func (vs *ViewServer) leaveError(code string) *pb.LeaveResponse {
+	return &pb.LeaveResponse{
+		View:  proto.Clone(vs.currentView).(*pb.View),
+		Ok:    false,
+		Error: code,
+	}
 }
 
 // ticker runs periodically to detect failures and manage promotions
 func (vs *ViewServer) ticker() {
-	ticker := time.NewTicker(TickerInterval)
+	ticker := vs.clock.NewTicker(TickerInterval)
 	defer ticker.Stop()
 
-	for !vs.dead {
-		<-ticker.C
-		vs.mu.Lock()
-		vs.checkFailuresAndPromote()
-		vs.mu.Unlock()
+	for !vs.dead.Load() {
+		<-ticker.C()
+		vs.Tick()
 	}
 }
 
+// Tick runs one round of failure detection and promotion
+func (vs *ViewServer) Tick() {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.checkFailuresAndPromote()
+}
+
 // checkFailuresAndPromote detects dead servers and handles promotions
 func (vs *ViewServer) checkFailuresAndPromote() {
-	now := time.Now()
+	now := vs.clock.Now()
 	viewChanged := false
+	blocked := ""
+	risk := false
 
 	// Mark dead servers
-	for name, server := range vs.servers {
+	for _, name := range vs.serverNames() {
+		server := vs.servers[name]
 		if now.Sub(server.LastPingTime) > DeadInterval {
 			if server.Alive {
 				server.Alive = false
-				log.Printf("Server %s declared dead\n", name)
+				vs.logger.Warn("Server declared dead", "server", name)
 			}
 		}
 	}
 
-	// Check if primary is dead
+	// Check if primary is dead or restarted
 	if vs.currentView.Primary != "" {
-		if server, exists := vs.servers[vs.currentView.Primary]; exists && !server.Alive {
-			log.Printf("Primary %s is dead\n", vs.currentView.Primary)
+		server, exists := vs.servers[vs.currentView.Primary]
+		restarted := exists && server.Alive && server.Incarnation != vs.currentView.PrimaryIncarnation
+		if exists && (!server.Alive || restarted) {
+			cause := fmt.Sprintf("primary %s is dead", vs.currentView.Primary)
+			if restarted {
+				cause = fmt.Sprintf("primary %s restarted and lost its data", vs.currentView.Primary)
+				vs.logger.Warn("Primary restarted and lost its data", "primary", vs.currentView.Primary)
+			} else {
+				vs.logger.Warn("Primary is dead", "primary", vs.currentView.Primary)
+			}
 
 			// Can only promote if primary has acked the current view
 			if vs.primaryAcked && vs.currentView.Backup != "" {
-				// Promote backup to primary
+				// Promote backup to primary, unless it restarted too or is provably
+				// missing writes the primary applied
 				backupServer, backupExists := vs.servers[vs.currentView.Backup]
-				if backupExists && backupServer.Alive {
-					log.Printf("Promoting backup %s to primary\n", vs.currentView.Backup)
+				if backupExists && backupServer.Alive && backupServer.Incarnation == vs.currentView.BackupIncarnation &&
+					vs.behind(backupServer) {
+					blocked = fmt.Sprintf("data loss risk: backup %s has applied writes up to sequence %d but primary %s reached %d; "+
+						"waiting for it to catch up or for an operator to force a primary",
+						vs.currentView.Backup, backupServer.Metadata.AppliedSeq, vs.currentView.Primary, vs.primarySeq)
+					risk = true
+				} else if backupExists && backupServer.Alive && backupServer.Incarnation == vs.currentView.BackupIncarnation {
+					vs.logger.Info("Promoting backup to primary", "backup", vs.currentView.Backup)
+					detail := fmt.Sprintf("%s, backup %s promoted", cause, vs.currentView.Backup)
 					vs.currentView.Primary = vs.currentView.Backup
+					vs.currentView.PrimaryIncarnation = vs.currentView.BackupIncarnation
 					vs.currentView.Backup = ""
-					vs.currentView.ViewNumber++
-					vs.primaryAcked = false
+					vs.currentView.BackupIncarnation = 0
+					vs.nextView(ReasonBackupPromoted, detail)
 					viewChanged = true
 				}
 			} else if vs.primaryAcked {
-				// No backup, just remove dead primary
+				// No backup, just remove dead primary. It held the only copy of the
+				// data, so ackedView keeps naming it and a new primary is only chosen
+				// once it returns or an operator forces one.
 				vs.currentView.Primary = ""
-				vs.currentView.ViewNumber++
-				vs.primaryAcked = true
+				vs.currentView.PrimaryIncarnation = 0
+				vs.nextView(ReasonPrimaryDied, cause+" and there is no backup")
 				viewChanged = true
+			} else {
+				// The primary never acknowledged its view
+				var recovered bool
+				recovered, blocked = vs.recoverStuckView()
+				risk = blocked != ""
+				viewChanged = viewChanged || recovered
 			}
 		}
 	}
 
-	// Check if backup is dead
+	// Check if backup is dead or restarted. Like every change, dropping it waits
+	// until the primary acknowledged the view naming it; until then the primary
+	// may not even know it has that backup.
 	if vs.currentView.Backup != "" {
-		if server, exists := vs.servers[vs.currentView.Backup]; exists && !server.Alive {
-			log.Printf("Backup %s is dead\n", vs.currentView.Backup)
+		if server, exists := vs.servers[vs.currentView.Backup]; exists && !server.Alive && vs.primaryAcked {
+			vs.logger.Warn("Backup is dead", "backup", vs.currentView.Backup)
+			detail := fmt.Sprintf("backup %s is dead", vs.currentView.Backup)
 			vs.currentView.Backup = ""
-			vs.currentView.ViewNumber++
+			vs.currentView.BackupIncarnation = 0
+			vs.nextView(ReasonBackupDied, detail)
+			viewChanged = true
+		} else if exists && server.Incarnation != vs.currentView.BackupIncarnation && vs.primaryAcked {
+			// Keep it as backup under its new incarnation; the primary sees the
+			// change and transfers its state again
+			vs.logger.Info("Backup restarted, primary must transfer state again", "backup", vs.currentView.Backup)
+			vs.currentView.BackupIncarnation = server.Incarnation
+			vs.nextView(ReasonBackupRestarted, fmt.Sprintf("backup %s restarted", vs.currentView.Backup))
 			viewChanged = true
 		}
 	}
 
-	// Assign new primary if none exists
+	// Assign new primary if none exists, but only one that holds every
+	// acknowledged write
 	if vs.currentView.Primary == "" && vs.primaryAcked {
-		for name, server := range vs.servers {
-			if server.Alive && name != vs.currentView.Backup {
-				log.Printf("Assigning %s as new primary\n", name)
-				vs.currentView.Primary = name
-				vs.currentView.ViewNumber++
-				vs.primaryAcked = false
-				viewChanged = true
-				vs.removeFromIdle(name)
-				break
-			}
+		if name, reason := vs.safePrimary(); name != "" {
+			vs.logger.Info("Assigning new primary", "primary", name)
+			vs.currentView.Primary = name
+			vs.currentView.PrimaryIncarnation = vs.servers[name].Incarnation
+			vs.nextView(ReasonServerAssigned, fmt.Sprintf("%s assigned as primary", name))
+			viewChanged = true
+		} else if reason != "" {
+			blocked = "data loss risk: " + reason
+			risk = true
 		}
 	}
 
 	// Assign new backup if none exists and we have a primary
 	if vs.currentView.Backup == "" && vs.currentView.Primary != "" && vs.primaryAcked {
-		for name, server := range vs.servers {
-			if server.Alive && name != vs.currentView.Primary {
-				log.Printf("Assigning %s as new backup\n", name)
-				vs.currentView.Backup = name
-				vs.currentView.ViewNumber++
-				viewChanged = true
-				vs.removeFromIdle(name)
-				break
-			}
+		if name := vs.policy.Backup(vs.candidate(vs.currentView.Primary), vs.candidates()); name != "" {
+			vs.logger.Info("Assigning new backup", "backup", name)
+			vs.currentView.Backup = name
+			vs.currentView.BackupIncarnation = vs.servers[name].Incarnation
+			vs.nextView(ReasonServerAssigned, fmt.Sprintf("%s assigned as backup", name))
+			viewChanged = true
 		}
 	}
 
+	// Swap primary and backup if the policy prefers the backup, once the primary
+	// has acknowledged the view and reported that the backup holds the full state
+	view := vs.currentView
+	if view.Primary != "" && view.Backup != "" && vs.primaryAcked && vs.backupSynced == view.ViewNumber &&
+		vs.policy.Failback(vs.candidate(view.Primary), vs.candidate(view.Backup)) {
+		vs.logger.Info("Failing back, backup takes over from primary", "backup", view.Backup, "primary", view.Primary)
+		view.Primary, view.Backup = view.Backup, view.Primary
+		view.PrimaryIncarnation, view.BackupIncarnation = view.BackupIncarnation, view.PrimaryIncarnation
+		vs.nextView(ReasonFailback, fmt.Sprintf("%s preferred over %s by the placement policy", view.Primary, view.Backup))
+		viewChanged = true
+	}
+
 	if viewChanged {
-		log.Printf("View changed: ViewNumber=%d, Primary=%s, Backup=%s\n",
-			vs.currentView.ViewNumber, vs.currentView.Primary, vs.currentView.Backup)
+		vs.logger.Info("View changed", "view", vs.currentView.ViewNumber,
+			"primary", vs.currentView.Primary, "backup", vs.currentView.Backup)
+	}
+	vs.setBlocked(blocked)
+	vs.dataLossRisk = risk
+}
+
+// serverNames returns the names of all servers that have pinged in join order, so
+// that promotions do not depend on map iteration order
+func (vs *ViewServer) serverNames() []string {
+	names := make([]string, 0, len(vs.servers))
+	for name := range vs.servers {
+		names = append(names, name)
 	}
+	sort.Slice(names, func(i, j int) bool {
+		return vs.servers[names[i]].Joined < vs.servers[names[j]].Joined
+	})
+	return names
 }
 
-// removeFromIdle removes a server from the default list
-func (vs *ViewServer) removeFromIdle(serverName string) {
-	newIdle := make([]string, 0)
-	for _, name := range vs.idleServers {
-		if name != serverName {
-			newIdle = append(newIdle, name)
+// candidates returns the live servers without a role that may be given one, in
+// join order
+func (vs *ViewServer) candidates() []Candidate {
+	candidates := make([]Candidate, 0)
+	for _, name := range vs.serverNames() {
+		server := vs.servers[name]
+		if !server.Alive || server.Leaving || !server.willing() || name == vs.currentView.Primary || name == vs.currentView.Backup {
+			continue
 		}
+		candidates = append(candidates, vs.candidate(name))
 	}
-	vs.idleServers = newIdle
+	return candidates
+}
+
+// candidate describes a known server to the policy
+func (vs *ViewServer) candidate(name string) Candidate {
+	c := Candidate{Name: name}
+	if server, ok := vs.servers[name]; ok {
+		c.Zone = server.Zone
+		if md := server.Metadata; md != nil {
+			c.Version = md.Version
+			c.Labels = md.Labels
+			c.AppliedSeq = md.AppliedSeq
+		}
+	}
+	return c
 }
 
 // Kill shuts down the server
 func (vs *ViewServer) Kill() {
-	vs.dead = true
+	vs.dead.Store(true)
+	if vs.health != nil {
+		vs.health.Shutdown()
+	}
 	if vs.grpcServer != nil {
 		vs.grpcServer.GracefulStop()
 	}
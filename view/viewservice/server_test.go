@@ -0,0 +1,130 @@
+package viewservice
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"goDistributedSystemDemo/clock"
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// fixture drives a view service on a simulated clock. Servers in live repeat
+// their last ping on every tick, so they stay alive without acknowledging new
+// views; ack makes the primary acknowledge the current one.
+type fixture struct {
+	t    *testing.T
+	clk  *clock.Sim
+	vs   *ViewServer
+	live map[string]*pb.PingRequest
+}
+
+// newFixture creates a view service with opts and no servers
+func newFixture(t *testing.T, opts ...Option) *fixture {
+	clk := clock.NewSim(time.Unix(0, 0))
+	opts = append([]Option{WithClock(clk), WithLogger(slog.New(slog.DiscardHandler))}, opts...)
+	return &fixture{t: t, clk: clk, vs: New(opts...), live: make(map[string]*pb.PingRequest)}
+}
+
+// ping sends req, which the server then repeats on every tick, and returns the view
+func (f *fixture) ping(req *pb.PingRequest) *pb.View {
+	f.t.Helper()
+	resp, err := f.vs.Ping(context.Background(), req)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.live[req.ServerName] = proto.Clone(req).(*pb.PingRequest)
+	return resp.View
+}
+
+// join starts pinging as a new server with incarnation 1 in zone
+func (f *fixture) join(name string, zone string) {
+	f.ping(&pb.PingRequest{ServerName: name, Incarnation: 1, Zone: zone})
+}
+
+// ack makes the primary acknowledge the current view and report its backup in sync
+func (f *fixture) ack() {
+	f.t.Helper()
+	view := f.view()
+	req, ok := f.live[view.Primary]
+	if !ok {
+		f.t.Fatalf("primary %q of view %d is not pinging", view.Primary, view.ViewNumber)
+	}
+	req = proto.Clone(req).(*pb.PingRequest)
+	req.ViewNumber = view.ViewNumber
+	req.BackupSynced = view.Backup != ""
+	f.ping(req)
+}
+
+// stop stops name from pinging; it is declared dead once DeadInterval passes
+func (f *fixture) stop(name string) {
+	delete(f.live, name)
+}
+
+// tick advances the clock by TickerInterval, repeats every live server's ping
+// and runs one round of failure detection
+func (f *fixture) tick() {
+	f.t.Helper()
+	f.clk.Advance(TickerInterval)
+	for _, req := range f.live {
+		f.ping(req)
+	}
+	f.vs.Tick()
+}
+
+// expire ticks until servers that stopped pinging are declared dead
+func (f *fixture) expire() {
+	f.t.Helper()
+	for d := time.Duration(0); d <= DeadInterval; d += TickerInterval {
+		f.tick()
+	}
+}
+
+// view returns the current view
+func (f *fixture) view() *pb.View {
+	f.t.Helper()
+	resp, err := f.vs.GetView(context.Background(), &pb.GetViewRequest{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return resp.View
+}
+
+// wantView fails the test unless the current view has number n, primary and backup
+func (f *fixture) wantView(n uint64, primary string, backup string) {
+	f.t.Helper()
+	v := f.view()
+	if v.ViewNumber != n || v.Primary != primary || v.Backup != backup {
+		f.t.Fatalf("view %d (%q, %q), want %d (%q, %q)", v.ViewNumber, v.Primary, v.Backup, n, primary, backup)
+	}
+}
+
+// TestBackupDiesBeforeAck kills the backup of a view its primary has not yet
+// acknowledged. The view service must keep the view until the primary knows of
+// it, and only then drop the dead backup.
+func TestBackupDiesBeforeAck(t *testing.T) {
+	f := newFixture(t)
+	f.join("p", "")
+	f.tick()
+	f.wantView(1, "p", "")
+	f.ack()
+
+	f.join("b", "")
+	f.tick()
+	f.wantView(2, "p", "b")
+
+	f.stop("b")
+	f.expire()
+	f.wantView(2, "p", "b")
+
+	f.ack()
+	f.tick()
+	f.wantView(3, "p", "")
+	history := f.vs.history
+	if reason := history[len(history)-1].Reason; reason != ReasonBackupDied {
+		t.Errorf("last view change reason %q, want %q", reason, ReasonBackupDied)
+	}
+}
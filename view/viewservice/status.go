@@ -0,0 +1,57 @@
+package viewservice
+
+import (
+	"context"
+
+	pb "goDistributedSystemDemo/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// willing reports whether the server wants a role. Servers that send no metadata
+// predate the flag and are always willing.
+func (s *ServerInfo) willing() bool {
+	return s.Metadata == nil || s.Metadata.Willing
+}
+
+// ListServers RPC handler - reports every server that has pinged, in join order,
+// with the metadata from its last ping
+func (vs *ViewServer) ListServers(ctx context.Context, req *pb.ListServersRequest) (*pb.ListServersResponse, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	return &pb.ListServersResponse{Servers: vs.serverStatuses()}, nil
+}
+
+// serverStatuses describes every known server in join order; vs.mu must be held
+func (vs *ViewServer) serverStatuses() []*pb.ServerStatus {
+	statuses := make([]*pb.ServerStatus, 0, len(vs.servers))
+	for _, name := range vs.serverNames() {
+		server := vs.servers[name]
+		status := &pb.ServerStatus{
+			Name:             name,
+			Incarnation:      server.Incarnation,
+			Alive:            server.Alive,
+			Leaving:          server.Leaving,
+			Zone:             server.Zone,
+			Role:             vs.role(name, server.Incarnation),
+			LastPingUnixNano: server.LastPingTime.UnixNano(),
+		}
+		if server.Metadata != nil {
+			status.Metadata = proto.Clone(server.Metadata).(*pb.ServerMetadata)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// role returns the role the current view gives the server incarnation
+func (vs *ViewServer) role(name string, incarnation uint64) string {
+	switch {
+	case name == vs.currentView.Primary && incarnation == vs.currentView.PrimaryIncarnation:
+		return "primary"
+	case name == vs.currentView.Backup && incarnation == vs.currentView.BackupIncarnation:
+		return "backup"
+	}
+	return "idle"
+}
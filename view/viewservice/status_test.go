@@ -0,0 +1,96 @@
+package viewservice
+
+import (
+	"context"
+	"testing"
+
+	pb "goDistributedSystemDemo/proto"
+)
+
+// TestListServers reports each server's role, liveness and metadata, in join
+// order, and gives no role to a server that is unwilling or has left
+func TestListServers(t *testing.T) {
+	f := newFixture(t)
+	pair(f)
+	f.ping(&pb.PingRequest{ServerName: "idle", Incarnation: 1, Zone: "z1",
+		Metadata: &pb.ServerMetadata{Version: "v2", Labels: map[string]string{"rack": "r1"}, AppliedSeq: 7, Willing: false}})
+	f.join("gone", "")
+	f.join("left", "")
+	f.tick()
+	if _, err := f.vs.Leave(context.Background(), &pb.LeaveRequest{ServerName: "left", Incarnation: 1}); err != nil {
+		t.Fatal(err)
+	}
+	f.stop("gone")
+	f.stop("b")
+	f.ack()
+	f.expire()
+	f.ack()
+	f.tick()
+	// b died and neither idle (unwilling), gone (dead) nor left may replace it
+	f.wantView(3, "p", "")
+
+	resp, err := f.vs.ListServers(context.Background(), &pb.ListServersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name    string
+		role    string
+		alive   bool
+		leaving bool
+	}{
+		{"p", "primary", true, false},
+		{"b", "idle", false, false},
+		{"idle", "idle", true, false},
+		{"gone", "idle", false, false},
+		{"left", "idle", true, true},
+	}
+	if len(resp.Servers) != len(want) {
+		t.Fatalf("got %d servers, want %d", len(resp.Servers), len(want))
+	}
+	for i, w := range want {
+		s := resp.Servers[i]
+		if s.Name != w.name || s.Role != w.role || s.Alive != w.alive || s.Leaving != w.leaving {
+			t.Errorf("server %d = %s %s alive=%v leaving=%v, want %s %s alive=%v leaving=%v",
+				i, s.Name, s.Role, s.Alive, s.Leaving, w.name, w.role, w.alive, w.leaving)
+		}
+	}
+
+	idle := resp.Servers[2]
+	if idle.Zone != "z1" || idle.Metadata.GetVersion() != "v2" || idle.Metadata.GetLabels()["rack"] != "r1" ||
+		idle.Metadata.GetAppliedSeq() != 7 || idle.LastPingUnixNano != f.clk.Now().UnixNano() {
+		t.Errorf("idle server reported as %v", idle)
+	}
+	// The reply is a copy the caller may change
+	idle.Metadata.Labels["rack"] = "changed"
+	again, err := f.vs.ListServers(context.Background(), &pb.ListServersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Servers[2].Metadata.Labels["rack"] != "r1" {
+		t.Error("changing a reply changed the view service's state")
+	}
+}
+
+// TestRestartedServer reports a server under its new incarnation and gives the
+// primary's restarted backup a new view, so the state is transferred again
+func TestRestartedServer(t *testing.T) {
+	f := newFixture(t)
+	pair(f)
+	f.ping(&pb.PingRequest{ServerName: "b", Incarnation: 2})
+	f.tick()
+	f.wantView(3, "p", "b")
+	if reason := f.vs.history[len(f.vs.history)-1].Reason; reason != ReasonBackupRestarted {
+		t.Errorf("last change %q, want %q", reason, ReasonBackupRestarted)
+	}
+
+	resp, err := f.vs.ListServers(context.Background(), &pb.ListServersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range resp.Servers {
+		if s.Name == "b" && (s.Incarnation != 2 || s.Role != "backup") {
+			t.Errorf("b reported as incarnation %d %s, want incarnation 2 backup", s.Incarnation, s.Role)
+		}
+	}
+}